@@ -0,0 +1,108 @@
+package handlers
+
+import (
+	"errors"
+	"log"
+	"net/http"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/sarika-p9/my-pipeline-project/internal/core/ports"
+	"github.com/sarika-p9/my-pipeline-project/internal/services"
+)
+
+type DeadLetterHandler struct {
+	Service *services.DeadLetterService
+}
+
+// DeadLetterRequest selects dead letters by message ID; no IDs selects all.
+type DeadLetterRequest struct {
+	IDs []string `json:"ids"`
+}
+
+type DeadLetterResponse struct {
+	ID            string `json:"id"`
+	Queue         string `json:"queue"`
+	Data          string `json:"data"`
+	CorrelationID string `json:"correlation_id,omitempty"`
+	Retries       int    `json:"retries"`
+	Reason        string `json:"reason"`
+	DeadAt        string `json:"dead_at,omitempty"` // RFC 3339
+}
+
+func (h *DeadLetterHandler) ListDeadLetters(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	deadLetters, err := h.Service.ListDeadLetters(c.Request.Context(), userID, c.Param("queue"))
+	if err != nil {
+		writeDeadLetterError(c, "list", err)
+		return
+	}
+
+	resp := make([]DeadLetterResponse, len(deadLetters))
+	for i, deadLetter := range deadLetters {
+		resp[i] = DeadLetterResponse{
+			ID:            deadLetter.Message.ID,
+			Queue:         deadLetter.Queue,
+			Data:          string(deadLetter.Message.Data),
+			CorrelationID: deadLetter.Message.CorrelationID,
+			Retries:       deadLetter.Message.Retries,
+			Reason:        deadLetter.Reason,
+		}
+		if !deadLetter.DeadAt.IsZero() {
+			resp[i].DeadAt = deadLetter.DeadAt.Format(time.RFC3339)
+		}
+	}
+	c.JSON(http.StatusOK, resp)
+}
+
+func (h *DeadLetterHandler) ReplayDeadLetters(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+	var req DeadLetterRequest
+	if !bindOptionalJSON(c, &req) {
+		return
+	}
+
+	replayed, err := h.Service.ReplayDeadLetters(c.Request.Context(), userID, c.Param("queue"), req.IDs)
+	if err != nil {
+		writeDeadLetterError(c, "replay", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"replayed": replayed})
+}
+
+func (h *DeadLetterHandler) PurgeDeadLetters(c *gin.Context) {
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+	var req DeadLetterRequest
+	if !bindOptionalJSON(c, &req) {
+		return
+	}
+
+	purged, err := h.Service.PurgeDeadLetters(c.Request.Context(), userID, c.Param("queue"), req.IDs)
+	if err != nil {
+		writeDeadLetterError(c, "purge", err)
+		return
+	}
+	c.JSON(http.StatusOK, gin.H{"purged": purged})
+}
+
+func writeDeadLetterError(c *gin.Context, action string, err error) {
+	switch {
+	case errors.Is(err, ports.ErrPermissionDenied):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	case errors.Is(err, ports.ErrDeadLettersUnsupported):
+		c.JSON(http.StatusNotImplemented, gin.H{"error": err.Error()})
+	default:
+		log.Printf("Error trying to %s dead letters: %v", action, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to " + action + " dead letters"})
+	}
+}
@@ -2,12 +2,19 @@ package handlers
 
 import (
 	"context"
+	"errors"
 	"fmt"
+	"io"
 	"log"
 	"net/http"
+	"strconv"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/google/uuid"
+	"github.com/sarika-p9/my-pipeline-project/internal/core/domain"
+	"github.com/sarika-p9/my-pipeline-project/internal/core/ports"
+	"github.com/sarika-p9/my-pipeline-project/internal/infrastructure"
 	"github.com/sarika-p9/my-pipeline-project/internal/services"
 )
 
@@ -16,11 +23,13 @@ type PipelineHandler struct {
 }
 
 type CreatePipelineRequest struct {
-	Name       string   `json:"name"`
-	Stages     int      `json:"stages"`
-	IsParallel bool     `json:"is_parallel"`
-	UserID     string   `json:"user_id"`
-	StageNames []string `json:"stage_names"`
+	Name             string                   `json:"name"`
+	Stages           int                      `json:"stages"`
+	IsParallel       bool                     `json:"is_parallel"`
+	StageNames       []string                 `json:"stage_names"`
+	StageDefinitions []domain.StageDefinition `json:"stage_definitions"` // Takes precedence over stage_names when set
+	FailurePolicy    string                   `json:"failure_policy"`    // halt (default), continue or rollback
+	TimeoutMs        int64                    `json:"timeout_ms"`        // Bounds the whole run; zero means no limit
 }
 
 func (h *PipelineHandler) CreatePipeline(c *gin.Context) {
@@ -34,28 +43,37 @@ func (h *PipelineHandler) CreatePipeline(c *gin.Context) {
 
 	fmt.Printf("📥 Received CreatePipeline Request: %+v\n", req)
 
-	if req.UserID == "" {
-		fmt.Println("❌ Missing User ID")
-		c.JSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
+	userUUID, ok := authenticatedUserID(c)
+	if !ok {
 		return
 	}
 
+	stages := domain.StageDefinitionsFromNames(req.StageNames)
+	if len(req.StageDefinitions) > 0 {
+		stages = req.StageDefinitions
+		if req.Stages == 0 {
+			req.Stages = len(stages)
+		}
+	}
+
 	if req.Stages <= 0 {
 		fmt.Println("❌ Invalid number of stages:", req.Stages)
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid number of stages! Must be greater than 0."})
 		return
 	}
 
-	userUUID, err := uuid.Parse(req.UserID)
-	if err != nil {
-		fmt.Println("❌ Invalid User ID format:", req.UserID)
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID format"})
+	fmt.Printf("🛠️ Creating Pipeline: Name=%s, Stages=%d, Parallel=%t, UserID=%s, StageDefinitions=%+v\n", req.Name, req.Stages, req.IsParallel, userUUID, stages)
+
+	pipelineID, err := h.Service.CreatePipeline(userUUID, req.Name, stages, services.PipelineOptions{
+		IsParallel:    req.IsParallel,
+		FailurePolicy: req.FailurePolicy,
+		TimeoutMs:     req.TimeoutMs,
+	})
+	if domain.IsInvalidDefinition(err) {
+		fmt.Println("❌ Invalid pipeline definition:", err)
+		c.JSON(http.StatusBadRequest, gin.H{"error": err.Error()})
 		return
 	}
-
-	fmt.Printf("🛠️ Creating Pipeline: Name=%s, Stages=%d, UserID=%s, StageNames=%v\n", req.Name, req.Stages, userUUID, req.StageNames)
-
-	pipelineID, err := h.Service.CreatePipeline(userUUID, req.Name, req.Stages, req.StageNames)
 	if err != nil {
 		fmt.Println("❌ Failed to create pipeline:", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to create pipeline"})
@@ -72,80 +90,137 @@ func (h *PipelineHandler) CreatePipeline(c *gin.Context) {
 
 type StartPipelineRequest struct {
 	Input      interface{} `json:"input"`
-	IsParallel bool        `json:"is_parallel"`
-	UserID     string      `json:"user_id"`
+	IsParallel *bool       `json:"is_parallel"` // Overrides the mode saved on the pipeline when set
 }
 
 func (h *PipelineHandler) StartPipeline(c *gin.Context) {
-	pipelineID, err := uuid.Parse(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pipeline ID"})
+	pipelineID, userID, ok := h.authorizePipeline(c, "id")
+	if !ok {
 		return
 	}
 
 	var req StartPipelineRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+	if !bindOptionalJSON(c, &req) {
 		return
 	}
 
-	userID, err := uuid.Parse(req.UserID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+	runID, err := h.Service.StartPipeline(context.Background(), userID, pipelineID, req.Input, req.IsParallel)
+	switch {
+	case errors.Is(err, domain.ErrRunInProgress):
+		c.JSON(http.StatusConflict, gin.H{"error": "Pipeline is already running"})
+		return
+	case errors.Is(err, ports.ErrPipelineNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": "Pipeline not found"})
+		return
+	case err != nil:
+		log.Printf("Failed to start pipeline %s: %v", pipelineID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to start pipeline"})
 		return
 	}
 
-	go func() {
-		h.Service.StartPipeline(context.Background(), userID, pipelineID, req.Input)
-	}()
-
-	c.JSON(http.StatusAccepted, gin.H{"message": "Pipeline execution started", "pipeline_id": pipelineID})
+	c.JSON(http.StatusAccepted, gin.H{"message": "Pipeline execution started", "pipeline_id": pipelineID, "run_id": runID})
 }
 
-type GetPipelineStatusRequest struct {
-	IsParallel bool `json:"is_parallel"`
+// runIDParam reads the optional run_id query parameter. uuid.Nil selects the
+// latest run.
+func runIDParam(c *gin.Context) (uuid.UUID, error) {
+	if raw := c.Query("run_id"); raw != "" {
+		return uuid.Parse(raw)
+	}
+	return uuid.Nil, nil
 }
 
 func (h *PipelineHandler) GetPipelineStatus(c *gin.Context) {
-	pipelineID, err := uuid.Parse(c.Param("id"))
+	pipelineID, _, ok := h.authorizePipeline(c, "id")
+	if !ok {
+		return
+	}
+	runID, err := runIDParam(c)
 	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pipeline ID"})
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid run ID"})
+		return
+	}
+	status, err := h.Service.GetPipelineStatus(pipelineID, runID)
+	if errors.Is(err, ports.ErrPipelineNotFound) || errors.Is(err, ports.ErrRunNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
 		return
 	}
-	status, err := h.Service.GetPipelineStatus(pipelineID)
 	if err != nil {
-		c.JSON(http.StatusNotFound, gin.H{"error": "Pipeline not found"})
+		log.Printf("Error fetching status of pipeline %s: %v", pipelineID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pipeline status"})
+		return
+	}
+
+	response := gin.H{"pipeline_id": pipelineID, "status": status}
+	if runID != uuid.Nil {
+		response["run_id"] = runID
+	}
+	c.JSON(http.StatusOK, response)
+}
+
+func (h *PipelineHandler) GetPipelineOutput(c *gin.Context) {
+	pipelineID, _, ok := h.authorizePipeline(c, "id")
+	if !ok {
+		return
+	}
+
+	runID, err := runIDParam(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid run ID"})
 		return
 	}
 
-	c.JSON(http.StatusOK, gin.H{"pipeline_id": pipelineID, "status": status})
+	status, output, err := h.Service.GetPipelineOutput(pipelineID, runID)
+	if errors.Is(err, ports.ErrPipelineNotFound) || errors.Is(err, ports.ErrRunNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching output of pipeline %s: %v", pipelineID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pipeline output"})
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"pipeline_id": pipelineID, "status": status, "output": output})
 }
 
 type CancelPipelineRequest struct {
-	IsParallel bool   `json:"is_parallel"`
-	UserID     string `json:"user_id"`
+	RunID string `json:"run_id"` // Optional, defaults to the active run
 }
 
 func (h *PipelineHandler) CancelPipeline(c *gin.Context) {
-	pipelineID, err := uuid.Parse(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pipeline ID"})
+	pipelineID, userID, ok := h.authorizePipeline(c, "id")
+	if !ok {
 		return
 	}
 
 	var req CancelPipelineRequest
-	if err := c.ShouldBindJSON(&req); err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+	if !bindOptionalJSON(c, &req) {
 		return
 	}
 
-	userID, err := uuid.Parse(req.UserID)
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
-		return
+	runID := uuid.Nil
+	if req.RunID != "" {
+		var err error
+		if runID, err = uuid.Parse(req.RunID); err != nil {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid run ID"})
+			return
+		}
 	}
 
-	err = h.Service.CancelPipeline(pipelineID, userID)
+	err := h.Service.CancelPipeline(pipelineID, runID, userID)
+	if errors.Is(err, domain.ErrRunFinishing) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Pipeline is already finishing"})
+		return
+	}
+	if errors.Is(err, domain.ErrRunFinished) {
+		c.JSON(http.StatusConflict, gin.H{"error": err.Error()})
+		return
+	}
+	if errors.Is(err, ports.ErrRunNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
 	if err != nil {
 		log.Printf("Error cancelling pipeline: %v", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to cancel pipeline"})
@@ -155,14 +230,14 @@ func (h *PipelineHandler) CancelPipeline(c *gin.Context) {
 	c.JSON(http.StatusOK, gin.H{"message": "Pipeline cancelled", "pipeline_id": pipelineID})
 }
 
+// GetUserPipelines lists the authenticated user's pipelines.
 func (h *PipelineHandler) GetUserPipelines(c *gin.Context) {
-	userID := c.Query("user_id")
-	if userID == "" {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "User ID is required"})
+	userID, ok := authenticatedUserID(c)
+	if !ok {
 		return
 	}
 
-	pipelines, err := h.Service.GetPipelinesByUser(userID)
+	pipelines, err := h.Service.GetPipelinesByUser(userID.String())
 	if err != nil {
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pipelines"})
 		return
@@ -172,9 +247,13 @@ func (h *PipelineHandler) GetUserPipelines(c *gin.Context) {
 }
 
 func (h *PipelineHandler) GetPipelineStages(c *gin.Context) {
-	pipelineID, err := uuid.Parse(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pipeline ID"})
+	pipelineID, _, ok := h.authorizePipeline(c, "id")
+	if !ok {
+		return
+	}
+
+	if c.Query("run_id") != "" {
+		h.getStageRuns(c, pipelineID)
 		return
 	}
 
@@ -186,3 +265,240 @@ func (h *PipelineHandler) GetPipelineStages(c *gin.Context) {
 
 	c.JSON(http.StatusOK, stages)
 }
+
+// getStageRuns serves GET /pipelines/:id/stages?run_id=..., the stages of
+// one specific run.
+func (h *PipelineHandler) getStageRuns(c *gin.Context, pipelineID uuid.UUID) {
+	runID, err := runIDParam(c)
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid run ID"})
+		return
+	}
+
+	stageRuns, err := h.Service.GetStageRuns(pipelineID, runID)
+	if errors.Is(err, ports.ErrRunNotFound) {
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+		return
+	}
+	if err != nil {
+		log.Printf("Error fetching stages of run %s: %v", runID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pipeline stages"})
+		return
+	}
+
+	c.JSON(http.StatusOK, stageRuns)
+}
+
+func (h *PipelineHandler) GetPipelineRuns(c *gin.Context) {
+	pipelineID, _, ok := h.authorizePipeline(c, "id")
+	if !ok {
+		return
+	}
+
+	runs, err := h.Service.GetPipelineRuns(pipelineID)
+	if err != nil {
+		log.Printf("Error fetching runs of pipeline %s: %v", pipelineID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch pipeline runs"})
+		return
+	}
+
+	c.JSON(http.StatusOK, runs)
+}
+
+// sseKeepAliveInterval is how often an idle event stream sends a comment, so
+// that proxies keep it open.
+const sseKeepAliveInterval = 15 * time.Second
+
+// DeletePipeline deletes the pipeline with its stages and runs. Pipelines
+// with a run in progress have to be cancelled first.
+func (h *PipelineHandler) DeletePipeline(c *gin.Context) {
+	pipelineID, err := uuid.Parse(c.Param("pipelineID"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pipeline ID"})
+		return
+	}
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	err = h.Service.DeletePipeline(c.Request.Context(), userID, pipelineID)
+	if errors.Is(err, domain.ErrRunInProgress) {
+		c.JSON(http.StatusConflict, gin.H{"error": "Pipeline is running, cancel it before deleting"})
+		return
+	}
+	if err != nil {
+		writePipelineAccessError(c, pipelineID, "Failed to delete pipeline", err)
+		return
+	}
+
+	c.JSON(http.StatusOK, gin.H{"message": "Pipeline deleted successfully"})
+}
+
+// StreamPipelineEvents streams the pipeline's stage and run updates as
+// Server-Sent Events, the same updates WebSocket clients receive. Each event
+// has the update's sequence as its ID, so a client that reconnects with
+// Last-Event-ID first receives the updates it missed, or a resync event when
+// they are no longer kept. A client without Last-Event-ID first receives the
+// state of the latest run, like WatchPipeline over gRPC. The stream ends once
+// the run in progress finishes, or right after the state and missed updates
+// when no run is in progress.
+func (h *PipelineHandler) StreamPipelineEvents(c *gin.Context) {
+	pipelineID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pipeline ID"})
+		return
+	}
+	userID, ok := authenticatedUserID(c)
+	if !ok {
+		return
+	}
+
+	var lastSeq int64
+	if lastEventID := c.GetHeader("Last-Event-ID"); lastEventID != "" {
+		lastSeq, err = strconv.ParseInt(lastEventID, 10, 64)
+		if err != nil || lastSeq < 0 {
+			c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid Last-Event-ID"})
+			return
+		}
+	}
+
+	watcher, activeRunID, err := h.Service.WatchPipeline(userID, pipelineID, lastSeq)
+	if err != nil {
+		writePipelineAccessError(c, pipelineID, "Failed to watch pipeline", err)
+		return
+	}
+	defer watcher.Stop()
+
+	var snapshot []infrastructure.PipelineUpdate
+	if lastSeq == 0 {
+		if snapshot, err = h.Service.LatestRunUpdates(pipelineID); err != nil {
+			log.Printf("Error fetching the latest run of pipeline %s: %v", pipelineID, err)
+			c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to fetch the latest run"})
+			return
+		}
+	}
+
+	c.Header("Content-Type", "text/event-stream")
+	c.Header("Cache-Control", "no-cache")
+	c.Header("Connection", "keep-alive")
+	c.Header("X-Accel-Buffering", "no") // Keeps nginx from buffering events
+	c.Status(http.StatusOK)
+	if watcher.Resync {
+		fmt.Fprintf(c.Writer, "event: resync\ndata: {\"type\":\"resync\",\"pipeline_id\":%q}\n\n", pipelineID)
+	}
+	c.Writer.Flush()
+
+	// send writes an update and reports whether the stream should go on.
+	send := func(update infrastructure.PipelineUpdate) bool {
+		data, err := update.Message()
+		if err != nil {
+			log.Printf("Error encoding update of pipeline %s: %v", pipelineID, err)
+			return true
+		}
+		if update.Sequence != 0 {
+			fmt.Fprintf(c.Writer, "id: %d\n", update.Sequence)
+		}
+		if _, err := fmt.Fprintf(c.Writer, "event: %s\ndata: %s\n\n", update.Type, data); err != nil {
+			return false
+		}
+		c.Writer.Flush()
+		return !services.EndsWatch(update, activeRunID)
+	}
+	for _, update := range snapshot {
+		if !send(update) {
+			return
+		}
+	}
+
+	keepAlive := time.NewTicker(sseKeepAliveInterval)
+	defer keepAlive.Stop()
+	for {
+		// The updates already held go out first, even when no run is in
+		// progress any more.
+		select {
+		case update := <-watcher.Updates():
+			if !send(update) {
+				return
+			}
+			continue
+		default:
+		}
+		if activeRunID == uuid.Nil {
+			return
+		}
+
+		select {
+		case update := <-watcher.Updates():
+			if !send(update) {
+				return
+			}
+		case <-keepAlive.C:
+			if _, err := fmt.Fprint(c.Writer, ": keepalive\n\n"); err != nil {
+				return
+			}
+			c.Writer.Flush()
+		case <-watcher.Done():
+			return
+		case <-c.Request.Context().Done():
+			return
+		}
+	}
+}
+
+// authenticatedUserID returns the user set by the auth middleware.
+func authenticatedUserID(c *gin.Context) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(c.GetString("user_id"))
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID"})
+		return uuid.Nil, false
+	}
+	return userID, true
+}
+
+// authorizePipeline reads the pipeline ID from the named path parameter and
+// checks that the authenticated user owns the pipeline or is an admin. When
+// not, it writes the error response and returns false.
+func (h *PipelineHandler) authorizePipeline(c *gin.Context, param string) (pipelineID uuid.UUID, userID uuid.UUID, ok bool) {
+	pipelineID, err := uuid.Parse(c.Param(param))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid pipeline ID"})
+		return uuid.Nil, uuid.Nil, false
+	}
+	if userID, ok = authenticatedUserID(c); !ok {
+		return uuid.Nil, uuid.Nil, false
+	}
+	if err := h.Service.CanViewPipeline(userID, pipelineID); err != nil {
+		writePipelineAccessError(c, pipelineID, "Failed to check access to the pipeline", err)
+		return uuid.Nil, uuid.Nil, false
+	}
+	return pipelineID, userID, true
+}
+
+// writePipelineAccessError answers 404 when the pipeline doesn't exist, 403
+// when it belongs to another user and 500 with the given message otherwise.
+func writePipelineAccessError(c *gin.Context, pipelineID uuid.UUID, message string, err error) {
+	switch {
+	case errors.Is(err, ports.ErrPipelineNotFound):
+		c.JSON(http.StatusNotFound, gin.H{"error": err.Error()})
+	case errors.Is(err, ports.ErrPermissionDenied):
+		c.JSON(http.StatusForbidden, gin.H{"error": err.Error()})
+	default:
+		log.Printf("%s %s: %v", message, pipelineID, err)
+		c.JSON(http.StatusInternalServerError, gin.H{"error": message})
+	}
+}
+
+// bindOptionalJSON binds the request body into req, leaving req as is when the
+// body is empty, including chunked bodies without a Content-Length. When the
+// body is invalid, it writes a 400 response and returns false.
+func bindOptionalJSON(c *gin.Context, req interface{}) bool {
+	if c.Request.Body == nil || c.Request.Body == http.NoBody {
+		return true
+	}
+	if err := c.ShouldBindJSON(req); err != nil && !errors.Is(err, io.EOF) {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request"})
+		return false
+	}
+	return true
+}
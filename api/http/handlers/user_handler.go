@@ -1,6 +1,7 @@
 package handlers
 
 import (
+	"log"
 	"net/http"
 
 	"github.com/gin-gonic/gin"
@@ -13,9 +14,8 @@ type UserHandler struct {
 }
 
 func (h *UserHandler) GetUserProfile(c *gin.Context) {
-	userID, err := uuid.Parse(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+	userID, ok := h.profileUserID(c)
+	if !ok {
 		return
 	}
 
@@ -32,23 +32,73 @@ func (h *UserHandler) GetUserProfile(c *gin.Context) {
 	})
 }
 
+type UpdateUserProfileRequest struct {
+	Name *string `json:"name"`
+	Role *string `json:"role"`
+}
+
+// UpdateUserProfile changes the user's name and role. Only admins can change
+// roles; sending the current role is allowed.
 func (h *UserHandler) UpdateUserProfile(c *gin.Context) {
-	userID, err := uuid.Parse(c.Param("id"))
-	if err != nil {
-		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+	userID, ok := h.profileUserID(c)
+	if !ok {
 		return
 	}
 
-	var updateData map[string]interface{}
-	if err := c.BindJSON(&updateData); err != nil {
+	var req UpdateUserProfileRequest
+	if err := c.BindJSON(&req); err != nil {
 		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid request data"})
 		return
 	}
 
-	if err := h.Service.UpdateUser(userID, updateData); err != nil {
+	updates := make(map[string]interface{})
+	if req.Name != nil {
+		updates["name"] = *req.Name
+	}
+	if req.Role != nil {
+		user, err := h.Service.GetUserByID(userID)
+		if err != nil {
+			c.JSON(http.StatusNotFound, gin.H{"error": "User not found"})
+			return
+		}
+		if *req.Role != user.Role {
+			callerID, _ := authenticatedUserID(c)
+			if !h.Service.IsAdmin(callerID) {
+				c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can change roles"})
+				return
+			}
+			updates["role"] = *req.Role
+		}
+	}
+	if len(updates) == 0 {
+		c.JSON(http.StatusOK, gin.H{"message": "User updated successfully"})
+		return
+	}
+
+	if err := h.Service.UpdateUser(userID, updates); err != nil {
+		log.Println("Update user error:", err)
 		c.JSON(http.StatusInternalServerError, gin.H{"error": "Failed to update user"})
 		return
 	}
 
 	c.JSON(http.StatusOK, gin.H{"message": "User updated successfully"})
 }
+
+// profileUserID reads the user ID in the path. Users can only reach their own
+// profile unless they are admins.
+func (h *UserHandler) profileUserID(c *gin.Context) (uuid.UUID, bool) {
+	userID, err := uuid.Parse(c.Param("id"))
+	if err != nil {
+		c.JSON(http.StatusBadRequest, gin.H{"error": "Invalid user ID"})
+		return uuid.Nil, false
+	}
+	callerID, ok := authenticatedUserID(c)
+	if !ok {
+		return uuid.Nil, false
+	}
+	if userID != callerID && !h.Service.IsAdmin(callerID) {
+		c.JSON(http.StatusForbidden, gin.H{"error": "Only admins can access other users' profiles"})
+		return uuid.Nil, false
+	}
+	return userID, true
+}
@@ -0,0 +1,112 @@
+package cmd
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"os"
+	"path/filepath"
+
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+// credentials are what democtl login saves for the other commands.
+type credentials struct {
+	UserID string `json:"user_id"`
+	Email  string `json:"email"`
+	Token  string `json:"token"`
+}
+
+// errNotLoggedIn is returned when no token was saved or given.
+var errNotLoggedIn = errors.New("not logged in, run democtl login first")
+
+func credentialsPath() (string, error) {
+	dir, err := os.UserConfigDir()
+	if err != nil {
+		return "", err
+	}
+	return filepath.Join(dir, "democtl", "credentials.json"), nil
+}
+
+// saveCredentials stores the credentials where only the current user can
+// read them.
+func saveCredentials(creds credentials) (string, error) {
+	path, err := credentialsPath()
+	if err != nil {
+		return "", err
+	}
+	if err := os.MkdirAll(filepath.Dir(path), 0o700); err != nil {
+		return "", err
+	}
+	data, err := json.Marshal(creds)
+	if err != nil {
+		return "", err
+	}
+	return path, os.WriteFile(path, data, 0o600)
+}
+
+// loadCredentials returns the saved credentials. DEMOCTL_TOKEN, when set,
+// takes the place of the saved token.
+func loadCredentials() (credentials, error) {
+	var creds credentials
+	path, err := credentialsPath()
+	if err != nil {
+		return creds, err
+	}
+	data, err := os.ReadFile(path)
+	switch {
+	case err == nil:
+		if err := json.Unmarshal(data, &creds); err != nil {
+			return creds, fmt.Errorf("invalid credentials in %s: %w", path, err)
+		}
+	case !errors.Is(err, os.ErrNotExist):
+		return creds, err
+	}
+
+	if token := os.Getenv("DEMOCTL_TOKEN"); token != "" {
+		creds.Token = token
+	}
+	if creds.Token == "" {
+		return creds, errNotLoggedIn
+	}
+	return creds, nil
+}
+
+func removeCredentials() error {
+	path, err := credentialsPath()
+	if err != nil {
+		return err
+	}
+	if err := os.Remove(path); err != nil && !errors.Is(err, os.ErrNotExist) {
+		return err
+	}
+	return nil
+}
+
+// tokenAuth sends a bearer token with every call.
+type tokenAuth string
+
+func (t tokenAuth) GetRequestMetadata(ctx context.Context, uri ...string) (map[string]string, error) {
+	return map[string]string{"authorization": "Bearer " + string(t)}, nil
+}
+
+// RequireTransportSecurity allows the token over the plaintext connection to
+// the local server.
+func (tokenAuth) RequireTransportSecurity() bool {
+	return false
+}
+
+// dialServer connects to the gRPC server as the user logged in with democtl
+// login.
+func dialServer() (*grpc.ClientConn, error) {
+	creds, err := loadCredentials()
+	if err != nil {
+		return nil, err
+	}
+	return grpc.Dial("localhost:50051",
+		grpc.WithTransportCredentials(insecure.NewCredentials()),
+		grpc.WithPerRPCCredentials(tokenAuth(creds.Token)),
+	)
+}
@@ -0,0 +1,111 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	proto "github.com/sarika-p9/my-pipeline-project/api/grpc/proto/pipeline"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+)
+
+var dlqCmd = &cobra.Command{
+	Use:   "dlq",
+	Short: "Inspect and re-drive dead-lettered tasks (admins only)",
+}
+
+var listDeadLettersCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List the dead letters of a queue",
+	Run: func(cmd *cobra.Command, args []string) {
+		queue, _ := readDeadLetterFlags(cmd)
+		client, conn := dialDeadLetters()
+		defer conn.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+		defer cancel()
+		resp, err := client.ListDeadLetters(ctx, &proto.ListDeadLettersRequest{Queue: queue})
+		if err != nil {
+			log.Fatalf("❌ Failed to list dead letters: %v", err)
+		}
+		if len(resp.DeadLetters) == 0 {
+			fmt.Println("ℹ️ No dead letters.")
+			return
+		}
+		for _, deadLetter := range resp.DeadLetters {
+			fmt.Printf("💀 %s  retries %d  dead %s\n   reason: %s\n   data:   %s\n", deadLetter.Id, deadLetter.Retries, deadLetter.DeadAt, deadLetter.Reason, deadLetter.Data)
+		}
+	},
+}
+
+var replayDeadLettersCmd = &cobra.Command{
+	Use:   "replay",
+	Short: "Send dead letters back to their queue",
+	Run: func(cmd *cobra.Command, args []string) {
+		queue, ids := readDeadLetterFlags(cmd)
+		client, conn := dialDeadLetters()
+		defer conn.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		resp, err := client.ReplayDeadLetters(ctx, &proto.ReplayDeadLettersRequest{Queue: queue, Ids: ids})
+		if err != nil {
+			log.Fatalf("❌ Failed to replay dead letters: %v", err)
+		}
+		fmt.Printf("🔁 Replayed %d dead letters.\n", resp.Replayed)
+	},
+}
+
+var purgeDeadLettersCmd = &cobra.Command{
+	Use:   "purge",
+	Short: "Drop dead letters for good",
+	Run: func(cmd *cobra.Command, args []string) {
+		queue, ids := readDeadLetterFlags(cmd)
+		client, conn := dialDeadLetters()
+		defer conn.Close()
+
+		ctx, cancel := context.WithTimeout(context.Background(), 30*time.Second)
+		defer cancel()
+		resp, err := client.PurgeDeadLetters(ctx, &proto.PurgeDeadLettersRequest{Queue: queue, Ids: ids})
+		if err != nil {
+			log.Fatalf("❌ Failed to purge dead letters: %v", err)
+		}
+		fmt.Printf("🗑️ Purged %d dead letters.\n", resp.Purged)
+	},
+}
+
+func readDeadLetterFlags(cmd *cobra.Command) (queue string, ids []string) {
+	queue, _ = cmd.Flags().GetString("queue")
+	if cmd.Flags().Lookup("ids") != nil {
+		idList, _ := cmd.Flags().GetString("ids")
+		for _, id := range strings.Split(idList, ",") {
+			if id = strings.TrimSpace(id); id != "" {
+				ids = append(ids, id)
+			}
+		}
+	}
+	return queue, ids
+}
+
+func dialDeadLetters() (proto.PipelineServiceClient, *grpc.ClientConn) {
+	conn, err := dialServer()
+	if err != nil {
+		log.Fatalf("❌ Failed to connect to gRPC server: %v", err)
+	}
+	return proto.NewPipelineServiceClient(conn), conn
+}
+
+func init() {
+	dlqCmd.AddCommand(listDeadLettersCmd)
+	dlqCmd.AddCommand(replayDeadLettersCmd)
+	dlqCmd.AddCommand(purgeDeadLettersCmd)
+
+	for _, c := range []*cobra.Command{listDeadLettersCmd, replayDeadLettersCmd, purgeDeadLettersCmd} {
+		c.Flags().String("queue", "", "Queue (defaults to the task queue)")
+	}
+	replayDeadLettersCmd.Flags().String("ids", "", "Comma-separated message IDs (defaults to all)")
+	purgeDeadLettersCmd.Flags().String("ids", "", "Comma-separated message IDs (defaults to all)")
+}
@@ -0,0 +1,104 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"os"
+	"os/signal"
+	"strconv"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/sarika-p9/my-pipeline-project/internal/core/ports"
+	"github.com/sarika-p9/my-pipeline-project/internal/messaging"
+	"github.com/spf13/cobra"
+)
+
+var eventsCmd = &cobra.Command{
+	Use:   "events",
+	Short: "Read the pipeline event log",
+}
+
+var tailEventsCmd = &cobra.Command{
+	Use:   "tail",
+	Short: "Print pipeline events from the event log",
+	Long: `Prints pipeline events from the JetStream event log, oldest first.
+--since takes a sequence number (e.g. 1042), an RFC 3339 time or a duration
+back from now (e.g. 30m). Without it, every retained event is printed.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		natsURL, _ := cmd.Flags().GetString("nats-url")
+		since, _ := cmd.Flags().GetString("since")
+		pipelineID, _ := cmd.Flags().GetString("pipeline-id")
+		follow, _ := cmd.Flags().GetBool("follow")
+
+		query, err := parseSince(since)
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		query.Follow = follow
+		if pipelineID != "" {
+			if query.PipelineID, err = uuid.Parse(pipelineID); err != nil {
+				log.Fatal("❌ Invalid pipeline ID format.")
+			}
+		}
+
+		conn, err := nats.Connect(natsURL)
+		if err != nil {
+			log.Fatalf("❌ Failed to connect to NATS: %v", err)
+		}
+		defer conn.Close()
+
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+		eventLog, err := messaging.OpenJetStreamEventLog(conn)
+		if err != nil {
+			log.Fatalf("❌ Failed to open the event log: %v", err)
+		}
+
+		err = eventLog.Read(ctx, query, func(ctx context.Context, logged ports.LoggedEvent) error {
+			event := logged.Event
+			fmt.Printf("#%-8d %s  %-22s %s %s\n         %s\n", logged.Sequence, event.Time.Local().Format(time.DateTime), event.Type, event.Source, event.Subject, event.Data)
+			return nil
+		})
+		if err != nil && ctx.Err() == nil {
+			log.Fatalf("❌ Failed to read events: %v", err)
+		}
+	},
+}
+
+// parseSince reads a sequence number, an RFC 3339 time or a duration back
+// from now.
+func parseSince(since string) (ports.EventLogQuery, error) {
+	var query ports.EventLogQuery
+	if since == "" {
+		return query, nil
+	}
+	if seq, err := strconv.ParseUint(since, 10, 64); err == nil {
+		query.FromSequence = seq
+		return query, nil
+	}
+	if t, err := time.Parse(time.RFC3339, since); err == nil {
+		query.FromTime = t
+		return query, nil
+	}
+	if d, err := time.ParseDuration(since); err == nil {
+		query.FromTime = time.Now().Add(-d)
+		return query, nil
+	}
+	return query, fmt.Errorf("invalid --since %q, expected a sequence number, an RFC 3339 time or a duration", since)
+}
+
+func init() {
+	eventsCmd.AddCommand(tailEventsCmd)
+
+	natsURL := os.Getenv("NATS_URL")
+	if natsURL == "" {
+		natsURL = "nats://localhost:4222"
+	}
+	tailEventsCmd.Flags().String("nats-url", natsURL, "NATS server holding the event log")
+	tailEventsCmd.Flags().String("since", "", "Start at a sequence number, an RFC 3339 time or a duration ago")
+	tailEventsCmd.Flags().String("pipeline-id", "", "Only print the events of this pipeline")
+	tailEventsCmd.Flags().BoolP("follow", "f", false, "Keep printing new events")
+}
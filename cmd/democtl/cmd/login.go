@@ -48,6 +48,12 @@ var loginCmd = &cobra.Command{
 			log.Fatalf("❌ Login failed: %v", err)
 		}
 		fmt.Printf("\n✅ Login successful!\nUserID: %s\nEmail: %s\nToken: %s\n", resp.UserId, resp.Email, resp.Token)
+
+		path, err := saveCredentials(credentials{UserID: resp.UserId, Email: resp.Email, Token: resp.Token})
+		if err != nil {
+			log.Fatalf("❌ Failed to save the token: %v", err)
+		}
+		fmt.Printf("🔑 Token saved to %s for the other commands\n", path)
 	},
 }
 
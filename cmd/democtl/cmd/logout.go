@@ -0,0 +1,51 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	proto "github.com/sarika-p9/my-pipeline-project/api/grpc/proto/authentication"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/credentials/insecure"
+)
+
+var logoutCmd = &cobra.Command{
+	Use:   "logout",
+	Short: "Log out, revoking the token saved at login",
+	Run: func(cmd *cobra.Command, args []string) {
+		token, _ := cmd.Flags().GetString("token")
+		if token == "" {
+			creds, err := loadCredentials()
+			if err != nil {
+				log.Fatalf("❌ %v", err)
+			}
+			token = creds.Token
+		}
+		conn, err := grpc.Dial("localhost:50051", grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			log.Fatalf("❌ Failed to connect to gRPC server: %v", err)
+		}
+		defer conn.Close()
+
+		client := proto.NewAuthServiceClient(conn)
+		ctx, cancel := context.WithTimeout(context.Background(), 15*time.Second)
+		defer cancel()
+		resp, err := client.Logout(ctx, &proto.LogoutRequest{Token: token})
+		if err != nil {
+			log.Fatalf("❌ Logout failed: %v", err)
+		}
+		if !cmd.Flags().Changed("token") {
+			if err := removeCredentials(); err != nil {
+				log.Fatalf("❌ Failed to remove the saved token: %v", err)
+			}
+		}
+		fmt.Printf("👋 %s\n", resp.Message)
+	},
+}
+
+func init() {
+	logoutCmd.Flags().String("token", "", "Token to revoke instead of the saved one")
+}
@@ -5,14 +5,13 @@ import (
 	"encoding/json"
 	"fmt"
 	"log"
+	"os"
 	"strings"
 	"time"
 
-	"github.com/google/uuid"
 	proto "github.com/sarika-p9/my-pipeline-project/api/grpc/proto/pipeline"
 	"github.com/spf13/cobra"
-	"google.golang.org/grpc"
-	"google.golang.org/grpc/credentials/insecure"
+	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/types/known/anypb"
 	"google.golang.org/protobuf/types/known/structpb"
 )
@@ -26,25 +25,34 @@ var createPipelineCmd = &cobra.Command{
 	Use:   "create",
 	Short: "Create a new pipeline",
 	Run: func(cmd *cobra.Command, args []string) {
-		userID, _ := cmd.Flags().GetString("user")
 		pipelineName, _ := cmd.Flags().GetString("pipeline-name")
 		stages, _ := cmd.Flags().GetInt("stages")
 		isParallel, _ := cmd.Flags().GetBool("parallel")
 		stageNames, _ := cmd.Flags().GetString("stage-names")
-		if userID == "" || pipelineName == "" || stages <= 0 {
-			log.Fatal("❌ User ID, Pipeline Name, and a valid number of stages are required.")
+		dependsOn, _ := cmd.Flags().GetString("depends-on")
+		onFailure, _ := cmd.Flags().GetString("on-failure")
+		definitionsFile, _ := cmd.Flags().GetString("stage-definitions-file")
+		timeout, _ := cmd.Flags().GetDuration("timeout")
+		if pipelineName == "" || stages <= 0 {
+			log.Fatal("❌ Pipeline Name and a valid number of stages are required.")
 		}
 
-		if _, err := uuid.Parse(userID); err != nil {
-			log.Fatal("❌ Invalid user ID format.")
-		}
 		var stageNamesList []string
-		if stageNames != "" {
+		var stageDefinitions []*proto.StageDefinition
+		var err error
+		switch {
+		case definitionsFile != "":
+			stageDefinitions, err = readStageDefinitions(definitionsFile)
+		case stageNames != "":
 			stageNamesList = strings.Split(stageNames, ",")
-		} else {
-			log.Fatal("❌ Stage names are required.")
+			stageDefinitions, err = parseStageDependencies(stageNamesList, dependsOn)
+		default:
+			log.Fatal("❌ Stage names or a stage definitions file are required.")
 		}
-		conn, err := grpc.Dial("localhost:50051", grpc.WithTransportCredentials(insecure.NewCredentials()))
+		if err != nil {
+			log.Fatalf("❌ %v", err)
+		}
+		conn, err := dialServer()
 		if err != nil {
 			log.Fatalf("❌ Failed to connect to gRPC server: %v", err)
 		}
@@ -55,11 +63,13 @@ var createPipelineCmd = &cobra.Command{
 		defer cancel()
 
 		resp, err := client.CreatePipeline(ctx, &proto.CreatePipelineRequest{
-			UserId:       userID,
-			PipelineName: pipelineName,
-			Stages:       int32(stages),
-			IsParallel:   isParallel,
-			StageNames:   stageNamesList,
+			PipelineName:     pipelineName,
+			Stages:           int32(stages),
+			IsParallel:       isParallel,
+			StageNames:       stageNamesList,
+			StageDefinitions: stageDefinitions,
+			FailurePolicy:    onFailure,
+			TimeoutMs:        timeout.Milliseconds(),
 		})
 		if err != nil {
 			log.Fatalf("❌ Pipeline creation failed: %v", err)
@@ -74,16 +84,12 @@ var startPipelineCmd = &cobra.Command{
 	Short: "Start a pipeline execution",
 	Run: func(cmd *cobra.Command, args []string) {
 		pipelineID, _ := cmd.Flags().GetString("pipeline-id")
-		userID, _ := cmd.Flags().GetString("user-id")
 		inputStr, _ := cmd.Flags().GetString("input")
 		isParallel, _ := cmd.Flags().GetBool("parallel")
-		if pipelineID == "" || userID == "" {
-			log.Fatal("❌ Pipeline ID and User ID are required.")
-		}
-		if _, err := uuid.Parse(userID); err != nil {
-			log.Fatal("❌ Invalid user ID format.")
+		if pipelineID == "" {
+			log.Fatal("❌ Pipeline ID is required.")
 		}
-		conn, err := grpc.Dial("localhost:50051", grpc.WithTransportCredentials(insecure.NewCredentials()))
+		conn, err := dialServer()
 		if err != nil {
 			log.Fatalf("❌ Failed to connect to gRPC server: %v", err)
 		}
@@ -108,17 +114,20 @@ var startPipelineCmd = &cobra.Command{
 		if err != nil {
 			log.Fatalf("❌ Failed to wrap input in Any: %v", err)
 		}
-		resp, err := client.StartPipeline(ctx, &proto.StartPipelineRequest{
+		req := &proto.StartPipelineRequest{
 			PipelineId: pipelineID,
-			UserId:     userID,
 			Input:      inputAny,
-			IsParallel: isParallel,
-		})
+		}
+		if cmd.Flags().Changed("parallel") {
+			req.IsParallel = &isParallel
+		}
+		resp, err := client.StartPipeline(ctx, req)
 		if err != nil {
 			log.Fatalf("❌ Failed to start pipeline: %v", err)
 		}
 
 		fmt.Printf("🚀 Pipeline execution started successfully! Message: %s\n", resp.Message)
+		fmt.Printf("🆔 Run ID: %s\n", resp.RunId)
 	},
 }
 
@@ -127,18 +136,14 @@ var cancelPipelineCmd = &cobra.Command{
 	Short: "Cancel a running pipeline",
 	Run: func(cmd *cobra.Command, args []string) {
 		pipelineID, _ := cmd.Flags().GetString("pipeline-id")
-		userID, _ := cmd.Flags().GetString("user-id")
 		isParallel, _ := cmd.Flags().GetBool("parallel")
+		runID, _ := cmd.Flags().GetString("run-id")
 
-		if pipelineID == "" || userID == "" {
-			log.Fatal("❌ Pipeline ID and User ID are required.")
-		}
-
-		if _, err := uuid.Parse(userID); err != nil {
-			log.Fatal("❌ Invalid user ID format.")
+		if pipelineID == "" {
+			log.Fatal("❌ Pipeline ID is required.")
 		}
 
-		conn, err := grpc.Dial("localhost:50051", grpc.WithTransportCredentials(insecure.NewCredentials()))
+		conn, err := dialServer()
 		if err != nil {
 			log.Fatalf("❌ Failed to connect to gRPC server: %v", err)
 		}
@@ -150,8 +155,8 @@ var cancelPipelineCmd = &cobra.Command{
 
 		resp, err := client.CancelPipeline(ctx, &proto.CancelPipelineRequest{
 			PipelineId: pipelineID,
-			UserId:     userID,
 			IsParallel: isParallel,
+			RunId:      runID,
 		})
 		if err != nil {
 			log.Fatalf("❌ Failed to cancel pipeline: %v", err)
@@ -167,10 +172,11 @@ var getPipelineStatusCmd = &cobra.Command{
 	Run: func(cmd *cobra.Command, args []string) {
 		pipelineID, _ := cmd.Flags().GetString("pipeline-id")
 		isParallel, _ := cmd.Flags().GetBool("parallel")
+		runID, _ := cmd.Flags().GetString("run-id")
 		if pipelineID == "" {
 			log.Fatal("❌ Pipeline ID is required.")
 		}
-		conn, err := grpc.Dial("localhost:50051", grpc.WithTransportCredentials(insecure.NewCredentials()))
+		conn, err := dialServer()
 		if err != nil {
 			log.Fatalf("❌ Failed to connect to gRPC server: %v", err)
 		}
@@ -182,6 +188,7 @@ var getPipelineStatusCmd = &cobra.Command{
 		resp, err := client.GetPipelineStatus(ctx, &proto.GetPipelineStatusRequest{
 			PipelineId: pipelineID,
 			IsParallel: isParallel,
+			RunId:      runID,
 		})
 		if err != nil {
 			log.Fatalf("❌ Failed to get pipeline status: %v", err)
@@ -189,37 +196,309 @@ var getPipelineStatusCmd = &cobra.Command{
 		fmt.Println("📊 Pipeline Status Report:")
 		fmt.Println("------------------------------")
 		fmt.Printf("🆔 Pipeline ID: %s\n", pipelineID)
+		if runID != "" {
+			fmt.Printf("🏃 Run ID: %s\n", runID)
+		}
 		fmt.Printf("📌 Status: %s\n", resp.Status)
 		fmt.Println("------------------------------")
 	},
 }
 
+var getPipelineOutputCmd = &cobra.Command{
+	Use:   "output",
+	Short: "Get the output of a pipeline's last run",
+	Run: func(cmd *cobra.Command, args []string) {
+		pipelineID, _ := cmd.Flags().GetString("pipeline-id")
+		runID, _ := cmd.Flags().GetString("run-id")
+		if pipelineID == "" {
+			log.Fatal("❌ Pipeline ID is required.")
+		}
+		conn, err := dialServer()
+		if err != nil {
+			log.Fatalf("❌ Failed to connect to gRPC server: %v", err)
+		}
+		defer conn.Close()
+
+		client := proto.NewPipelineServiceClient(conn)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		resp, err := client.GetPipelineOutput(ctx, &proto.GetPipelineOutputRequest{PipelineId: pipelineID, RunId: runID})
+		if err != nil {
+			log.Fatalf("❌ Failed to get pipeline output: %v", err)
+		}
+		output, err := protojson.MarshalOptions{Multiline: true}.Marshal(resp.Output)
+		if err != nil {
+			log.Fatalf("❌ Failed to format pipeline output: %v", err)
+		}
+		fmt.Printf("📌 Status: %s\n", resp.Status)
+		fmt.Printf("📦 Output:\n%s\n", output)
+	},
+}
+
+var listPipelineRunsCmd = &cobra.Command{
+	Use:   "runs",
+	Short: "List the runs of a pipeline, newest first",
+	Run: func(cmd *cobra.Command, args []string) {
+		pipelineID, _ := cmd.Flags().GetString("pipeline-id")
+		if pipelineID == "" {
+			log.Fatal("❌ Pipeline ID is required.")
+		}
+		conn, err := dialServer()
+		if err != nil {
+			log.Fatalf("❌ Failed to connect to gRPC server: %v", err)
+		}
+		defer conn.Close()
+
+		client := proto.NewPipelineServiceClient(conn)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		resp, err := client.ListPipelineRuns(ctx, &proto.ListPipelineRunsRequest{PipelineId: pipelineID})
+		if err != nil {
+			log.Fatalf("❌ Failed to list pipeline runs: %v", err)
+		}
+		if len(resp.Runs) == 0 {
+			fmt.Println("ℹ️ This pipeline has not been run yet.")
+			return
+		}
+		for _, run := range resp.Runs {
+			fmt.Printf("🏃 %s  %-10s created %s  finished %s\n", run.RunId, run.Status, run.CreatedAt, run.FinishedAt)
+		}
+	},
+}
+
+var listPipelinesCmd = &cobra.Command{
+	Use:   "list",
+	Short: "List your pipelines, oldest first",
+	Run: func(cmd *cobra.Command, args []string) {
+		pageSize, _ := cmd.Flags().GetInt32("page-size")
+		pageToken, _ := cmd.Flags().GetString("page-token")
+		conn, err := dialServer()
+		if err != nil {
+			log.Fatalf("❌ Failed to connect to gRPC server: %v", err)
+		}
+		defer conn.Close()
+
+		client := proto.NewPipelineServiceClient(conn)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		resp, err := client.ListPipelines(ctx, &proto.ListPipelinesRequest{
+			PageSize:  pageSize,
+			PageToken: pageToken,
+		})
+		if err != nil {
+			log.Fatalf("❌ Failed to list pipelines: %v", err)
+		}
+		if len(resp.Pipelines) == 0 {
+			fmt.Println("ℹ️ No pipelines.")
+			return
+		}
+		for _, pipeline := range resp.Pipelines {
+			fmt.Printf("🆔 %s  %-10s %s\n", pipeline.PipelineId, pipeline.Status, pipeline.PipelineName)
+		}
+		if resp.NextPageToken != "" {
+			fmt.Printf("➡️ More pipelines follow, list them with --page-token=%s\n", resp.NextPageToken)
+		}
+	},
+}
+
+var getPipelineCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Show a pipeline's settings and status",
+	Run: func(cmd *cobra.Command, args []string) {
+		pipelineID, _ := cmd.Flags().GetString("pipeline-id")
+		if pipelineID == "" {
+			log.Fatal("❌ Pipeline ID is required.")
+		}
+		conn, err := dialServer()
+		if err != nil {
+			log.Fatalf("❌ Failed to connect to gRPC server: %v", err)
+		}
+		defer conn.Close()
+
+		client := proto.NewPipelineServiceClient(conn)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		pipeline, err := client.GetPipeline(ctx, &proto.GetPipelineRequest{PipelineId: pipelineID})
+		if err != nil {
+			log.Fatalf("❌ Failed to get pipeline: %v", err)
+		}
+		mode := "sequential"
+		if pipeline.IsParallel {
+			mode = "parallel"
+		}
+		timeout := "none"
+		if pipeline.TimeoutMs > 0 {
+			timeout = (time.Duration(pipeline.TimeoutMs) * time.Millisecond).String()
+		}
+		fmt.Println("------------------------------")
+		fmt.Printf("🆔 Pipeline ID: %s\n", pipeline.PipelineId)
+		fmt.Printf("🏷️ Name: %s\n", pipeline.PipelineName)
+		fmt.Printf("👤 Owner: %s\n", pipeline.UserId)
+		fmt.Printf("📌 Status: %s\n", pipeline.Status)
+		fmt.Printf("⚙️ Mode: %s, on failure: %s, timeout: %s\n", mode, pipeline.FailurePolicy, timeout)
+		fmt.Printf("🕒 Created: %s, updated: %s\n", pipeline.CreatedAt, pipeline.UpdatedAt)
+		fmt.Println("------------------------------")
+	},
+}
+
+var getPipelineStagesCmd = &cobra.Command{
+	Use:   "stages",
+	Short: "List the stages of a pipeline with their status",
+	Run: func(cmd *cobra.Command, args []string) {
+		pipelineID, _ := cmd.Flags().GetString("pipeline-id")
+		runID, _ := cmd.Flags().GetString("run-id")
+		if pipelineID == "" {
+			log.Fatal("❌ Pipeline ID is required.")
+		}
+		conn, err := dialServer()
+		if err != nil {
+			log.Fatalf("❌ Failed to connect to gRPC server: %v", err)
+		}
+		defer conn.Close()
+
+		client := proto.NewPipelineServiceClient(conn)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		resp, err := client.GetPipelineStages(ctx, &proto.GetPipelineStagesRequest{
+			PipelineId: pipelineID,
+			RunId:      runID,
+		})
+		if err != nil {
+			log.Fatalf("❌ Failed to get pipeline stages: %v", err)
+		}
+		for _, stage := range resp.Stages {
+			fmt.Printf("%s %-20s %-10s %-10s %s\n", stageStatusIcon(stage.Status), stage.StageName, stage.StageType, stage.Status, stage.ErrorMsg)
+		}
+	},
+}
+
+var deletePipelineCmd = &cobra.Command{
+	Use:   "delete",
+	Short: "Delete a pipeline with its stages and runs",
+	Run: func(cmd *cobra.Command, args []string) {
+		pipelineID, _ := cmd.Flags().GetString("pipeline-id")
+		if pipelineID == "" {
+			log.Fatal("❌ Pipeline ID is required.")
+		}
+		conn, err := dialServer()
+		if err != nil {
+			log.Fatalf("❌ Failed to connect to gRPC server: %v", err)
+		}
+		defer conn.Close()
+
+		client := proto.NewPipelineServiceClient(conn)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		resp, err := client.DeletePipeline(ctx, &proto.DeletePipelineRequest{PipelineId: pipelineID})
+		if err != nil {
+			log.Fatalf("❌ Failed to delete pipeline: %v", err)
+		}
+		fmt.Printf("🗑️ %s\n", resp.Message)
+	},
+}
+
+// parseStageDependencies turns "assembly=machining+painting,packing=assembly"
+// into stage definitions for the given stage names.
+func parseStageDependencies(stageNames []string, dependsOn string) ([]*proto.StageDefinition, error) {
+	if dependsOn == "" {
+		return nil, nil
+	}
+
+	upstream := make(map[string][]string)
+	for _, entry := range strings.Split(dependsOn, ",") {
+		stage, deps, ok := strings.Cut(entry, "=")
+		if !ok || strings.TrimSpace(stage) == "" || strings.TrimSpace(deps) == "" {
+			return nil, fmt.Errorf("invalid dependency %q, expected stage=upstream1+upstream2", entry)
+		}
+		for _, dep := range strings.Split(deps, "+") {
+			upstream[strings.TrimSpace(stage)] = append(upstream[strings.TrimSpace(stage)], strings.TrimSpace(dep))
+		}
+	}
+
+	definitions := make([]*proto.StageDefinition, len(stageNames))
+	for i, name := range stageNames {
+		definitions[i] = &proto.StageDefinition{Name: name, DependsOn: upstream[name]}
+	}
+	return definitions, nil
+}
+
+// readStageDefinitions loads a JSON array of stage definitions, in the same
+// shape as the REST API's stage_definitions field.
+func readStageDefinitions(path string) ([]*proto.StageDefinition, error) {
+	data, err := os.ReadFile(path)
+	if err != nil {
+		return nil, fmt.Errorf("failed to read stage definitions: %w", err)
+	}
+
+	var req proto.CreatePipelineRequest
+	wrapped := append(append([]byte(`{"stage_definitions":`), data...), '}')
+	if err := protojson.Unmarshal(wrapped, &req); err != nil {
+		return nil, fmt.Errorf("failed to parse stage definitions: %w", err)
+	}
+	return req.StageDefinitions, nil
+}
+
 func init() {
 	pipelineCmd.AddCommand(createPipelineCmd)
 	pipelineCmd.AddCommand(startPipelineCmd)
 	pipelineCmd.AddCommand(cancelPipelineCmd)
 	pipelineCmd.AddCommand(getPipelineStatusCmd)
+	pipelineCmd.AddCommand(getPipelineOutputCmd)
+	pipelineCmd.AddCommand(listPipelineRunsCmd)
+	pipelineCmd.AddCommand(watchPipelineCmd)
+	pipelineCmd.AddCommand(listPipelinesCmd)
+	pipelineCmd.AddCommand(getPipelineCmd)
+	pipelineCmd.AddCommand(getPipelineStagesCmd)
+	pipelineCmd.AddCommand(deletePipelineCmd)
 
-	createPipelineCmd.Flags().String("user", "", "User ID")
 	createPipelineCmd.Flags().String("pipeline-name", "", "Pipeline Name")
 	createPipelineCmd.Flags().Int("stages", 0, "Number of stages (required)")
 	createPipelineCmd.Flags().Bool("parallel", true, "Parallel execution")
 	createPipelineCmd.Flags().String("stage-names", "", "Comma-separated list of stage names")
+	createPipelineCmd.Flags().String("depends-on", "", "Stage dependencies, e.g. assembly=machining+painting,packing=assembly")
+	createPipelineCmd.Flags().String("on-failure", "halt", "What to do when a stage fails: halt, continue or rollback")
+	createPipelineCmd.Flags().String("stage-definitions-file", "", "JSON file with stage definitions (dependencies, retry policies, timeouts), used instead of --stage-names")
+	createPipelineCmd.Flags().Duration("timeout", 0, "Maximum duration of a pipeline run, e.g. 5m (0 means no limit)")
 
-	createPipelineCmd.MarkFlagRequired("user")
 	createPipelineCmd.MarkFlagRequired("pipeline-name")
 	createPipelineCmd.MarkFlagRequired("stages")
-	createPipelineCmd.MarkFlagRequired("stage-names")
 
 	startPipelineCmd.Flags().String("pipeline-id", "", "Pipeline ID")
-	startPipelineCmd.Flags().String("user-id", "", "User ID")
 	startPipelineCmd.Flags().String("input", "", "Input for pipeline")
-	startPipelineCmd.Flags().Bool("parallel", true, "Run in parallel mode")
+	startPipelineCmd.Flags().Bool("parallel", true, "Run in parallel mode (defaults to the mode chosen at creation)")
 	startPipelineCmd.MarkFlagRequired("pipeline-id")
-	startPipelineCmd.MarkFlagRequired("user-id")
+
+	cancelPipelineCmd.Flags().String("pipeline-id", "", "Pipeline ID")
+	cancelPipelineCmd.Flags().Bool("parallel", false, "Parallel execution")
+	cancelPipelineCmd.Flags().String("run-id", "", "Run ID (defaults to the active run)")
+	cancelPipelineCmd.MarkFlagRequired("pipeline-id")
 
 	getPipelineStatusCmd.Flags().String("pipeline-id", "", "Pipeline ID")
 	getPipelineStatusCmd.Flags().Bool("parallel", false, "Check parallel pipeline status")
+	getPipelineStatusCmd.Flags().String("run-id", "", "Run ID (defaults to the latest run)")
 	getPipelineStatusCmd.MarkFlagRequired("pipeline-id")
 
+	getPipelineOutputCmd.Flags().String("pipeline-id", "", "Pipeline ID")
+	getPipelineOutputCmd.Flags().String("run-id", "", "Run ID (defaults to the latest run)")
+	getPipelineOutputCmd.MarkFlagRequired("pipeline-id")
+
+	listPipelineRunsCmd.Flags().String("pipeline-id", "", "Pipeline ID")
+	listPipelineRunsCmd.MarkFlagRequired("pipeline-id")
+
+	watchPipelineCmd.Flags().String("pipeline-id", "", "Pipeline ID")
+	watchPipelineCmd.MarkFlagRequired("pipeline-id")
+
+	listPipelinesCmd.Flags().Int32("page-size", 0, "Pipelines per page (defaults to 50, at most 500)")
+	listPipelinesCmd.Flags().String("page-token", "", "Token of the page to list, printed with the previous page")
+
+	getPipelineCmd.Flags().String("pipeline-id", "", "Pipeline ID")
+	getPipelineCmd.MarkFlagRequired("pipeline-id")
+
+	getPipelineStagesCmd.Flags().String("pipeline-id", "", "Pipeline ID")
+	getPipelineStagesCmd.Flags().String("run-id", "", "Run ID (defaults to the latest run)")
+	getPipelineStagesCmd.MarkFlagRequired("pipeline-id")
+
+	deletePipelineCmd.Flags().String("pipeline-id", "", "Pipeline ID")
+	deletePipelineCmd.MarkFlagRequired("pipeline-id")
+
 }
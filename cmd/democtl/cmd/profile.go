@@ -0,0 +1,83 @@
+package cmd
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"time"
+
+	proto "github.com/sarika-p9/my-pipeline-project/api/grpc/proto/authentication"
+	"github.com/spf13/cobra"
+)
+
+var profileCmd = &cobra.Command{
+	Use:   "profile",
+	Short: "Show or update your profile",
+}
+
+var getProfileCmd = &cobra.Command{
+	Use:   "get",
+	Short: "Show your profile, or another user's",
+	Run: func(cmd *cobra.Command, args []string) {
+		userID, _ := cmd.Flags().GetString("user-id")
+		conn, err := dialServer()
+		if err != nil {
+			log.Fatalf("❌ Failed to connect to gRPC server: %v", err)
+		}
+		defer conn.Close()
+
+		client := proto.NewAuthServiceClient(conn)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		profile, err := client.GetUserProfile(ctx, &proto.GetUserProfileRequest{UserId: userID})
+		if err != nil {
+			log.Fatalf("❌ Failed to get profile: %v", err)
+		}
+		fmt.Printf("👤 Name: %s\nEmail: %s\nRole: %s\n", profile.Name, profile.Email, profile.Role)
+	},
+}
+
+var updateProfileCmd = &cobra.Command{
+	Use:   "update",
+	Short: "Update your name, or another user's name or role",
+	Run: func(cmd *cobra.Command, args []string) {
+		userID, _ := cmd.Flags().GetString("user-id")
+		req := &proto.UpdateUserProfileRequest{UserId: userID}
+		if cmd.Flags().Changed("name") {
+			name, _ := cmd.Flags().GetString("name")
+			req.Name = &name
+		}
+		if cmd.Flags().Changed("role") {
+			role, _ := cmd.Flags().GetString("role")
+			req.Role = &role
+		}
+		if req.Name == nil && req.Role == nil {
+			log.Fatal("❌ Set --name or --role.")
+		}
+		conn, err := dialServer()
+		if err != nil {
+			log.Fatalf("❌ Failed to connect to gRPC server: %v", err)
+		}
+		defer conn.Close()
+
+		client := proto.NewAuthServiceClient(conn)
+		ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+		defer cancel()
+		resp, err := client.UpdateUserProfile(ctx, req)
+		if err != nil {
+			log.Fatalf("❌ Failed to update profile: %v", err)
+		}
+		fmt.Printf("✅ %s\n", resp.Message)
+	},
+}
+
+func init() {
+	profileCmd.AddCommand(getProfileCmd)
+	profileCmd.AddCommand(updateProfileCmd)
+
+	getProfileCmd.Flags().String("user-id", "", "User ID (defaults to you; admins only for other users)")
+
+	updateProfileCmd.Flags().String("user-id", "", "User ID (defaults to you; admins only for other users)")
+	updateProfileCmd.Flags().String("name", "", "New name")
+	updateProfileCmd.Flags().String("role", "", "New role: super_admin, admin, manager or worker (admins only)")
+}
@@ -25,6 +25,10 @@ func init() {
 	rootCmd.AddCommand(startCmd)
 	rootCmd.AddCommand(registerCmd)
 	rootCmd.AddCommand(loginCmd)
+	rootCmd.AddCommand(logoutCmd)
+	rootCmd.AddCommand(profileCmd)
 	rootCmd.AddCommand(pipelineCmd)
+	rootCmd.AddCommand(dlqCmd)
+	rootCmd.AddCommand(eventsCmd)
 
 }
@@ -0,0 +1,184 @@
+package cmd
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"os"
+	"os/signal"
+	"strings"
+	"text/tabwriter"
+
+	proto "github.com/sarika-p9/my-pipeline-project/api/grpc/proto/pipeline"
+	"github.com/spf13/cobra"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+var watchPipelineCmd = &cobra.Command{
+	Use:   "watch",
+	Short: "Follow the stages of a pipeline's run live",
+	Long: `Shows the stages of the pipeline's latest run and follows them until the run
+finishes. Exits with status 1 unless the run completes.`,
+	Run: func(cmd *cobra.Command, args []string) {
+		pipelineID, _ := cmd.Flags().GetString("pipeline-id")
+		if pipelineID == "" {
+			log.Fatal("❌ Pipeline ID is required.")
+		}
+		conn, err := dialServer()
+		if err != nil {
+			log.Fatalf("❌ Failed to connect to gRPC server: %v", err)
+		}
+		defer conn.Close()
+
+		client := proto.NewPipelineServiceClient(conn)
+		ctx, stop := signal.NotifyContext(context.Background(), os.Interrupt)
+		defer stop()
+
+		table := newStageTable(os.Stdout)
+		for {
+			err := watchStages(ctx, client, pipelineID, table)
+			if errors.Is(err, errResync) || (status.Code(err) == codes.Unavailable && table.lastSeq > 0) {
+				continue
+			}
+			if ctx.Err() != nil {
+				return
+			}
+			if err != nil {
+				log.Fatalf("❌ Failed to watch pipeline: %v", err)
+			}
+			break
+		}
+
+		switch table.runStatus {
+		case "":
+			fmt.Println("ℹ️ This pipeline has not been run yet.")
+		case "Completed":
+			fmt.Printf("✅ Run %s completed.\n", table.runID)
+		default:
+			fmt.Printf("❌ Run %s ended %s.\n", table.runID, table.runStatus)
+			os.Exit(1)
+		}
+	},
+}
+
+// errResync is returned by watchStages when the missed updates are gone and
+// the watch has to start over from the run's state.
+var errResync = errors.New("resync")
+
+// watchStages follows the pipeline until the server ends the stream. It
+// resumes after the last update the table has seen, if any.
+func watchStages(ctx context.Context, client proto.PipelineServiceClient, pipelineID string, table *stageTable) error {
+	stream, err := client.WatchPipeline(ctx, &proto.WatchPipelineRequest{
+		PipelineId: pipelineID,
+		LastSeq:    table.lastSeq,
+	})
+	if err != nil {
+		return err
+	}
+	for {
+		event, err := stream.Recv()
+		if err == io.EOF {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+		if event.Type == "resync" {
+			table.lastSeq = 0
+			return errResync
+		}
+		table.apply(event)
+	}
+}
+
+// stageTable shows the stages of the run being watched. On a terminal it is
+// redrawn in place; otherwise every change is printed on its own line.
+type stageTable struct {
+	out       io.Writer
+	redraw    bool
+	lines     int // Lines drawn last time
+	lastSeq   int64
+	runID     string
+	runStatus string
+	stageIDs  []string // In the order they were first seen
+	names     map[string]string
+	statuses  map[string]string
+}
+
+func newStageTable(out *os.File) *stageTable {
+	info, err := out.Stat()
+	return &stageTable{
+		out:      out,
+		redraw:   err == nil && info.Mode()&os.ModeCharDevice != 0,
+		names:    make(map[string]string),
+		statuses: make(map[string]string),
+	}
+}
+
+func (t *stageTable) apply(event *proto.PipelineEvent) {
+	if event.Seq > t.lastSeq {
+		t.lastSeq = event.Seq
+	}
+	if event.RunId != t.runID {
+		t.runID = event.RunId
+		t.runStatus = ""
+		t.stageIDs = nil
+		t.names = make(map[string]string)
+		t.statuses = make(map[string]string)
+	}
+
+	switch event.Type {
+	case "run_update":
+		t.runStatus = event.Status
+		if !t.redraw {
+			fmt.Fprintf(t.out, "🏃 Run %s: %s\n", event.RunId, event.Status)
+		}
+	case "stage_update":
+		if _, seen := t.statuses[event.StageId]; !seen {
+			t.stageIDs = append(t.stageIDs, event.StageId)
+		}
+		t.names[event.StageId] = event.StageName
+		t.statuses[event.StageId] = event.Status
+		if !t.redraw {
+			fmt.Fprintf(t.out, "%s %-20s %s\n", stageStatusIcon(event.Status), event.StageName, event.Status)
+		}
+	}
+	if t.redraw {
+		t.draw()
+	}
+}
+
+func (t *stageTable) draw() {
+	var b strings.Builder
+	fmt.Fprintf(&b, "🏃 Run %s: %s\n", t.runID, t.runStatus)
+	w := tabwriter.NewWriter(&b, 0, 0, 2, ' ', 0)
+	fmt.Fprintln(w, "STAGE\tSTATUS")
+	for _, stageID := range t.stageIDs {
+		fmt.Fprintf(w, "%s\t%s %s\n", t.names[stageID], stageStatusIcon(t.statuses[stageID]), t.statuses[stageID])
+	}
+	w.Flush()
+
+	if t.lines > 0 {
+		fmt.Fprintf(t.out, "\033[%dA\033[J", t.lines) // Move up and clear the previous table
+	}
+	fmt.Fprint(t.out, b.String())
+	t.lines = strings.Count(b.String(), "\n")
+}
+
+func stageStatusIcon(status string) string {
+	switch status {
+	case "Completed":
+		return "✅"
+	case "Running", "Retrying", "RollingBack":
+		return "🔄"
+	case "Pending":
+		return "⏳"
+	case "Skipped", "RolledBack":
+		return "⏭️"
+	default:
+		return "❌"
+	}
+}
@@ -70,49 +70,90 @@ const CreatePipeline = () => {
   useEffect(() => {
     if (!selectedPipelineId) return;
 
-    const ws = new WebSocket("ws://localhost:30002/ws");
+    const token = localStorage.getItem("token");
+    let ws;
+    let lastSeq = 0; // ✅ Last update seen, to catch up after reconnecting
+    let closed = false;
+
+    const connect = () => {
+        ws = new WebSocket(`ws://localhost:30002/ws?token=${encodeURIComponent(token)}`);
+
+        ws.onopen = () => {
+            console.log("✅ WebSocket Connected");
+            const subscribe = { action: "subscribe", pipeline_ids: [selectedPipelineId] };
+            if (lastSeq > 0) {
+                subscribe.last_seq = { [selectedPipelineId]: lastSeq };
+            }
+            ws.send(JSON.stringify(subscribe));
+        };
 
-    ws.onopen = () => console.log("✅ WebSocket Connected");
+        ws.onmessage = async (event) => {
+            try {
+                const data = JSON.parse(event.data);
+                console.log("🔄 WebSocket Data Received:", data);
 
-    ws.onmessage = (event) => {
-        try {
-            const data = JSON.parse(event.data);
-            console.log("🔄 WebSocket Data Received:", data);
+                if (data.type === "error") {
+                    console.error("❌ WebSocket Error:", data.error);
+                    return;
+                }
 
-            // ✅ Ensure updates are only for the selected pipeline
-            if (data.pipelineId !== selectedPipelineId) return;
+                // ✅ Missed updates are gone, reload the stages instead
+                if (data.type === "resync" && data.pipeline_id === selectedPipelineId) {
+                    const response = await authAxios.get(`/pipelines/${selectedPipelineId}/stages`);
+                    if (Array.isArray(response.data)) {
+                        setSelectedPipelineStages(response.data);
+                    }
+                    return;
+                }
 
-            setSelectedPipelineStages((prevStages) =>
-                prevStages.map((stage) =>
-                    stage.StageID === data.stageId
-                        ? { ...stage, Status: data.status }
-                        : stage
-                ).sort((a, b) => a.StageID.localeCompare(b.StageID))
-            );
+                // ✅ Ensure updates are only for the selected pipeline
+                if (data.type !== "stage_update" || data.pipeline_id !== selectedPipelineId) return;
+                if (data.seq) {
+                    lastSeq = Math.max(lastSeq, data.seq);
+                }
 
-            setTimeout(async () => {
-                const allCompleted = selectedPipelineStages.every(
-                    (stage) => stage.Status === "Completed"
+                setSelectedPipelineStages((prevStages) =>
+                    prevStages.map((stage) =>
+                        stage.StageID === data.stage_id
+                            ? { ...stage, Status: data.status }
+                            : stage
+                    ).sort((a, b) => a.StageID.localeCompare(b.StageID))
                 );
 
-                if (allCompleted) {
-                    console.log("✅ All Stages Completed! Updating Pipeline Status...");
-                    await fetchAndUpdatePipelineStatus();
-                    setTimeout(() => {
-                        setOpenStageModal(false); // ✅ Auto-close modal if needed
-                    }, 1000);
-                }
-            }, 500);
-        } catch (error) {
-            console.error("❌ WebSocket Error:", event.data, error);
-        }
-    };
+                setTimeout(async () => {
+                    const allCompleted = selectedPipelineStages.every(
+                        (stage) => stage.Status === "Completed"
+                    );
+
+                    if (allCompleted) {
+                        console.log("✅ All Stages Completed! Updating Pipeline Status...");
+                        await fetchAndUpdatePipelineStatus();
+                        setTimeout(() => {
+                            setOpenStageModal(false); // ✅ Auto-close modal if needed
+                        }, 1000);
+                    }
+                }, 500);
+            } catch (error) {
+                console.error("❌ WebSocket Error:", event.data, error);
+            }
+        };
 
-    ws.onerror = (error) => console.error("❌ WebSocket Error:", error);
-    ws.onclose = () => console.log("🔴 WebSocket Disconnected");
+        ws.onerror = (error) => console.error("❌ WebSocket Error:", error);
+        ws.onclose = () => {
+            console.log("🔴 WebSocket Disconnected");
+            if (!closed) {
+                setTimeout(connect, 2000); // ✅ Reconnect and catch up
+            }
+        };
 
-    setSocket(ws);
-    return () => ws.close();
+        setSocket(ws);
+    };
+
+    connect();
+    return () => {
+        closed = true;
+        ws.close();
+    };
 }, [selectedPipelineId]);
 
 
@@ -342,7 +383,7 @@ const handleShowStages = async (pipelineID) => {
     if (!window.confirm("Are you sure you want to delete this pipeline?")) return;
   
     try {
-      await axios.delete(`http://localhost:30002/api/pipelines/${pipelineId}`);
+      await authAxios.delete(`/api/pipelines/${pipelineId}`);
       
       setPipelines(pipelines.filter(pipeline => pipeline.PipelineID !== pipelineId));
     } catch (error) {
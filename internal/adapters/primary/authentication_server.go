@@ -5,8 +5,12 @@ import (
 	"errors"
 	"log"
 
+	"github.com/google/uuid"
 	proto "github.com/sarika-p9/my-pipeline-project/api/grpc/proto/authentication"
+	"github.com/sarika-p9/my-pipeline-project/internal/middleware"
 	"github.com/sarika-p9/my-pipeline-project/internal/services"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
 )
 
 type AuthServer struct {
@@ -53,3 +57,72 @@ func (s *AuthServer) Logout(ctx context.Context, req *proto.LogoutRequest) (*pro
 		Message: "Logout successful",
 	}, nil
 }
+
+func (s *AuthServer) GetUserProfile(ctx context.Context, req *proto.GetUserProfileRequest) (*proto.UserProfile, error) {
+	userID, err := s.profileUserID(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	user, err := s.AuthService.GetUserByID(userID)
+	if err != nil {
+		return nil, status.Error(codes.NotFound, "User not found")
+	}
+
+	return &proto.UserProfile{
+		UserId: user.UserID.String(),
+		Name:   user.Name,
+		Email:  user.Email,
+		Role:   user.Role,
+	}, nil
+}
+
+func (s *AuthServer) UpdateUserProfile(ctx context.Context, req *proto.UpdateUserProfileRequest) (*proto.UpdateUserProfileResponse, error) {
+	userID, err := s.profileUserID(ctx, req.UserId)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make(map[string]interface{})
+	if req.Name != nil {
+		updates["name"] = req.GetName()
+	}
+	if req.Role != nil {
+		callerID, _ := middleware.UserIDFromContext(ctx)
+		if !s.AuthService.IsAdmin(callerID) {
+			return nil, status.Error(codes.PermissionDenied, "Only admins can change roles")
+		}
+		updates["role"] = req.GetRole()
+	}
+	if len(updates) == 0 {
+		return nil, status.Error(codes.InvalidArgument, "Nothing to update")
+	}
+
+	if err := s.AuthService.UpdateUser(userID, updates); err != nil {
+		log.Println("Update user error:", err)
+		return nil, status.Error(codes.Internal, "Failed to update user")
+	}
+
+	return &proto.UpdateUserProfileResponse{Message: "User updated successfully"}, nil
+}
+
+// profileUserID returns the user whose profile a call is about: the caller
+// when userID is empty. Only admins may name other users.
+func (s *AuthServer) profileUserID(ctx context.Context, userID string) (uuid.UUID, error) {
+	callerID, ok := middleware.UserIDFromContext(ctx)
+	if !ok {
+		return uuid.Nil, status.Error(codes.Unauthenticated, "Authorization token is required")
+	}
+	if userID == "" {
+		return callerID, nil
+	}
+
+	parsed, err := uuid.Parse(userID)
+	if err != nil {
+		return uuid.Nil, status.Errorf(codes.InvalidArgument, "Invalid user ID: %v", err)
+	}
+	if parsed != callerID && !s.AuthService.IsAdmin(callerID) {
+		return uuid.Nil, status.Error(codes.PermissionDenied, "Only admins can access other users' profiles")
+	}
+	return parsed, nil
+}
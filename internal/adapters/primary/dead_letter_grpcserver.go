@@ -0,0 +1,77 @@
+package primary
+
+import (
+	"context"
+	"errors"
+	"time"
+
+	proto "github.com/sarika-p9/my-pipeline-project/api/grpc/proto/pipeline"
+	"github.com/sarika-p9/my-pipeline-project/internal/core/ports"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/status"
+)
+
+func (s *PipelineServer) ListDeadLetters(ctx context.Context, req *proto.ListDeadLettersRequest) (*proto.ListDeadLettersResponse, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	deadLetters, err := s.DeadLetters.ListDeadLetters(ctx, userID, req.Queue)
+	if err != nil {
+		return nil, deadLetterStatus("list", err)
+	}
+
+	resp := &proto.ListDeadLettersResponse{DeadLetters: make([]*proto.DeadLetter, len(deadLetters))}
+	for i, deadLetter := range deadLetters {
+		resp.DeadLetters[i] = &proto.DeadLetter{
+			Id:            deadLetter.Message.ID,
+			Queue:         deadLetter.Queue,
+			Data:          deadLetter.Message.Data,
+			CorrelationId: deadLetter.Message.CorrelationID,
+			Retries:       int32(deadLetter.Message.Retries),
+			Reason:        deadLetter.Reason,
+		}
+		if !deadLetter.DeadAt.IsZero() {
+			resp.DeadLetters[i].DeadAt = deadLetter.DeadAt.Format(time.RFC3339)
+		}
+	}
+	return resp, nil
+}
+
+func (s *PipelineServer) ReplayDeadLetters(ctx context.Context, req *proto.ReplayDeadLettersRequest) (*proto.ReplayDeadLettersResponse, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	replayed, err := s.DeadLetters.ReplayDeadLetters(ctx, userID, req.Queue, req.Ids)
+	if err != nil {
+		return nil, deadLetterStatus("replay", err)
+	}
+	return &proto.ReplayDeadLettersResponse{Replayed: int32(replayed)}, nil
+}
+
+func (s *PipelineServer) PurgeDeadLetters(ctx context.Context, req *proto.PurgeDeadLettersRequest) (*proto.PurgeDeadLettersResponse, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	purged, err := s.DeadLetters.PurgeDeadLetters(ctx, userID, req.Queue, req.Ids)
+	if err != nil {
+		return nil, deadLetterStatus("purge", err)
+	}
+	return &proto.PurgeDeadLettersResponse{Purged: int32(purged)}, nil
+}
+
+func deadLetterStatus(action string, err error) error {
+	switch {
+	case errors.Is(err, ports.ErrPermissionDenied):
+		return status.Error(codes.PermissionDenied, err.Error())
+	case errors.Is(err, ports.ErrDeadLettersUnsupported):
+		return status.Error(codes.Unimplemented, err.Error())
+	default:
+		return status.Errorf(codes.Internal, "Failed to %s dead letters: %v", action, err)
+	}
+}
@@ -2,26 +2,35 @@ package primary
 
 import (
 	"context"
+	"errors"
 	"log"
+	"time"
 
 	"github.com/google/uuid"
 	proto "github.com/sarika-p9/my-pipeline-project/api/grpc/proto/pipeline"
+	"github.com/sarika-p9/my-pipeline-project/internal/core/domain"
+	"github.com/sarika-p9/my-pipeline-project/internal/core/ports"
+	"github.com/sarika-p9/my-pipeline-project/internal/infrastructure"
+	"github.com/sarika-p9/my-pipeline-project/internal/middleware"
+	"github.com/sarika-p9/my-pipeline-project/internal/models"
 	"github.com/sarika-p9/my-pipeline-project/internal/services"
 	"google.golang.org/grpc/codes"
 	"google.golang.org/grpc/status"
+	"google.golang.org/protobuf/encoding/protojson"
 	"google.golang.org/protobuf/types/known/structpb"
 	"google.golang.org/protobuf/types/known/wrapperspb"
 )
 
 type PipelineServer struct {
 	proto.UnimplementedPipelineServiceServer
-	Service *services.PipelineService
+	Service     *services.PipelineService
+	DeadLetters *services.DeadLetterService
 }
 
 func (s *PipelineServer) CreatePipeline(ctx context.Context, req *proto.CreatePipelineRequest) (*proto.CreatePipelineResponse, error) {
-	userID, err := uuid.Parse(req.UserId)
+	userID, err := callerID(ctx)
 	if err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, "Invalid user ID: %v", err)
+		return nil, err
 	}
 
 	pipelineName := req.PipelineName
@@ -38,7 +47,43 @@ func (s *PipelineServer) CreatePipeline(ctx context.Context, req *proto.CreatePi
 		}
 	}
 
-	pipelineID, err := s.Service.CreatePipeline(userID, pipelineName, int(req.Stages), stageNames)
+	stages := domain.StageDefinitionsFromNames(stageNames)
+	if len(req.StageDefinitions) > 0 {
+		stages = make([]domain.StageDefinition, len(req.StageDefinitions))
+		for i, def := range req.StageDefinitions {
+			name := def.Name
+			if name == "" {
+				name = "Untitled Stage"
+			}
+			stages[i] = domain.StageDefinition{Name: name, Type: def.Type, DependsOn: def.DependsOn, TimeoutMs: def.TimeoutMs}
+			if def.Config != nil {
+				config, err := protojson.Marshal(def.Config)
+				if err != nil {
+					return nil, status.Errorf(codes.InvalidArgument, "Invalid config for stage %s: %v", name, err)
+				}
+				stages[i].Config = config
+			}
+			if retry := def.Retry; retry != nil {
+				stages[i].Retry = &domain.RetryPolicy{
+					MaxAttempts:      int(retry.MaxAttempts),
+					InitialBackoffMs: retry.InitialBackoffMs,
+					Multiplier:       retry.Multiplier,
+					MaxBackoffMs:     retry.MaxBackoffMs,
+					Jitter:           retry.Jitter,
+					RetryableErrors:  retry.RetryableErrors,
+				}
+			}
+		}
+	}
+
+	pipelineID, err := s.Service.CreatePipeline(userID, pipelineName, stages, services.PipelineOptions{
+		IsParallel:    req.IsParallel,
+		FailurePolicy: req.FailurePolicy,
+		TimeoutMs:     req.TimeoutMs,
+	})
+	if domain.IsInvalidDefinition(err) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Failed to create pipeline: %v", err)
 	}
@@ -55,15 +100,12 @@ func (s *PipelineServer) StartPipeline(ctx context.Context, req *proto.StartPipe
 		return nil, status.Errorf(codes.InvalidArgument, "Invalid pipeline ID: %v", err)
 	}
 
-	userID, err := uuid.Parse(req.UserId)
+	userID, err := callerID(ctx)
 	if err != nil {
-		log.Printf("[ERROR] Invalid user ID: %v", err)
-		return nil, status.Errorf(codes.InvalidArgument, "Invalid user ID: %v", err)
+		return nil, err
 	}
-
-	if userID == uuid.Nil {
-		log.Println("[ERROR] User ID is required but received nil")
-		return nil, status.Error(codes.InvalidArgument, "User ID is required")
+	if err := s.Service.CanViewPipeline(userID, pipelineID); err != nil {
+		return nil, pipelineAccessError("start pipeline", err)
 	}
 
 	var input interface{}
@@ -88,28 +130,55 @@ func (s *PipelineServer) StartPipeline(ctx context.Context, req *proto.StartPipe
 		log.Println("[DEBUG] No input data provided, using nil")
 	}
 
-	go func() {
-		log.Printf("[INFO] Starting pipeline execution: %s", pipelineID)
-		err := s.Service.StartPipeline(context.Background(), userID, pipelineID, input)
-		if err != nil {
-			log.Printf("[ERROR] Pipeline execution failed for %s: %v", pipelineID, err)
-		} else {
-			log.Printf("[INFO] Pipeline execution completed successfully: %s", pipelineID)
-		}
-	}()
+	log.Printf("[INFO] Starting pipeline execution: %s", pipelineID)
+	runID, err := s.Service.StartPipeline(context.Background(), userID, pipelineID, input, req.IsParallel)
+	switch {
+	case errors.Is(err, domain.ErrRunInProgress):
+		return nil, status.Error(codes.FailedPrecondition, "Pipeline is already running")
+	case errors.Is(err, ports.ErrPipelineNotFound):
+		return nil, status.Error(codes.NotFound, "Pipeline not found")
+	case err != nil:
+		log.Printf("[ERROR] Failed to start pipeline %s: %v", pipelineID, err)
+		return nil, status.Errorf(codes.Internal, "Failed to start pipeline: %v", err)
+	}
 
 	return &proto.StartPipelineResponse{
 		Message: "Pipeline execution started",
+		RunId:   runID.String(),
 	}, nil
 }
 
+// parseRunID parses an optional run ID. An empty string selects the latest
+// or active run.
+func parseRunID(runID string) (uuid.UUID, error) {
+	if runID == "" {
+		return uuid.Nil, nil
+	}
+	parsed, err := uuid.Parse(runID)
+	if err != nil {
+		return uuid.Nil, status.Errorf(codes.InvalidArgument, "Invalid run ID: %v", err)
+	}
+	return parsed, nil
+}
+
 func (s *PipelineServer) GetPipelineStatus(ctx context.Context, req *proto.GetPipelineStatusRequest) (*proto.GetPipelineStatusResponse, error) {
 	pipelineID, err := uuid.Parse(req.PipelineId)
 	if err != nil {
 		return nil, status.Errorf(codes.InvalidArgument, "Invalid pipeline ID: %v", err)
 	}
+	if err := s.canView(ctx, pipelineID, "get pipeline status"); err != nil {
+		return nil, err
+	}
 
-	stat, err := s.Service.GetPipelineStatus(pipelineID)
+	runID, err := parseRunID(req.RunId)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, err := s.Service.GetPipelineStatus(pipelineID, runID)
+	if errors.Is(err, ports.ErrPipelineNotFound) || errors.Is(err, ports.ErrRunNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
 	if err != nil {
 		return nil, status.Errorf(codes.Internal, "Failed to get pipeline status: %v", err)
 	}
@@ -117,6 +186,41 @@ func (s *PipelineServer) GetPipelineStatus(ctx context.Context, req *proto.GetPi
 	return &proto.GetPipelineStatusResponse{
 		PipelineId: pipelineID.String(),
 		Status:     stat,
+		RunId:      req.RunId,
+	}, nil
+}
+
+func (s *PipelineServer) GetPipelineOutput(ctx context.Context, req *proto.GetPipelineOutputRequest) (*proto.GetPipelineOutputResponse, error) {
+	pipelineID, err := uuid.Parse(req.PipelineId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid pipeline ID: %v", err)
+	}
+	if err := s.canView(ctx, pipelineID, "get pipeline output"); err != nil {
+		return nil, err
+	}
+
+	runID, err := parseRunID(req.RunId)
+	if err != nil {
+		return nil, err
+	}
+
+	stat, output, err := s.Service.GetPipelineOutput(pipelineID, runID)
+	if errors.Is(err, ports.ErrPipelineNotFound) || errors.Is(err, ports.ErrRunNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to get pipeline output: %v", err)
+	}
+
+	outputValue, err := structpb.NewValue(output)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to encode pipeline output: %v", err)
+	}
+
+	return &proto.GetPipelineOutputResponse{
+		PipelineId: pipelineID.String(),
+		Status:     stat,
+		Output:     outputValue,
 	}, nil
 }
 
@@ -126,12 +230,29 @@ func (s *PipelineServer) CancelPipeline(ctx context.Context, req *proto.CancelPi
 		return nil, status.Errorf(codes.InvalidArgument, "Invalid pipeline ID: %v", err)
 	}
 
-	userID, err := uuid.Parse(req.UserId)
+	userID, err := callerID(ctx)
 	if err != nil {
-		return nil, status.Errorf(codes.InvalidArgument, "Invalid user ID: %v", err)
+		return nil, err
+	}
+	if err := s.Service.CanViewPipeline(userID, pipelineID); err != nil {
+		return nil, pipelineAccessError("cancel pipeline", err)
 	}
 
-	err = s.Service.CancelPipeline(pipelineID, userID)
+	runID, err := parseRunID(req.RunId)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Service.CancelPipeline(pipelineID, runID, userID)
+	if errors.Is(err, domain.ErrRunFinishing) {
+		return nil, status.Error(codes.FailedPrecondition, "Pipeline is already finishing")
+	}
+	if errors.Is(err, domain.ErrRunFinished) {
+		return nil, status.Error(codes.FailedPrecondition, err.Error())
+	}
+	if errors.Is(err, ports.ErrRunNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
 	if err != nil {
 		log.Printf("Error cancelling pipeline %s: %v", pipelineID, err)
 		return nil, status.Errorf(codes.Internal, "Failed to cancel pipeline: %v", err)
@@ -139,3 +260,296 @@ func (s *PipelineServer) CancelPipeline(ctx context.Context, req *proto.CancelPi
 
 	return &proto.CancelPipelineResponse{Message: "Pipeline cancelled"}, nil
 }
+
+func (s *PipelineServer) ListPipelineRuns(ctx context.Context, req *proto.ListPipelineRunsRequest) (*proto.ListPipelineRunsResponse, error) {
+	pipelineID, err := uuid.Parse(req.PipelineId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid pipeline ID: %v", err)
+	}
+	if err := s.canView(ctx, pipelineID, "list pipeline runs"); err != nil {
+		return nil, err
+	}
+
+	runs, err := s.Service.GetPipelineRuns(pipelineID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to list pipeline runs: %v", err)
+	}
+
+	resp := &proto.ListPipelineRunsResponse{Runs: make([]*proto.PipelineRun, len(runs))}
+	for i, run := range runs {
+		resp.Runs[i] = &proto.PipelineRun{
+			RunId:      run.RunID.String(),
+			Status:     run.Status,
+			IsParallel: run.IsParallel,
+			ErrorMsg:   run.ErrorMsg,
+			CreatedAt:  run.CreatedAt.Format(time.RFC3339),
+			StartedAt:  formatOptionalTime(run.StartedAt),
+			FinishedAt: formatOptionalTime(run.FinishedAt),
+		}
+	}
+	return resp, nil
+}
+
+func (s *PipelineServer) ListPipelines(ctx context.Context, req *proto.ListPipelinesRequest) (*proto.ListPipelinesResponse, error) {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	if req.PageSize < 0 {
+		return nil, status.Error(codes.InvalidArgument, "page_size must not be negative")
+	}
+
+	pipelines, nextPageToken, err := s.Service.ListPipelines(userID, req.PageToken, int(req.PageSize))
+	if errors.Is(err, services.ErrInvalidPageToken) {
+		return nil, status.Error(codes.InvalidArgument, err.Error())
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to list pipelines: %v", err)
+	}
+
+	resp := &proto.ListPipelinesResponse{
+		Pipelines:     make([]*proto.Pipeline, len(pipelines)),
+		NextPageToken: nextPageToken,
+	}
+	for i := range pipelines {
+		resp.Pipelines[i] = pipelineMessage(&pipelines[i])
+	}
+	return resp, nil
+}
+
+func (s *PipelineServer) GetPipeline(ctx context.Context, req *proto.GetPipelineRequest) (*proto.Pipeline, error) {
+	pipelineID, err := uuid.Parse(req.PipelineId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid pipeline ID: %v", err)
+	}
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	pipeline, err := s.Service.GetPipeline(userID, pipelineID)
+	if err != nil {
+		return nil, pipelineAccessError("get pipeline", err)
+	}
+	return pipelineMessage(pipeline), nil
+}
+
+func (s *PipelineServer) GetPipelineStages(ctx context.Context, req *proto.GetPipelineStagesRequest) (*proto.GetPipelineStagesResponse, error) {
+	pipelineID, err := uuid.Parse(req.PipelineId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid pipeline ID: %v", err)
+	}
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+	runID, err := parseRunID(req.RunId)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := s.Service.CanViewPipeline(userID, pipelineID); err != nil {
+		return nil, pipelineAccessError("get pipeline stages", err)
+	}
+	stages, err := s.Service.GetPipelineStages(pipelineID)
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to get pipeline stages: %v", err)
+	}
+
+	resp := &proto.GetPipelineStagesResponse{Stages: make([]*proto.Stage, len(stages))}
+	byID := make(map[uuid.UUID]*proto.Stage, len(stages))
+	for i, stage := range stages {
+		resp.Stages[i] = &proto.Stage{
+			StageId:   stage.StageID.String(),
+			StageName: stage.StageName,
+			StageType: stage.StageType,
+			Position:  int32(stage.Position),
+			Status:    stage.Status,
+			ErrorMsg:  stage.ErrorMsg,
+		}
+		byID[stage.StageID] = resp.Stages[i]
+	}
+	if runID == uuid.Nil {
+		return resp, nil
+	}
+
+	// A specific run reports its own state of each stage.
+	stageRuns, err := s.Service.GetStageRuns(pipelineID, runID)
+	if errors.Is(err, ports.ErrRunNotFound) {
+		return nil, status.Error(codes.NotFound, err.Error())
+	}
+	if err != nil {
+		return nil, status.Errorf(codes.Internal, "Failed to get pipeline stages: %v", err)
+	}
+	for _, stageRun := range stageRuns {
+		if stage, ok := byID[stageRun.StageID]; ok {
+			stage.Status = stageRun.Status
+			stage.ErrorMsg = stageRun.ErrorMsg
+			stage.StartedAt = formatOptionalTime(stageRun.StartedAt)
+			stage.FinishedAt = formatOptionalTime(stageRun.FinishedAt)
+		}
+	}
+	return resp, nil
+}
+
+func (s *PipelineServer) DeletePipeline(ctx context.Context, req *proto.DeletePipelineRequest) (*proto.DeletePipelineResponse, error) {
+	pipelineID, err := uuid.Parse(req.PipelineId)
+	if err != nil {
+		return nil, status.Errorf(codes.InvalidArgument, "Invalid pipeline ID: %v", err)
+	}
+	userID, err := callerID(ctx)
+	if err != nil {
+		return nil, err
+	}
+
+	err = s.Service.DeletePipeline(ctx, userID, pipelineID)
+	if errors.Is(err, domain.ErrRunInProgress) {
+		return nil, status.Error(codes.FailedPrecondition, "Pipeline is running, cancel it first")
+	}
+	if err != nil {
+		return nil, pipelineAccessError("delete pipeline", err)
+	}
+	return &proto.DeletePipelineResponse{Message: "Pipeline deleted successfully"}, nil
+}
+
+// callerID returns the user whose token authenticated the call.
+func callerID(ctx context.Context) (uuid.UUID, error) {
+	userID, ok := middleware.UserIDFromContext(ctx)
+	if !ok {
+		return uuid.Nil, status.Error(codes.Unauthenticated, "Authorization token is required")
+	}
+	return userID, nil
+}
+
+// canView checks that the caller may see the pipeline, returning a status
+// error otherwise.
+func (s *PipelineServer) canView(ctx context.Context, pipelineID uuid.UUID, action string) error {
+	userID, err := callerID(ctx)
+	if err != nil {
+		return err
+	}
+	if err := s.Service.CanViewPipeline(userID, pipelineID); err != nil {
+		return pipelineAccessError(action, err)
+	}
+	return nil
+}
+
+// pipelineAccessError maps the errors of looking up a pipeline on behalf of a
+// user to status errors.
+func pipelineAccessError(action string, err error) error {
+	switch {
+	case errors.Is(err, ports.ErrPipelineNotFound):
+		return status.Error(codes.NotFound, err.Error())
+	case errors.Is(err, ports.ErrPermissionDenied):
+		return status.Error(codes.PermissionDenied, err.Error())
+	default:
+		return status.Errorf(codes.Internal, "Failed to %s: %v", action, err)
+	}
+}
+
+func pipelineMessage(pipeline *models.Pipelines) *proto.Pipeline {
+	return &proto.Pipeline{
+		PipelineId:    pipeline.PipelineID.String(),
+		UserId:        pipeline.UserID.String(),
+		PipelineName:  pipeline.PipelineName,
+		Status:        pipeline.Status,
+		IsParallel:    pipeline.IsParallel,
+		FailurePolicy: pipeline.FailurePolicy,
+		TimeoutMs:     pipeline.TimeoutMs,
+		CreatedAt:     pipeline.CreatedAt.Format(time.RFC3339),
+		UpdatedAt:     pipeline.UpdatedAt.Format(time.RFC3339),
+	}
+}
+
+func formatOptionalTime(t *time.Time) string {
+	if t == nil {
+		return ""
+	}
+	return t.Format(time.RFC3339)
+}
+
+// WatchPipeline streams the pipeline's updates until its run in progress
+// finishes. Unless it resumes after last_seq, the stream starts with the
+// state of the latest run, and when no run is in progress it ends there.
+func (s *PipelineServer) WatchPipeline(req *proto.WatchPipelineRequest, stream proto.PipelineService_WatchPipelineServer) error {
+	pipelineID, err := uuid.Parse(req.PipelineId)
+	if err != nil {
+		return status.Errorf(codes.InvalidArgument, "Invalid pipeline ID: %v", err)
+	}
+	userID, err := callerID(stream.Context())
+	if err != nil {
+		return err
+	}
+	if req.LastSeq < 0 {
+		return status.Error(codes.InvalidArgument, "last_seq must not be negative")
+	}
+
+	watcher, activeRunID, err := s.Service.WatchPipeline(userID, pipelineID, req.LastSeq)
+	if err != nil {
+		return pipelineAccessError("watch pipeline", err)
+	}
+	defer watcher.Stop()
+
+	if watcher.Resync {
+		if err := stream.Send(&proto.PipelineEvent{Type: "resync", PipelineId: pipelineID.String()}); err != nil {
+			return err
+		}
+	}
+	if req.LastSeq == 0 {
+		updates, err := s.Service.LatestRunUpdates(pipelineID)
+		if err != nil {
+			return status.Errorf(codes.Internal, "Failed to fetch the latest run: %v", err)
+		}
+		for _, update := range updates {
+			if err := stream.Send(pipelineEvent(update)); err != nil {
+				return err
+			}
+			if services.EndsWatch(update, activeRunID) {
+				return nil
+			}
+		}
+	}
+
+	for {
+		// The updates already held go out first, even when no run is in
+		// progress any more.
+		var update infrastructure.PipelineUpdate
+		select {
+		case update = <-watcher.Updates():
+		default:
+			if activeRunID == uuid.Nil {
+				return nil
+			}
+			select {
+			case update = <-watcher.Updates():
+			case <-watcher.Done():
+				return status.Error(codes.Unavailable, "Fell behind the pipeline's updates, watch again from the last seq")
+			case <-stream.Context().Done():
+				return stream.Context().Err()
+			}
+		}
+
+		if err := stream.Send(pipelineEvent(update)); err != nil {
+			return err
+		}
+		if services.EndsWatch(update, activeRunID) {
+			return nil
+		}
+	}
+}
+
+func pipelineEvent(update infrastructure.PipelineUpdate) *proto.PipelineEvent {
+	event := &proto.PipelineEvent{
+		Type:         update.Type,
+		Seq:          update.Sequence,
+		PipelineId:   update.PipelineID.String(),
+		PipelineName: update.PipelineName,
+		RunId:        update.RunID.String(),
+		Status:       update.Status,
+	}
+	if update.Type == infrastructure.StageUpdateType {
+		event.StageId = update.StageID.String()
+		event.StageName = update.StageName
+	}
+	return event
+}
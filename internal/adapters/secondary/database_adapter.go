@@ -2,11 +2,16 @@ package secondary
 
 import (
 	"context"
+	"errors"
+	"fmt"
+	"sort"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/sarika-p9/my-pipeline-project/internal/core/ports"
 	"github.com/sarika-p9/my-pipeline-project/internal/models"
 	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
 )
 
 type DatabaseAdapter struct {
@@ -35,14 +40,60 @@ func (d *DatabaseAdapter) UpdateUser(userID uuid.UUID, updates map[string]interf
 	return d.DB.Model(&models.User{}).Where("user_id = ?", userID).Updates(updates).Error
 }
 
-func (d *DatabaseAdapter) SavePipelineExecution(execution *models.Pipelines) error {
-	return d.DB.Create(execution).Error
+func (d *DatabaseAdapter) SavePipelineExecution(execution *models.Pipelines, stages []models.Stages, deps []models.StageDependencies) error {
+	return d.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(execution).Error; err != nil {
+			return err
+		}
+		if len(stages) > 0 {
+			if err := tx.Create(&stages).Error; err != nil {
+				return err
+			}
+		}
+		if len(deps) > 0 {
+			if err := tx.Create(&deps).Error; err != nil {
+				return err
+			}
+		}
+		return addOutboxEvent(tx, ports.EventPipelineCreated, execution.PipelineID, "", ports.PipelineEventData{
+			PipelineID:   execution.PipelineID,
+			PipelineName: execution.PipelineName,
+			UserID:       execution.UserID.String(),
+			Status:       execution.Status,
+		})
+	})
 }
 
+// UpdatePipelineExecution updates the pipeline's status and records the
+// change in the outbox.
 func (d *DatabaseAdapter) UpdatePipelineExecution(execution *models.Pipelines) error {
-	return d.DB.Model(&models.Pipelines{}).
-		Where("pipeline_id = ?", execution.PipelineID).
-		Update("status", execution.Status).Error
+	return d.DB.Transaction(func(tx *gorm.DB) error {
+		var pipelines []models.Pipelines
+		if err := tx.Select("pipeline_id, pipeline_name").Where("pipeline_id = ?", execution.PipelineID).Find(&pipelines).Error; err != nil {
+			return err
+		}
+		if len(pipelines) == 0 {
+			return nil
+		}
+
+		err := tx.Model(&models.Pipelines{}).
+			Where("pipeline_id = ?", execution.PipelineID).
+			Update("status", execution.Status).Error
+		if err != nil {
+			return err
+		}
+
+		runID, err := latestRunID(tx, execution.PipelineID)
+		if err != nil {
+			return err
+		}
+		return addOutboxEvent(tx, ports.EventPipelineStatusChanged, execution.PipelineID, "", ports.PipelineEventData{
+			PipelineID:   execution.PipelineID,
+			PipelineName: pipelines[0].PipelineName,
+			Status:       execution.Status,
+			RunID:        runID,
+		})
+	})
 }
 
 func (d *DatabaseAdapter) GetPipelineStatus(pipelineID string) (string, error) {
@@ -52,7 +103,11 @@ func (d *DatabaseAdapter) GetPipelineStatus(pipelineID string) (string, error) {
 	}
 
 	var execution models.Pipelines
-	if err := d.DB.Where("pipeline_id = ?", parsedID).First(&execution).Error; err != nil {
+	err = d.DB.Where("pipeline_id = ?", parsedID).First(&execution).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return "", fmt.Errorf("%w: %s", ports.ErrPipelineNotFound, parsedID)
+	}
+	if err != nil {
 		return "", err
 	}
 
@@ -66,12 +121,25 @@ func (d *DatabaseAdapter) GetPipelinesByUser(userID string) ([]models.Pipelines,
 	}
 
 	var pipelines []models.Pipelines
-	err = d.DB.Select("pipeline_id, user_id, status, pipeline_name").
+	err = d.DB.Select("pipeline_id, user_id, status, pipeline_name, is_parallel, failure_policy, timeout_ms").
 		Where("user_id = ?", parsedID).
 		Find(&pipelines).Error
 	return pipelines, err
 }
 
+// GetPipelinesPage returns up to limit of the user's pipelines, oldest first,
+// skipping the first offset.
+func (d *DatabaseAdapter) GetPipelinesPage(userID uuid.UUID, offset, limit int) ([]models.Pipelines, error) {
+	var pipelines []models.Pipelines
+	err := d.DB.Select("pipeline_id, user_id, status, pipeline_name, is_parallel, failure_policy, timeout_ms, created_at, updated_at").
+		Where("user_id = ?", userID).
+		Order("created_at, pipeline_id").
+		Offset(offset).
+		Limit(limit).
+		Find(&pipelines).Error
+	return pipelines, err
+}
+
 func (d *DatabaseAdapter) SaveExecutionLog(logEntry *models.Stages) error {
 	if logEntry.StageName == "" {
 		logEntry.StageName = "Untitled Stage"
@@ -80,35 +148,275 @@ func (d *DatabaseAdapter) SaveExecutionLog(logEntry *models.Stages) error {
 	return d.DB.Create(logEntry).Error
 }
 
-func (r *DatabaseAdapter) UpdateStageStatus(stageID uuid.UUID, status string) error {
-	return r.DB.Model(&models.Stages{}).
-		Where("stage_id = ?", stageID).
-		Update("status", status).
-		Error
+func (d *DatabaseAdapter) UpdateStageStatus(stageID uuid.UUID, status string) error {
+	return d.updateStageStatus(stageID, map[string]interface{}{"status": status})
+}
+
+func (d *DatabaseAdapter) UpdateStageError(stageID uuid.UUID, status string, errorMsg string) error {
+	return d.updateStageStatus(stageID, map[string]interface{}{"status": status, "error_msg": errorMsg})
+}
+
+// updateStageStatus applies a status change to a stage and records it in the
+// outbox, attributed to the pipeline's latest run.
+func (d *DatabaseAdapter) updateStageStatus(stageID uuid.UUID, updates map[string]interface{}) error {
+	return d.DB.Transaction(func(tx *gorm.DB) error {
+		var stages []models.Stages
+		if err := tx.Select("stage_id, pipeline_id, stage_name").Where("stage_id = ?", stageID).Find(&stages).Error; err != nil {
+			return err
+		}
+		if len(stages) == 0 {
+			return nil
+		}
+		stage := stages[0]
+
+		if err := tx.Model(&models.Stages{}).Where("stage_id = ?", stageID).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		runID, err := latestRunID(tx, stage.PipelineID)
+		if err != nil {
+			return err
+		}
+		status, _ := updates["status"].(string)
+		errorMsg, _ := updates["error_msg"].(string)
+		return addOutboxEvent(tx, ports.StageEventType(status), stage.PipelineID, ports.StageSubject(runID, stageID), ports.StageEventData{
+			PipelineID: stage.PipelineID,
+			RunID:      runID,
+			StageID:    stageID,
+			StageName:  stage.StageName,
+			Status:     status,
+			Error:      errorMsg,
+		})
+	})
 }
 
 func (d *DatabaseAdapter) GetPipelineStages(pipelineID uuid.UUID) ([]models.Stages, error) {
 	var stages []models.Stages
-	if err := d.DB.Select("stage_id, pipeline_id, stage_name, status, error_msg, timestamp").
+	if err := d.DB.Select("stage_id, pipeline_id, stage_name, stage_type, config, position, status, error_msg, output, retry_policy, timeout_ms, timestamp").
 		Where("pipeline_id = ?", pipelineID).
+		Preload("Attempts", func(db *gorm.DB) *gorm.DB { return db.Order("attempt") }).
+		Order("position, timestamp").
 		Find(&stages).Error; err != nil {
 		return nil, err
 	}
 	return stages, nil
 }
 
+func (d *DatabaseAdapter) GetStageDependencies(pipelineID uuid.UUID) ([]models.StageDependencies, error) {
+	var deps []models.StageDependencies
+	if err := d.DB.Where("pipeline_id = ?", pipelineID).Find(&deps).Error; err != nil {
+		return nil, err
+	}
+	return deps, nil
+}
+
+func (d *DatabaseAdapter) SaveStageAttempt(attempt *models.StageAttempts) error {
+	return d.DB.Create(attempt).Error
+}
+
+func (d *DatabaseAdapter) UpdateStageOutput(stageID uuid.UUID, output string) error {
+	return d.DB.Model(&models.Stages{}).
+		Where("stage_id = ?", stageID).
+		Update("output", output).
+		Error
+}
+
+func (d *DatabaseAdapter) UpdatePipelineOutput(pipelineID uuid.UUID, output string) error {
+	return d.DB.Model(&models.Pipelines{}).
+		Where("pipeline_id = ?", pipelineID).
+		Update("output", output).
+		Error
+}
+
+// NextEventSequence increments the pipeline's event sequence in one statement,
+// so concurrent callers on any replica get distinct values.
+func (d *DatabaseAdapter) NextEventSequence(pipelineID uuid.UUID) (int64, error) {
+	var pipeline models.Pipelines
+	result := d.DB.Model(&pipeline).
+		Clauses(clause.Returning{Columns: []clause.Column{{Name: "event_sequence"}}}).
+		Where("pipeline_id = ?", pipelineID).
+		UpdateColumn("event_sequence", gorm.Expr("event_sequence + 1"))
+	if result.Error != nil {
+		return 0, result.Error
+	}
+	if result.RowsAffected == 0 {
+		return 0, fmt.Errorf("%w: %s", ports.ErrPipelineNotFound, pipelineID)
+	}
+	return pipeline.EventSequence, nil
+}
+
+func (d *DatabaseAdapter) SavePipelineRun(run *models.PipelineRun) error {
+	return d.DB.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(run).Error; err != nil {
+			return err
+		}
+		return addOutboxEvent(tx, ports.RunEventType(run.Status), run.PipelineID, ports.RunSubject(run.RunID), ports.RunEventData{
+			PipelineID: run.PipelineID,
+			RunID:      run.RunID,
+			Status:     run.Status,
+		})
+	})
+}
+
+// UpdatePipelineRun applies the updates to the run. Status changes are
+// recorded in the outbox, and refused with ports.ErrRunAlreadyCancelled once
+// the run is Cancelled.
+func (d *DatabaseAdapter) UpdatePipelineRun(runID uuid.UUID, updates map[string]interface{}) error {
+	status, ok := updates["status"].(string)
+	if !ok {
+		return d.DB.Model(&models.PipelineRun{}).
+			Where("run_id = ?", runID).
+			Updates(updates).
+			Error
+	}
+
+	return d.DB.Transaction(func(tx *gorm.DB) error {
+		var runs []models.PipelineRun
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).
+			Select("run_id, pipeline_id, status").
+			Where("run_id = ?", runID).
+			Find(&runs).
+			Error
+		if err != nil {
+			return err
+		}
+		if len(runs) == 0 {
+			return nil
+		}
+		if runs[0].Status == "Cancelled" && status != "Cancelled" {
+			return fmt.Errorf("%w: %s", ports.ErrRunAlreadyCancelled, runID)
+		}
+
+		if err := tx.Model(&models.PipelineRun{}).Where("run_id = ?", runID).Updates(updates).Error; err != nil {
+			return err
+		}
+
+		errorMsg, _ := updates["error_msg"].(string)
+		return addOutboxEvent(tx, ports.RunEventType(status), runs[0].PipelineID, ports.RunSubject(runID), ports.RunEventData{
+			PipelineID: runs[0].PipelineID,
+			RunID:      runID,
+			Status:     status,
+			Error:      errorMsg,
+		})
+	})
+}
+
+func (d *DatabaseAdapter) GetPipelineRun(runID uuid.UUID) (*models.PipelineRun, error) {
+	var run models.PipelineRun
+	err := d.DB.Where("run_id = ?", runID).First(&run).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s", ports.ErrRunNotFound, runID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (d *DatabaseAdapter) GetLatestPipelineRun(pipelineID uuid.UUID) (*models.PipelineRun, error) {
+	var run models.PipelineRun
+	err := d.DB.Where("pipeline_id = ?", pipelineID).Order("created_at DESC").First(&run).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: pipeline %s has not been run", ports.ErrRunNotFound, pipelineID)
+	}
+	if err != nil {
+		return nil, err
+	}
+	return &run, nil
+}
+
+func (d *DatabaseAdapter) GetPipelineRuns(pipelineID uuid.UUID) ([]models.PipelineRun, error) {
+	var runs []models.PipelineRun
+	if err := d.DB.Where("pipeline_id = ?", pipelineID).Order("created_at DESC").Find(&runs).Error; err != nil {
+		return nil, err
+	}
+	return runs, nil
+}
+
+// ClaimExpiredRuns takes over the expired runs in one statement, so when
+// several replicas look for them at once each run goes to only one.
+func (d *DatabaseAdapter) ClaimExpiredRuns(owner string, leaseUntil time.Time, statuses ...string) ([]models.PipelineRun, error) {
+	var runs []models.PipelineRun
+	err := d.DB.Model(&runs).
+		Clauses(clause.Returning{}).
+		Where("status IN ?", statuses).
+		Where("(lease_expires_at IS NULL OR lease_expires_at < ?)", time.Now()).
+		Updates(map[string]interface{}{"owner": owner, "lease_expires_at": leaseUntil}).
+		Error
+	if err != nil {
+		return nil, err
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].CreatedAt.Before(runs[j].CreatedAt) })
+	return runs, nil
+}
+
+func (d *DatabaseAdapter) RenewRunLeases(owner string, leaseUntil time.Time, statuses ...string) error {
+	return d.DB.Model(&models.PipelineRun{}).
+		Where("owner = ? AND status IN ?", owner, statuses).
+		Update("lease_expires_at", leaseUntil).
+		Error
+}
+
+func (d *DatabaseAdapter) SaveStageRuns(stageRuns []models.StageRun) error {
+	if len(stageRuns) == 0 {
+		return nil
+	}
+	return d.DB.Create(&stageRuns).Error
+}
+
+// UpdateStageRun writes no outbox event. Stage status changes also go through
+// UpdateStageError or UpdateStageStatus, whose events are the source of truth,
+// so an event here would be a duplicate.
+func (d *DatabaseAdapter) UpdateStageRun(runID uuid.UUID, stageID uuid.UUID, updates map[string]interface{}) error {
+	return d.DB.Model(&models.StageRun{}).
+		Where("run_id = ? AND stage_id = ?", runID, stageID).
+		Updates(updates).
+		Error
+}
+
+// GetStageRuns returns the stage runs of a run with their attempts attached.
+func (d *DatabaseAdapter) GetStageRuns(runID uuid.UUID) ([]models.StageRun, error) {
+	var stageRuns []models.StageRun
+	if err := d.DB.Where("run_id = ?", runID).Order("position").Find(&stageRuns).Error; err != nil {
+		return nil, err
+	}
+
+	var attempts []models.StageAttempts
+	if err := d.DB.Where("run_id = ?", runID).Order("attempt").Find(&attempts).Error; err != nil {
+		return nil, err
+	}
+	for i := range stageRuns {
+		for _, attempt := range attempts {
+			if attempt.StageID == stageRuns[i].StageID {
+				stageRuns[i].Attempts = append(stageRuns[i].Attempts, attempt)
+			}
+		}
+	}
+	return stageRuns, nil
+}
+
 func (d *DatabaseAdapter) DeletePipeline(ctx context.Context, pipelineID string) error {
 	parsedID, err := uuid.Parse(pipelineID)
 	if err != nil {
 		return err
 	}
 
-	return d.DB.WithContext(ctx).Where("pipeline_id = ?", parsedID).Delete(&models.Pipelines{}).Error
+	return d.DB.WithContext(ctx).Transaction(func(tx *gorm.DB) error {
+		result := tx.Where("pipeline_id = ?", parsedID).Delete(&models.Pipelines{})
+		if result.Error != nil || result.RowsAffected == 0 {
+			return result.Error
+		}
+		return addOutboxEvent(tx, ports.EventPipelineDeleted, parsedID, "", ports.PipelineEventData{PipelineID: parsedID})
+	})
 }
 
 func (d *DatabaseAdapter) GetPipelineByID(pipelineID uuid.UUID) (*models.Pipelines, error) {
 	var pipeline models.Pipelines
-	if err := d.DB.Where("pipeline_id = ?", pipelineID).First(&pipeline).Error; err != nil {
+	err := d.DB.Where("pipeline_id = ?", pipelineID).First(&pipeline).Error
+	if errors.Is(err, gorm.ErrRecordNotFound) {
+		return nil, fmt.Errorf("%w: %s", ports.ErrPipelineNotFound, pipelineID)
+	}
+	if err != nil {
 		return nil, err
 	}
 	return &pipeline, nil
@@ -0,0 +1,120 @@
+package secondary
+
+import (
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sarika-p9/my-pipeline-project/internal/core/ports"
+	"github.com/sarika-p9/my-pipeline-project/internal/models"
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+var _ ports.OutboxRepository = (*DatabaseAdapter)(nil)
+
+// outboxClaimTimeout is how long events claimed by a relay are left alone by
+// the others. A relay that stops while publishing leaves its events to be
+// relayed again after it.
+const outboxClaimTimeout = time.Minute
+
+// addOutboxEvent writes a new event about the pipeline to the outbox as part
+// of tx.
+func addOutboxEvent(tx *gorm.DB, eventType string, pipelineID uuid.UUID, subject string, data interface{}) error {
+	event, err := ports.NewEvent(eventType, pipelineID, subject, data)
+	if err != nil {
+		return err
+	}
+	payload, err := json.Marshal(event)
+	if err != nil {
+		return err
+	}
+
+	return tx.Create(&models.OutboxEvent{
+		EventID:    uuid.MustParse(event.ID),
+		PipelineID: pipelineID,
+		EventType:  eventType,
+		Payload:    string(payload),
+	}).Error
+}
+
+// latestRunID returns the ID of the pipeline's latest run, which the Status
+// columns of Pipelines and Stages mirror, or "" when it has not been run.
+func latestRunID(tx *gorm.DB, pipelineID uuid.UUID) (string, error) {
+	var runs []models.PipelineRun
+	err := tx.Select("run_id").
+		Where("pipeline_id = ?", pipelineID).
+		Order("created_at DESC").
+		Limit(1).
+		Find(&runs).Error
+	if err != nil || len(runs) == 0 {
+		return "", err
+	}
+	return runs[0].RunID.String(), nil
+}
+
+func (d *DatabaseAdapter) RelayOutboxEvents(limit int, retry ports.OutboxRetry, publish func(event models.OutboxEvent) error) (int, error) {
+	events, err := d.claimOutboxEvents(limit)
+	if err != nil {
+		return 0, err
+	}
+
+	published := 0
+	for _, event := range events {
+		updates := map[string]interface{}{"attempts": event.Attempts + 1}
+		if publishErr := publish(event); publishErr != nil {
+			updates["last_error"] = publishErr.Error()
+			updates["next_attempt_at"] = time.Now().Add(retry.Delay(event.Attempts + 1))
+			if retry.MaxAttempts > 0 && event.Attempts+1 >= retry.MaxAttempts {
+				updates["failed_at"] = time.Now()
+			}
+		} else {
+			updates["published_at"] = time.Now()
+			updates["last_error"] = ""
+			published++
+		}
+
+		err := d.DB.Model(&models.OutboxEvent{}).Where("event_id = ?", event.EventID).Updates(updates).Error
+		if err != nil {
+			return published, err
+		}
+	}
+	return published, nil
+}
+
+// claimOutboxEvents picks up to limit events that are due and keeps the other
+// relays off them for outboxClaimTimeout. The rows are locked only while they
+// are claimed, not while they are published.
+func (d *DatabaseAdapter) claimOutboxEvents(limit int) ([]models.OutboxEvent, error) {
+	var events []models.OutboxEvent
+	err := d.DB.Transaction(func(tx *gorm.DB) error {
+		now := time.Now()
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE", Options: "SKIP LOCKED"}).
+			Where("published_at IS NULL AND failed_at IS NULL").
+			Where("next_attempt_at IS NULL OR next_attempt_at <= ?", now).
+			Order("created_at").
+			Limit(limit).
+			Find(&events).Error
+		if err != nil || len(events) == 0 {
+			return err
+		}
+
+		ids := make([]uuid.UUID, len(events))
+		for i, event := range events {
+			ids[i] = event.EventID
+		}
+		return tx.Model(&models.OutboxEvent{}).
+			Where("event_id IN ?", ids).
+			Update("next_attempt_at", now.Add(outboxClaimTimeout)).
+			Error
+	})
+	if err != nil {
+		return nil, err
+	}
+	return events, nil
+}
+
+func (d *DatabaseAdapter) DeleteOutboxEvents(publishedBefore time.Time) (int64, error) {
+	result := d.DB.Where("published_at < ?", publishedBefore).Delete(&models.OutboxEvent{})
+	return result.RowsAffected, result.Error
+}
@@ -0,0 +1,134 @@
+package secondary
+
+import (
+	"context"
+	"encoding/json"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sarika-p9/my-pipeline-project/internal/core/ports"
+	"github.com/sarika-p9/my-pipeline-project/internal/producer"
+)
+
+// DefaultDispatchTimeout bounds the wait for a stage result, on top of the
+// stage timeout, when the caller sets no deadline.
+const DefaultDispatchTimeout = 10 * time.Minute
+
+// cancelPublishTimeout bounds publishing the cancel of a task given up on.
+const cancelPublishTimeout = 5 * time.Second
+
+// BusDispatcher queues stage tasks for the workers on a MessageBus and waits
+// for their results on a reply topic owned by this process.
+type BusDispatcher struct {
+	Timeout time.Duration // See DefaultDispatchTimeout
+
+	producer     *producer.Producer
+	replyTopic   string
+	subscription ports.Subscription
+
+	mu      sync.Mutex
+	pending map[uuid.UUID]chan ports.StageResult
+}
+
+var _ ports.StageDispatcher = (*BusDispatcher)(nil)
+
+// NewBusDispatcher subscribes to a reply topic unique to this dispatcher and
+// starts routing the results that arrive on it.
+func NewBusDispatcher(bus ports.MessageBus) (*BusDispatcher, error) {
+	d := &BusDispatcher{
+		Timeout:    DefaultDispatchTimeout,
+		producer:   producer.NewProducer(bus),
+		replyTopic: "pipeline.results." + uuid.NewString(),
+		pending:    make(map[uuid.UUID]chan ports.StageResult),
+	}
+
+	subscription, err := bus.Subscribe(d.replyTopic, d.receive)
+	if err != nil {
+		return nil, err
+	}
+	d.subscription = subscription
+
+	log.Printf("✅ Dispatching stage tasks to queue '%s', replies on '%s'", producer.TaskQueue, d.replyTopic)
+	return d, nil
+}
+
+// Dispatch queues the task with the deadline of ctx, or with Timeout when ctx
+// has none, and waits for its result. When it gives up waiting it tells the
+// workers to cancel the task.
+func (d *BusDispatcher) Dispatch(ctx context.Context, task ports.StageTask) (ports.StageResult, error) {
+	if task.TaskID == uuid.Nil {
+		task.TaskID = uuid.New()
+	}
+	if _, ok := ctx.Deadline(); !ok && d.Timeout > 0 {
+		var cancel context.CancelFunc
+		ctx, cancel = context.WithTimeout(ctx, d.Timeout+time.Duration(task.TimeoutMs)*time.Millisecond)
+		defer cancel()
+	}
+	if deadline, ok := ctx.Deadline(); ok {
+		task.Deadline = &deadline
+	}
+
+	results := make(chan ports.StageResult, 1)
+	d.mu.Lock()
+	d.pending[task.TaskID] = results
+	d.mu.Unlock()
+
+	defer func() {
+		d.mu.Lock()
+		delete(d.pending, task.TaskID)
+		d.mu.Unlock()
+	}()
+
+	if err := d.producer.PublishTask(ctx, task, d.replyTopic); err != nil {
+		return ports.StageResult{}, err
+	}
+
+	select {
+	case result := <-results:
+		return result, nil
+	case <-ctx.Done():
+		d.cancel(task.TaskID)
+		return ports.StageResult{}, ctx.Err()
+	}
+}
+
+// cancel tells the workers to stop a task. Workers also stop it at its
+// deadline, so a lost cancel only keeps it running until then.
+func (d *BusDispatcher) cancel(taskID uuid.UUID) {
+	ctx, cancel := context.WithTimeout(context.Background(), cancelPublishTimeout)
+	defer cancel()
+	if err := d.producer.PublishCancel(ctx, taskID); err != nil {
+		log.Printf("⚠️ Failed to cancel task %s: %v", taskID, err)
+	}
+}
+
+// receive hands each result to the Dispatch call waiting for it. Results of
+// tasks nobody waits for anymore, e.g. after a cancel, are dropped.
+func (d *BusDispatcher) receive(ctx context.Context, msg ports.Message) error {
+	var result ports.StageResult
+	if err := json.Unmarshal(msg.Data, &result); err != nil {
+		log.Printf("❌ Failed to unmarshal stage result: %v", err)
+		return nil
+	}
+
+	d.mu.Lock()
+	results, ok := d.pending[result.TaskID]
+	d.mu.Unlock()
+	if !ok {
+		log.Printf("⚠️ Dropping result of task %s, nobody is waiting for it", result.TaskID)
+		return nil
+	}
+
+	select {
+	case results <- result:
+	default: // A redelivered result of a task that already has one
+	}
+	return nil
+}
+
+// Close stops receiving results.
+func (d *BusDispatcher) Close() error {
+	return d.subscription.Unsubscribe()
+}
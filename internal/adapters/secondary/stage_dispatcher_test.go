@@ -0,0 +1,183 @@
+package secondary
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sarika-p9/my-pipeline-project/internal/consumer"
+	"github.com/sarika-p9/my-pipeline-project/internal/core/domain"
+	"github.com/sarika-p9/my-pipeline-project/internal/core/ports"
+	"github.com/sarika-p9/my-pipeline-project/internal/messaging"
+)
+
+// startWorkers runs stage workers on the in-memory bus until the test ends,
+// the way STAGE_EXECUTION=worker does with MESSAGE_BUS=memory.
+func startWorkers(t *testing.T, bus ports.MessageBus) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	t.Cleanup(cancel)
+
+	stageTypes := domain.NewStageRegistry()
+	go consumer.NewConsumer(bus, 2).StartConsuming(ctx, func(ctx context.Context, task consumer.Task) ports.StageResult {
+		return domain.RunStageTask(ctx, stageTypes, task)
+	})
+}
+
+func newTestDispatcher(t *testing.T, bus ports.MessageBus) *BusDispatcher {
+	t.Helper()
+	dispatcher, err := NewBusDispatcher(bus)
+	if err != nil {
+		t.Fatalf("NewBusDispatcher: %v", err)
+	}
+	t.Cleanup(func() { dispatcher.Close() })
+	return dispatcher
+}
+
+func TestBusDispatcherRunsStagesOnWorkers(t *testing.T) {
+	bus := messaging.NewMemoryBus()
+	defer bus.Close()
+	startWorkers(t, bus)
+	dispatcher := newTestDispatcher(t, bus)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	result, err := dispatcher.Dispatch(ctx, ports.StageTask{
+		StageID:   uuid.New(),
+		StageName: "rename",
+		StageType: "transform",
+		Config:    json.RawMessage(`{"rename": {"qty": "quantity"}}`),
+		Input:     json.RawMessage(`{"qty": 3}`),
+	})
+	if err != nil {
+		t.Fatalf("Dispatch: %v", err)
+	}
+	if result.Error != "" {
+		t.Fatalf("stage failed: %s", result.Error)
+	}
+	var output map[string]interface{}
+	if err := json.Unmarshal(result.Output, &output); err != nil {
+		t.Fatalf("invalid output %s: %v", result.Output, err)
+	}
+	if output["quantity"] != float64(3) || output["qty"] != nil {
+		t.Errorf("output = %v, want the quantity renamed", output)
+	}
+}
+
+func TestBusDispatcherReturnsStageErrors(t *testing.T) {
+	bus := messaging.NewMemoryBus()
+	defer bus.Close()
+	startWorkers(t, bus)
+	dispatcher := newTestDispatcher(t, bus)
+
+	ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+	defer cancel()
+
+	tests := []struct {
+		name       string
+		task       ports.StageTask
+		errorClass string
+	}{
+		{"failing stage", ports.StageTask{StageType: "fail", Config: json.RawMessage(`{"message": "jammed", "error_class": "transient"}`)}, domain.ErrorClassTransient},
+		{"unknown type", ports.StageTask{StageType: "shell"}, domain.ErrorClassUnknown},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			tt.task.StageID = uuid.New()
+			tt.task.StageName = tt.name
+			result, err := dispatcher.Dispatch(ctx, tt.task)
+			if err != nil {
+				t.Fatalf("Dispatch: %v", err)
+			}
+			if result.Error == "" || result.ErrorClass != tt.errorClass {
+				t.Errorf("result = %+v, want an error of class %s", result, tt.errorClass)
+			}
+		})
+	}
+}
+
+func TestBusDispatcherStopsWaitingWhenCancelled(t *testing.T) {
+	bus := messaging.NewMemoryBus()
+	defer bus.Close()
+	dispatcher := newTestDispatcher(t, bus) // No workers, so no reply comes
+
+	ctx, cancel := context.WithTimeout(context.Background(), 50*time.Millisecond)
+	defer cancel()
+
+	_, err := dispatcher.Dispatch(ctx, ports.StageTask{StageID: uuid.New(), StageType: "sleep"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Dispatch returned %v, want %v", err, context.DeadlineExceeded)
+	}
+}
+
+func TestBusDispatcherCancelsTasksOnWorkers(t *testing.T) {
+	bus := messaging.NewMemoryBus()
+	defer bus.Close()
+	dispatcher := newTestDispatcher(t, bus)
+
+	workerCtx, stopWorker := context.WithCancel(context.Background())
+	defer stopWorker()
+	started, stopped := make(chan struct{}), make(chan error, 1)
+	go consumer.NewConsumer(bus, 1).StartConsuming(workerCtx, func(ctx context.Context, task consumer.Task) ports.StageResult {
+		close(started)
+		<-ctx.Done()
+		stopped <- ctx.Err()
+		return ports.StageResult{TaskID: task.TaskID}
+	})
+
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() {
+		_, err := dispatcher.Dispatch(ctx, ports.StageTask{StageID: uuid.New(), StageType: "sleep"})
+		done <- err
+	}()
+
+	select {
+	case <-started:
+	case <-time.After(5 * time.Second):
+		t.Fatal("task never reached the worker")
+	}
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Dispatch returned %v, want %v", err, context.Canceled)
+	}
+	select {
+	case err := <-stopped:
+		if !errors.Is(err, context.Canceled) {
+			t.Errorf("task stopped with %v, want %v", err, context.Canceled)
+		}
+	case <-time.After(5 * time.Second):
+		t.Fatal("worker kept running the cancelled task")
+	}
+}
+
+func TestBusDispatcherTimesOutWithoutDeadline(t *testing.T) {
+	bus := messaging.NewMemoryBus()
+	defer bus.Close()
+	dispatcher := newTestDispatcher(t, bus) // No workers, so no reply comes
+	dispatcher.Timeout = 50 * time.Millisecond
+
+	_, err := dispatcher.Dispatch(context.Background(), ports.StageTask{StageID: uuid.New(), StageType: "sleep"})
+	if !errors.Is(err, context.DeadlineExceeded) {
+		t.Errorf("Dispatch returned %v, want %v", err, context.DeadlineExceeded)
+	}
+
+	// The queued task carries the deadline, so a worker starting later
+	// skips it.
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+	ran := make(chan uuid.UUID, 1)
+	go consumer.NewConsumer(bus, 1).StartConsuming(ctx, func(ctx context.Context, task consumer.Task) ports.StageResult {
+		ran <- task.TaskID
+		return ports.StageResult{TaskID: task.TaskID}
+	})
+	select {
+	case taskID := <-ran:
+		t.Errorf("worker ran task %s after its deadline", taskID)
+	case <-time.After(200 * time.Millisecond):
+	}
+}
@@ -0,0 +1,133 @@
+// Package bootstrap wires up the backend shared by the REST and gRPC servers
+// from the environment.
+package bootstrap
+
+import (
+	"context"
+	"log"
+	"os"
+	"strconv"
+	"time"
+
+	"github.com/sarika-p9/my-pipeline-project/internal/adapters/secondary"
+	"github.com/sarika-p9/my-pipeline-project/internal/consumer"
+	"github.com/sarika-p9/my-pipeline-project/internal/core/domain"
+	"github.com/sarika-p9/my-pipeline-project/internal/core/ports"
+	"github.com/sarika-p9/my-pipeline-project/internal/infrastructure"
+	"github.com/sarika-p9/my-pipeline-project/internal/messaging"
+	"github.com/sarika-p9/my-pipeline-project/internal/services"
+)
+
+// Backend holds the services the servers expose.
+type Backend struct {
+	Auth        *services.AuthService
+	Pipelines   *services.PipelineService
+	DeadLetters *services.DeadLetterService
+	Bus         ports.MessageBus
+}
+
+// Start connects to the database and to the message bus, starts the outbox
+// relay, the WebSocket fan-out, the in-process stage workers and run
+// recovery, and returns the services. RABBITMQ_URL and NATS_URL default to
+// the given URLs. Invalid settings are fatal.
+func Start(defaultRabbitURL, defaultNATSURL string) *Backend {
+	infrastructure.InitDatabase()
+	dbRepo := secondary.NewDatabaseAdapter(infrastructure.GetDB())
+
+	b := &Backend{
+		Auth:      services.NewAuthService(dbRepo),
+		Pipelines: services.NewPipelineService(dbRepo),
+	}
+
+	rabbitURL := os.Getenv("RABBITMQ_URL")
+	if rabbitURL == "" {
+		rabbitURL = defaultRabbitURL
+	}
+	natsURL := os.Getenv("NATS_URL")
+	if natsURL == "" {
+		natsURL = defaultNATSURL
+	}
+	bus, err := messaging.NewMessageBus(os.Getenv("MESSAGE_BUS"), rabbitURL, natsURL)
+	if err != nil {
+		log.Fatalf("Failed to connect to the message bus: %v", err)
+	}
+	b.Bus = bus
+	b.DeadLetters = services.NewDeadLetterService(bus, dbRepo)
+
+	startOutboxRelay(dbRepo, bus, natsURL)
+
+	webSocketFanout, err := messaging.NewWebSocketFanout(os.Getenv("WS_FANOUT"), bus, natsURL)
+	if err != nil {
+		log.Fatalf("Invalid WS_FANOUT: %v", err)
+	}
+	if webSocketFanout != nil {
+		if _, err := infrastructure.WebSocket.Relay(webSocketFanout); err != nil {
+			log.Fatalf("Failed to relay WebSocket updates: %v", err)
+		}
+	}
+
+	if os.Getenv("STAGE_EXECUTION") == "worker" {
+		startStageWorkers(b.Pipelines, bus)
+	}
+
+	recoveryPolicy, err := domain.ParseRecoveryPolicy(os.Getenv("RUN_RECOVERY_POLICY"))
+	if err != nil {
+		log.Fatalf("Invalid RUN_RECOVERY_POLICY: %v", err)
+	}
+	if err := b.Pipelines.RecoverRuns(context.Background(), recoveryPolicy); err != nil {
+		log.Printf("Failed to recover unfinished pipeline runs: %v", err)
+	}
+	go b.Pipelines.MaintainRunLeases(context.Background(), recoveryPolicy)
+
+	return b
+}
+
+// Close disconnects from the message bus.
+func (b *Backend) Close() error {
+	return b.Bus.Close()
+}
+
+// startOutboxRelay publishes the outbox on NATS, and to the event log when
+// EVENT_LOG is set.
+func startOutboxRelay(dbRepo *secondary.DatabaseAdapter, bus ports.MessageBus, natsURL string) {
+	eventLogRetention := messaging.DefaultEventLogRetention
+	if maxAge := os.Getenv("EVENT_LOG_MAX_AGE"); maxAge != "" {
+		var err error
+		if eventLogRetention.MaxAge, err = time.ParseDuration(maxAge); err != nil {
+			log.Fatalf("Invalid EVENT_LOG_MAX_AGE: %v", err)
+		}
+	}
+	eventLog, err := messaging.NewEventLog(context.Background(), os.Getenv("EVENT_LOG"), natsURL, eventLogRetention)
+	if err != nil {
+		log.Fatalf("Failed to set up the event log: %v", err)
+	}
+
+	outboxRelay := services.NewOutboxRelay(dbRepo, messaging.NewEventBus(bus, natsURL))
+	outboxRelay.Log = eventLog
+	go outboxRelay.Run(context.Background())
+}
+
+// startStageWorkers makes the service run stages on workers. With the
+// in-memory bus the workers run in this process, so no broker is needed.
+func startStageWorkers(pipelineService *services.PipelineService, bus ports.MessageBus) {
+	dispatcher, err := secondary.NewBusDispatcher(bus)
+	if err != nil {
+		log.Fatalf("Failed to set up stage dispatching: %v", err)
+	}
+	pipelineService.Dispatcher = dispatcher
+
+	if _, inProcess := bus.(*messaging.MemoryBus); !inProcess {
+		log.Println("Stages run on workers (STAGE_EXECUTION=worker)")
+		return
+	}
+
+	concurrency := 4
+	if value, err := strconv.Atoi(os.Getenv("WORKER_CONCURRENCY")); err == nil && value > 0 {
+		concurrency = value
+	}
+	log.Printf("Stages run on %d in-process workers (STAGE_EXECUTION=worker, MESSAGE_BUS=memory)", concurrency)
+	worker := consumer.NewConsumer(bus, concurrency)
+	go worker.StartConsuming(context.Background(), func(ctx context.Context, task consumer.Task) ports.StageResult {
+		return domain.RunStageTask(ctx, pipelineService.StageTypes, task)
+	})
+}
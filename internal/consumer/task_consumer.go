@@ -1,52 +1,166 @@
 package consumer
 
 import (
+	"context"
 	"encoding/json"
+	"fmt"
 	"log"
+	"sync"
+	"time"
 
-	"github.com/sarika-p9/my-pipeline-project/internal/infrastructure"
+	"github.com/google/uuid"
+	"github.com/sarika-p9/my-pipeline-project/internal/core/ports"
+	"github.com/sarika-p9/my-pipeline-project/internal/producer"
 )
 
-type Task struct {
-	PipelineID string `json:"pipeline_id"`
-	JobType    string `json:"job_type"`
-	Payload    string `json:"payload"`
-}
+// Task asks a worker to run one attempt of a stage.
+type Task = ports.StageTask
+
+// TaskHandler runs a task and returns the result to send back.
+type TaskHandler func(ctx context.Context, task Task) ports.StageResult
+
+// cancelMemory is how long the cancel of a task that has not reached this
+// worker yet is remembered.
+const cancelMemory = 30 * time.Minute
 
 type Consumer struct {
-	RabbitMQ *infrastructure.RabbitMQ
+	Bus         ports.MessageBus
+	Concurrency int // Tasks handled at once; defaults to 1
+
+	mu        sync.Mutex
+	running   map[uuid.UUID]context.CancelFunc
+	cancelled map[uuid.UUID]time.Time // Cancels of tasks not running here, by arrival
 }
 
-func NewConsumer(rabbitMQ *infrastructure.RabbitMQ) *Consumer {
-	return &Consumer{RabbitMQ: rabbitMQ}
+func NewConsumer(bus ports.MessageBus, concurrency int) *Consumer {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	return &Consumer{
+		Bus:         bus,
+		Concurrency: concurrency,
+		running:     make(map[uuid.UUID]context.CancelFunc),
+		cancelled:   make(map[uuid.UUID]time.Time),
+	}
 }
 
-func (c *Consumer) StartConsuming() {
-	msgs, err := c.RabbitMQ.Channel.Consume(
-		c.RabbitMQ.Queue.Name,
-		"",
-		false,
-		false,
-		false,
-		false,
-		nil,
-	)
+// StartConsuming runs tasks from the task queue with handler and publishes
+// each result on the task's reply topic. A task is acknowledged once its
+// result has been published, so tasks of a worker that stops are delivered
+// again. Tasks are stopped at their deadline or when their dispatcher cancels
+// them, without a result. It returns when ctx is done or the bus closes.
+func (c *Consumer) StartConsuming(ctx context.Context, handler TaskHandler) error {
+	subscription, err := c.Bus.Subscribe(producer.CancelTopic, c.receiveCancel)
 	if err != nil {
-		log.Fatalf("❌ Failed to register consumer: %v", err)
+		return fmt.Errorf("subscribing to task cancels: %w", err)
 	}
+	defer subscription.Unsubscribe()
 
-	log.Println("🚀 Consumer started. Waiting for tasks...")
+	log.Printf("🚀 Consumer started with %d workers. Waiting for tasks...", c.Concurrency)
+	return c.Bus.Consume(ctx, producer.TaskQueue, c.Concurrency, func(ctx context.Context, msg ports.Message) error {
+		return c.handle(ctx, msg, handler)
+	})
+}
 
-	for d := range msgs {
-		var task Task
-		if err := json.Unmarshal(d.Body, &task); err != nil {
-			log.Printf("❌ Failed to unmarshal task: %v", err)
-			d.Nack(false, false)
-			continue
-		}
+func (c *Consumer) handle(ctx context.Context, msg ports.Message, handler TaskHandler) error {
+	var task Task
+	if err := json.Unmarshal(msg.Data, &task); err != nil {
+		log.Printf("❌ Failed to unmarshal task: %v", err)
+		return fmt.Errorf("%w: %v", ports.ErrRejectMessage, err)
+	}
+
+	taskCtx, cancel := c.track(ctx, task)
+	defer cancel()
+	if taskCtx.Err() != nil {
+		log.Printf("⏹️ Skipping task %s, its dispatcher gave up on it", task.TaskID)
+		return nil
+	}
+
+	log.Printf("📥 Processing task %s: stage %s (%s) of pipeline %s", task.TaskID, task.StageName, task.StageType, task.PipelineName)
+	result := handler(taskCtx, task)
+	if ctx.Err() != nil {
+		// Shutting down: leave the task for another worker.
+		return ctx.Err()
+	}
+	if taskCtx.Err() != nil {
+		log.Printf("⏹️ Stopped task %s, its dispatcher gave up on it", task.TaskID)
+		return nil
+	}
+
+	if msg.ReplyTo == "" {
+		log.Printf("⚠️ Task %s has no reply topic, dropping its result", task.TaskID)
+		return nil
+	}
+
+	body, err := json.Marshal(result)
+	if err != nil {
+		log.Printf("❌ Failed to marshal result of task %s: %v", task.TaskID, err)
+		return fmt.Errorf("%w: %v", ports.ErrRejectMessage, err)
+	}
+	if err := c.Bus.Publish(ctx, msg.ReplyTo, ports.Message{Data: body, CorrelationID: msg.CorrelationID}); err != nil {
+		log.Printf("❌ Failed to publish result of task %s: %v", task.TaskID, err)
+		return err
+	}
 
-		log.Printf("📥 Processing task: %+v", task)
+	if result.Error != "" {
+		log.Printf("⚠️ Task %s failed: %s", task.TaskID, result.Error)
+	} else {
+		log.Printf("✅ Task %s completed", task.TaskID)
+	}
+	return nil
+}
+
+// track returns the context to run the task in. It is cancelled at the task's
+// deadline, when a cancel for the task arrives, or when the returned cancel
+// is called.
+func (c *Consumer) track(ctx context.Context, task Task) (context.Context, context.CancelFunc) {
+	var taskCtx context.Context
+	var cancelTask context.CancelFunc
+	if task.Deadline != nil {
+		taskCtx, cancelTask = context.WithDeadline(ctx, *task.Deadline)
+	} else {
+		taskCtx, cancelTask = context.WithCancel(ctx)
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if _, cancelled := c.cancelled[task.TaskID]; cancelled {
+		delete(c.cancelled, task.TaskID)
+		cancelTask()
+		return taskCtx, cancelTask
+	}
+	c.running[task.TaskID] = cancelTask
 
-		d.Ack(false)
+	return taskCtx, func() {
+		c.mu.Lock()
+		delete(c.running, task.TaskID)
+		c.mu.Unlock()
+		cancelTask()
+	}
+}
+
+// receiveCancel stops the task if it runs here, and otherwise remembers the
+// cancel in case the task is still queued.
+func (c *Consumer) receiveCancel(ctx context.Context, msg ports.Message) error {
+	var cancel ports.StageTaskCancel
+	if err := json.Unmarshal(msg.Data, &cancel); err != nil {
+		log.Printf("❌ Failed to unmarshal task cancel: %v", err)
+		return nil
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	if cancelTask, running := c.running[cancel.TaskID]; running {
+		cancelTask()
+		return nil
+	}
+
+	now := time.Now()
+	for taskID, received := range c.cancelled {
+		if now.Sub(received) > cancelMemory {
+			delete(c.cancelled, taskID)
+		}
 	}
+	c.cancelled[cancel.TaskID] = now
+	return nil
 }
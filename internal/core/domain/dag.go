@@ -0,0 +1,180 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"strings"
+
+	"github.com/google/uuid"
+)
+
+// ErrInvalidStageGraph is returned when a pipeline definition declares
+// dependencies that cannot be scheduled.
+var ErrInvalidStageGraph = errors.New("invalid stage graph")
+
+// IsInvalidDefinition reports whether err was caused by a pipeline definition
+// the caller submitted, as opposed to a storage or execution failure.
+func IsInvalidDefinition(err error) bool {
+	return errors.Is(err, ErrInvalidStageGraph) ||
+		errors.Is(err, ErrInvalidFailurePolicy) ||
+		errors.Is(err, ErrInvalidRetryPolicy) ||
+		errors.Is(err, ErrInvalidTimeout) ||
+		errors.Is(err, ErrUnknownStageType) ||
+		errors.Is(err, ErrInvalidStageConfig)
+}
+
+// StageDefinition describes a stage as submitted at pipeline creation time.
+type StageDefinition struct {
+	Name      string          `json:"name"`
+	Type      string          `json:"type,omitempty"`   // A registered stage type; defaults to sleep
+	Config    json.RawMessage `json:"config,omitempty"` // Type-specific settings
+	DependsOn []string        `json:"depends_on"`
+	Retry     *RetryPolicy    `json:"retry,omitempty"`
+	TimeoutMs int64           `json:"timeout_ms,omitempty"` // Per attempt; zero means no limit
+}
+
+// StageDefinitionsFromNames builds dependency-free definitions for callers
+// that only supply stage names.
+func StageDefinitionsFromNames(names []string) []StageDefinition {
+	defs := make([]StageDefinition, len(names))
+	for i, name := range names {
+		defs[i] = StageDefinition{Name: name}
+	}
+	return defs
+}
+
+// ValidateStageDefinitions checks every stage's settings and the dependency
+// graph between them.
+func ValidateStageDefinitions(defs []StageDefinition) error {
+	for _, def := range defs {
+		if def.TimeoutMs < 0 {
+			return fmt.Errorf("stage %q: %w: timeout_ms must not be negative", def.Name, ErrInvalidTimeout)
+		}
+		if def.Retry == nil {
+			continue
+		}
+		if err := def.Retry.Validate(); err != nil {
+			return fmt.Errorf("stage %q: %w", def.Name, err)
+		}
+	}
+	return ValidateStageGraph(defs)
+}
+
+// ValidateStageGraph rejects references to unknown or ambiguous stage names
+// and dependency cycles.
+func ValidateStageGraph(defs []StageDefinition) error {
+	count := make(map[string]int, len(defs))
+	for _, def := range defs {
+		count[def.Name]++
+	}
+
+	edges := make(map[string][]string, len(defs))
+	for _, def := range defs {
+		for _, upstream := range def.DependsOn {
+			switch {
+			case upstream == def.Name:
+				return fmt.Errorf("%w: stage %q depends on itself", ErrInvalidStageGraph, def.Name)
+			case count[upstream] == 0:
+				return fmt.Errorf("%w: stage %q depends on unknown stage %q", ErrInvalidStageGraph, def.Name, upstream)
+			case count[upstream] > 1:
+				return fmt.Errorf("%w: stage %q depends on %q, which is not a unique stage name", ErrInvalidStageGraph, def.Name, upstream)
+			case count[def.Name] > 1:
+				return fmt.Errorf("%w: stage %q declares dependencies but its name is not unique", ErrInvalidStageGraph, def.Name)
+			}
+			edges[def.Name] = append(edges[def.Name], upstream)
+		}
+	}
+
+	// Depth-first search keeping the current path so a cycle can be reported.
+	const (
+		unvisited = iota
+		visiting
+		visited
+	)
+	state := make(map[string]int, len(defs))
+	var path []string
+	var visit func(name string) error
+	visit = func(name string) error {
+		switch state[name] {
+		case visiting:
+			start := 0
+			for i, n := range path {
+				if n == name {
+					start = i
+					break
+				}
+			}
+			cycle := append(append([]string(nil), path[start:]...), name)
+			return fmt.Errorf("%w: dependency cycle %s", ErrInvalidStageGraph, strings.Join(cycle, " -> "))
+		case visited:
+			return nil
+		}
+		state[name] = visiting
+		path = append(path, name)
+		for _, upstream := range edges[name] {
+			if err := visit(upstream); err != nil {
+				return err
+			}
+		}
+		path = path[:len(path)-1]
+		state[name] = visited
+		return nil
+	}
+	for _, def := range defs {
+		if err := visit(def.Name); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// stageGraph holds the upstream edges between stages of one orchestrator.
+type stageGraph struct {
+	upstream map[uuid.UUID][]uuid.UUID
+}
+
+func newStageGraph() stageGraph {
+	return stageGraph{upstream: make(map[uuid.UUID][]uuid.UUID)}
+}
+
+func (g stageGraph) addDependency(stageID uuid.UUID, dependsOn uuid.UUID) {
+	g.upstream[stageID] = append(g.upstream[stageID], dependsOn)
+}
+
+func (g stageGraph) clone() stageGraph {
+	clone := newStageGraph()
+	for stageID, parents := range g.upstream {
+		clone.upstream[stageID] = append([]uuid.UUID(nil), parents...)
+	}
+	return clone
+}
+
+// downstream returns, for every stage, the stages waiting on it.
+func (g stageGraph) downstream() map[uuid.UUID][]uuid.UUID {
+	children := make(map[uuid.UUID][]uuid.UUID)
+	for stageID, parents := range g.upstream {
+		for _, parent := range parents {
+			children[parent] = append(children[parent], stageID)
+		}
+	}
+	return children
+}
+
+// pendingCounts returns how many upstream stages each stage is waiting on,
+// ignoring edges to stages that are not part of this run.
+func (g stageGraph) pendingCounts(stages []Stage) map[uuid.UUID]int {
+	known := make(map[uuid.UUID]bool, len(stages))
+	for _, stage := range stages {
+		known[stage.GetID()] = true
+	}
+	pending := make(map[uuid.UUID]int, len(stages))
+	for _, stage := range stages {
+		for _, parent := range g.upstream[stage.GetID()] {
+			if known[parent] {
+				pending[stage.GetID()]++
+			}
+		}
+	}
+	return pending
+}
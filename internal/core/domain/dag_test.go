@@ -0,0 +1,48 @@
+package domain
+
+import (
+	"errors"
+	"strings"
+	"testing"
+)
+
+func TestValidateStageGraph(t *testing.T) {
+	stage := func(name string, dependsOn ...string) StageDefinition {
+		return StageDefinition{Name: name, DependsOn: dependsOn}
+	}
+
+	tests := []struct {
+		name    string
+		defs    []StageDefinition
+		wantErr string // Empty when the graph is valid
+	}{
+		{"no dependencies", []StageDefinition{stage("a"), stage("b")}, ""},
+		{"chain", []StageDefinition{stage("a"), stage("b", "a"), stage("c", "b")}, ""},
+		{"diamond", []StageDefinition{stage("a"), stage("b", "a"), stage("c", "a"), stage("d", "b", "c")}, ""},
+		{"dependency declared first", []StageDefinition{stage("b", "a"), stage("a")}, ""},
+		{"duplicate names without dependencies", []StageDefinition{stage("a"), stage("a")}, ""},
+		{"self dependency", []StageDefinition{stage("a", "a")}, `stage "a" depends on itself`},
+		{"unknown stage", []StageDefinition{stage("a", "missing")}, `depends on unknown stage "missing"`},
+		{"ambiguous dependency", []StageDefinition{stage("a"), stage("a"), stage("b", "a")}, "not a unique stage name"},
+		{"ambiguous dependent", []StageDefinition{stage("a"), stage("b", "a"), stage("b")}, "its name is not unique"},
+		{"cycle", []StageDefinition{stage("a", "c"), stage("b", "a"), stage("c", "b")}, "dependency cycle a -> c -> b -> a"},
+		{"cycle off the first stage", []StageDefinition{stage("a"), stage("b", "a", "c"), stage("c", "b")}, "dependency cycle b -> c -> b"},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			err := ValidateStageGraph(tt.defs)
+			if tt.wantErr == "" {
+				if err != nil {
+					t.Fatalf("ValidateStageGraph returned %v, want nil", err)
+				}
+				return
+			}
+			if !errors.Is(err, ErrInvalidStageGraph) {
+				t.Fatalf("ValidateStageGraph returned %v, want %v", err, ErrInvalidStageGraph)
+			}
+			if !strings.Contains(err.Error(), tt.wantErr) {
+				t.Errorf("ValidateStageGraph returned %q, want it to mention %q", err, tt.wantErr)
+			}
+		})
+	}
+}
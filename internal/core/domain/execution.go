@@ -0,0 +1,305 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sarika-p9/my-pipeline-project/internal/core/ports"
+)
+
+type stageOutcome struct {
+	stage  Stage
+	result interface{}
+	err    error
+}
+
+// graphExecution schedules the stages of one run over their dependency graph.
+// Both orchestrators use it; they differ only in maxConcurrent, where zero
+// means no limit. Ready stages are started in the order they were added.
+//
+// A stage with a single upstream stage receives that stage's output as input,
+// and a stage with several receives a map of their outputs keyed by stage
+// name. Stages without upstream stages receive the pipeline input, or with
+// chainOutputs set, the output of the stage that completed before them.
+// Stages in resumed count as completed before the run starts, with the
+// outputs they produced before a restart.
+type graphExecution struct {
+	recorder      *runRecorder
+	stages        []Stage
+	graph         stageGraph
+	input         interface{}
+	maxConcurrent int
+	chainOutputs  bool
+	policy        FailurePolicy
+	resumed       map[uuid.UUID]interface{}
+}
+
+// run executes the stages and returns the pipeline output: the last output
+// with chainOutputs set, otherwise the outputs of the completed stages nothing
+// depends on, keyed by stage name when there is more than one.
+func (e *graphExecution) run(ctx context.Context) (interface{}, error) {
+	pending := e.graph.pendingCounts(e.stages)
+	children := e.graph.downstream()
+	byID := make(map[uuid.UUID]Stage, len(e.stages))
+	for _, stage := range e.stages {
+		byID[stage.GetID()] = stage
+	}
+
+	outcomes := make(chan stageOutcome)
+	started := make(map[uuid.UUID]bool, len(e.stages))
+	skipped := make(map[uuid.UUID]bool)
+	running := 0
+	halted := false
+
+	inputs := make(map[uuid.UUID]interface{}, len(e.stages))
+	outputs := make(map[uuid.UUID]interface{}, len(e.stages))
+	completed := make([]Stage, 0, len(e.stages))
+	errorsSlice := make([]error, 0)
+
+	for _, stage := range e.stages {
+		id := stage.GetID()
+		output, ok := e.resumed[id]
+		if !ok {
+			continue
+		}
+		started[id] = true
+		inputs[id] = e.stageInput(stage, byID, outputs, completed)
+		outputs[id] = output
+		completed = append(completed, stage)
+		for _, childID := range children[id] {
+			if _, ok := byID[childID]; ok {
+				pending[childID]--
+			}
+		}
+	}
+
+	var skipDownstream func(stageID uuid.UUID)
+	skipDownstream = func(stageID uuid.UUID) {
+		for _, childID := range children[stageID] {
+			child, ok := byID[childID]
+			if !ok || skipped[childID] {
+				continue
+			}
+			skipped[childID] = true
+			e.recorder.skipStage(child, "upstream stage did not complete")
+			skipDownstream(childID)
+		}
+	}
+
+	launchReady := func() {
+		for _, stage := range e.stages {
+			if halted || ctx.Err() != nil {
+				return
+			}
+			if e.maxConcurrent > 0 && running >= e.maxConcurrent {
+				return
+			}
+			id := stage.GetID()
+			if started[id] || skipped[id] || pending[id] > 0 {
+				continue
+			}
+			started[id] = true
+			running++
+			input := e.stageInput(stage, byID, outputs, completed)
+			inputs[id] = input
+			go func(stage Stage) {
+				result, err := e.recorder.runStage(ctx, stage, input)
+				outcomes <- stageOutcome{stage: stage, result: result, err: err}
+			}(stage)
+		}
+	}
+
+	launchReady()
+	for running > 0 {
+		outcome := <-outcomes
+		running--
+
+		stageID := outcome.stage.GetID()
+		if outcome.err != nil {
+			errorsSlice = append(errorsSlice, outcome.err)
+			skipDownstream(stageID)
+			if e.policy != FailurePolicyContinue {
+				halted = true
+			}
+		} else {
+			outputs[stageID] = outcome.result
+			completed = append(completed, outcome.stage)
+			for _, childID := range children[stageID] {
+				if _, ok := byID[childID]; ok {
+					pending[childID]--
+				}
+			}
+		}
+		launchReady()
+	}
+
+	cancelled := errors.Is(ctx.Err(), context.Canceled)
+	timedOut := errors.Is(ctx.Err(), context.DeadlineExceeded)
+	if timedOut {
+		errorsSlice = append(errorsSlice, ErrPipelineTimedOut)
+	}
+
+	reason := ""
+	switch {
+	case cancelled:
+		reason = "pipeline cancelled"
+	case timedOut:
+		reason = "pipeline timed out"
+	case halted:
+		reason = "pipeline halted after a stage failure"
+	default:
+		reason = "stage is part of a dependency cycle"
+	}
+	unreachable := false
+	for _, stage := range e.stages {
+		id := stage.GetID()
+		if started[id] || skipped[id] {
+			continue
+		}
+		e.recorder.skipStage(stage, reason)
+		unreachable = true
+	}
+	if unreachable && ctx.Err() == nil && !halted {
+		errorsSlice = append(errorsSlice, ErrInvalidStageGraph)
+	}
+
+	if len(errorsSlice) > 0 && !cancelled && e.policy == FailurePolicyRollback {
+		e.rollback(context.WithoutCancel(ctx), completed, inputs, outputs)
+	}
+
+	output := e.output(children, outputs, completed)
+	if cancelled {
+		return output, ctx.Err()
+	}
+	return output, errors.Join(errorsSlice...)
+}
+
+func (e *graphExecution) stageInput(stage Stage, byID map[uuid.UUID]Stage, outputs map[uuid.UUID]interface{}, completed []Stage) interface{} {
+	var upstream []Stage
+	for _, parentID := range e.graph.upstream[stage.GetID()] {
+		if parent, ok := byID[parentID]; ok {
+			upstream = append(upstream, parent)
+		}
+	}
+
+	switch {
+	case len(upstream) == 1:
+		return outputs[upstream[0].GetID()]
+	case len(upstream) > 1:
+		input := make(map[string]interface{}, len(upstream))
+		for _, parent := range upstream {
+			input[parent.GetName()] = outputs[parent.GetID()]
+		}
+		return input
+	case e.chainOutputs && len(completed) > 0:
+		return outputs[completed[len(completed)-1].GetID()]
+	default:
+		return e.input
+	}
+}
+
+func (e *graphExecution) output(children map[uuid.UUID][]uuid.UUID, outputs map[uuid.UUID]interface{}, completed []Stage) interface{} {
+	if len(completed) == 0 {
+		return nil
+	}
+	if e.chainOutputs {
+		return outputs[completed[len(completed)-1].GetID()]
+	}
+
+	var sinks []Stage
+	for _, stage := range completed {
+		if len(children[stage.GetID()]) == 0 {
+			sinks = append(sinks, stage)
+		}
+	}
+	if len(sinks) == 1 {
+		return outputs[sinks[0].GetID()]
+	}
+	output := make(map[string]interface{}, len(sinks))
+	for _, stage := range sinks {
+		output[stage.GetName()] = outputs[stage.GetID()]
+	}
+	return output
+}
+
+// rollback compensates completed stages, most recently completed first. Each
+// stage gets the input it ran with and the output it produced.
+func (e *graphExecution) rollback(ctx context.Context, completed []Stage, inputs, outputs map[uuid.UUID]interface{}) {
+	for i := len(completed) - 1; i >= 0; i-- {
+		stage := completed[i]
+		e.recorder.reportStageStatus(stage, "RollingBack", "")
+		if err := stage.Rollback(ctx, inputs[stage.GetID()], outputs[stage.GetID()]); err != nil {
+			log.Printf("Rollback of stage %s (%s) failed: %v", stage.GetName(), stage.GetID(), err)
+			e.recorder.reportStageStatus(stage, "RollbackFailed", err.Error())
+			continue
+		}
+		e.recorder.reportStageStatus(stage, "RolledBack", "")
+	}
+}
+
+// execute registers the run, marks it Running, schedules the stages under
+// the pipeline's timeout and records the output and final status. Sequential
+// runs (maxConcurrent of one) chain stage outputs. A resumed run only executes
+// the stages it had not completed, and its timeout starts over.
+func execute(ctx context.Context, runs *RunRegistry, dbRepo ports.PipelineRepository, userID uuid.UUID, pipelineID uuid.UUID, runID uuid.UUID, stages []Stage, graph stageGraph, input interface{}, maxConcurrent int, resume bool) (interface{}, error) {
+	if runID == uuid.Nil {
+		return nil, errors.New("run ID is required")
+	}
+
+	runCtx, err := runs.Start(withRun(ctx, pipelineID, runID), pipelineID, runID)
+	if errors.Is(err, ErrRunCancelled) {
+		// The cancel may have come before the run was saved, so record it
+		// again rather than leave the run Pending.
+		log.Printf("Run %s of pipeline %s was cancelled before it began", runID, pipelineID)
+		if err := markRunCancelled(dbRepo, pipelineID, runID, true); err != nil {
+			log.Printf("Failed to record cancelled run %s: %v", runID, err)
+		}
+		return nil, err
+	}
+	if err != nil {
+		failRun(dbRepo, pipelineID, runID, err)
+		return nil, err
+	}
+	defer runs.Remove(pipelineID)
+
+	recorder, resumed, err := beginExecution(dbRepo, userID, pipelineID, runID, stages, resume)
+	if errors.Is(err, ports.ErrRunAlreadyCancelled) {
+		log.Printf("Run %s of pipeline %s was cancelled before it began", runID, pipelineID)
+		return nil, err
+	}
+	if err != nil {
+		failRun(dbRepo, pipelineID, runID, err)
+		return nil, err
+	}
+	pipeline := recorder.pipeline
+
+	policy, err := ParseFailurePolicy(pipeline.FailurePolicy)
+	if err != nil {
+		log.Printf("Pipeline %s has %v, falling back to %s", pipelineID, err, FailurePolicyHalt)
+		policy = FailurePolicyHalt
+	}
+
+	execution := &graphExecution{
+		recorder:      recorder,
+		stages:        stages,
+		graph:         graph,
+		input:         input,
+		maxConcurrent: maxConcurrent,
+		chainOutputs:  maxConcurrent == 1,
+		policy:        policy,
+		resumed:       resumed,
+	}
+	if pipeline.TimeoutMs > 0 {
+		var cancel context.CancelFunc
+		runCtx, cancel = context.WithTimeout(runCtx, time.Duration(pipeline.TimeoutMs)*time.Millisecond)
+		defer cancel()
+	}
+	output, err := execution.run(runCtx)
+
+	recorder.saveOutput(output)
+	recorder.finish(runs, err)
+	return output, err
+}
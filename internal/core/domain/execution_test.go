@@ -0,0 +1,347 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"reflect"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sarika-p9/my-pipeline-project/internal/models"
+)
+
+// testStage runs a function instead of sleeping, and records its rollbacks
+// with the pipelineTest it belongs to.
+type testStage struct {
+	*BaseStage
+	run          func(ctx context.Context, input interface{}) (interface{}, error)
+	rollbackErr  error
+	pipelineTest *pipelineTest
+}
+
+func (s *testStage) Execute(ctx context.Context, pipelineName string, input interface{}) (interface{}, error) {
+	return s.run(ctx, input)
+}
+
+func (s *testStage) Rollback(ctx context.Context, input interface{}, output interface{}) error {
+	s.pipelineTest.mu.Lock()
+	defer s.pipelineTest.mu.Unlock()
+	s.pipelineTest.rollbacks = append(s.pipelineTest.rollbacks, rollbackCall{s.Name, input, output})
+	return s.rollbackErr
+}
+
+type rollbackCall struct {
+	stage  string
+	input  interface{}
+	output interface{}
+}
+
+// appendName is a stage function passing on its input with ">name" appended.
+func appendName(name string) func(context.Context, interface{}) (interface{}, error) {
+	return func(ctx context.Context, input interface{}) (interface{}, error) {
+		return input.(string) + ">" + name, nil
+	}
+}
+
+func failWith(err error) func(context.Context, interface{}) (interface{}, error) {
+	return func(ctx context.Context, input interface{}) (interface{}, error) {
+		return nil, err
+	}
+}
+
+// waitForCancel blocks until the stage is stopped, closing started first.
+func waitForCancel(started chan struct{}) func(context.Context, interface{}) (interface{}, error) {
+	return func(ctx context.Context, input interface{}) (interface{}, error) {
+		close(started)
+		<-ctx.Done()
+		return nil, ctx.Err()
+	}
+}
+
+// pipelineTest runs the stages of one pipeline in memory.
+type pipelineTest struct {
+	t            *testing.T
+	repo         *memoryRepository
+	orchestrator PipelineOrchestrator
+	pipelineID   uuid.UUID
+	userID       uuid.UUID
+	runID        uuid.UUID
+	stages       map[string]*testStage
+
+	mu        sync.Mutex
+	rollbacks []rollbackCall
+}
+
+func newPipelineTest(t *testing.T, pipeline models.Pipelines) *pipelineTest {
+	repo := newMemoryRepository()
+	pipeline.PipelineID = uuid.New()
+	userID, runID := repo.addPipeline(pipeline)
+	return &pipelineTest{
+		t:            t,
+		repo:         repo,
+		orchestrator: NewPipelineOrchestrator(pipeline.IsParallel, pipeline.PipelineID, repo, NewRunRegistry()),
+		pipelineID:   pipeline.PipelineID,
+		userID:       userID,
+		runID:        runID,
+		stages:       make(map[string]*testStage),
+	}
+}
+
+// addStage adds a stage running fn after the named upstream stages.
+func (p *pipelineTest) addStage(name string, fn func(context.Context, interface{}) (interface{}, error), dependsOn ...string) *testStage {
+	stage := &testStage{BaseStage: NewBaseStage(name), run: fn, pipelineTest: p}
+	if err := p.orchestrator.AddStage(stage); err != nil {
+		p.t.Fatalf("AddStage(%s): %v", name, err)
+	}
+	for _, upstream := range dependsOn {
+		if err := p.orchestrator.AddStageDependency(stage.ID, p.stages[upstream].ID); err != nil {
+			p.t.Fatalf("AddStageDependency(%s, %s): %v", name, upstream, err)
+		}
+	}
+	p.stages[name] = stage
+	return stage
+}
+
+func (p *pipelineTest) execute(ctx context.Context, input interface{}) (interface{}, error) {
+	_, output, err := p.orchestrator.Execute(ctx, p.userID, p.pipelineID, p.runID, input)
+	return output, err
+}
+
+// wantStatuses checks the status each named stage ended with in the run.
+func (p *pipelineTest) wantStatuses(want map[string]string) {
+	p.t.Helper()
+	for name, status := range want {
+		if got := p.repo.stageRun(p.runID, name).Status; got != status {
+			p.t.Errorf("stage %s ended as %s, want %s", name, got, status)
+		}
+	}
+}
+
+func (p *pipelineTest) wantRunStatus(want string) {
+	p.t.Helper()
+	if got := p.repo.run(p.runID).Status; got != want {
+		p.t.Errorf("run ended as %s, want %s", got, want)
+	}
+}
+
+func TestSequentialExecutionChainsOutputs(t *testing.T) {
+	p := newPipelineTest(t, models.Pipelines{})
+	p.addStage("a", appendName("a"))
+	p.addStage("b", appendName("b"))
+	p.addStage("c", appendName("c"))
+
+	output, err := p.execute(context.Background(), "in")
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	if output != "in>a>b>c" {
+		t.Errorf("output = %v, want in>a>b>c", output)
+	}
+	p.wantStatuses(map[string]string{"a": "Completed", "b": "Completed", "c": "Completed"})
+	p.wantRunStatus("Completed")
+}
+
+func TestParallelExecutionPassesUpstreamOutputs(t *testing.T) {
+	p := newPipelineTest(t, models.Pipelines{IsParallel: true})
+	p.addStage("a", appendName("a"))
+	p.addStage("b", appendName("b"))
+	p.addStage("c", func(ctx context.Context, input interface{}) (interface{}, error) {
+		return input, nil
+	}, "a", "b")
+	p.addStage("d", appendName("d"), "a")
+
+	output, err := p.execute(context.Background(), "in")
+	if err != nil {
+		t.Fatalf("Execute: %v", err)
+	}
+	// c gets a map of its upstream outputs by stage name, and the pipeline
+	// output holds the outputs of c and d, which nothing depends on.
+	want := map[string]interface{}{
+		"c": map[string]interface{}{"a": "in>a", "b": "in>b"},
+		"d": "in>a>d",
+	}
+	if !reflect.DeepEqual(output, want) {
+		t.Errorf("output = %v, want %v", output, want)
+	}
+	p.wantRunStatus("Completed")
+}
+
+func TestExecutionConcurrency(t *testing.T) {
+	tests := []struct {
+		isParallel bool
+		want       int32
+	}{
+		{isParallel: false, want: 1},
+		{isParallel: true, want: 3},
+	}
+	for _, tt := range tests {
+		t.Run(map[bool]string{false: "sequential", true: "parallel"}[tt.isParallel], func(t *testing.T) {
+			p := newPipelineTest(t, models.Pipelines{IsParallel: tt.isParallel})
+
+			// Each stage waits for the others to start, for a while when
+			// they run one at a time.
+			var running, maxRunning atomic.Int32
+			var arrived sync.WaitGroup
+			arrived.Add(int(tt.want))
+			for _, name := range []string{"a", "b", "c"} {
+				p.addStage(name, func(ctx context.Context, input interface{}) (interface{}, error) {
+					n := running.Add(1)
+					defer running.Add(-1)
+					for {
+						max := maxRunning.Load()
+						if n <= max || maxRunning.CompareAndSwap(max, n) {
+							break
+						}
+					}
+					if tt.isParallel {
+						arrived.Done()
+						arrived.Wait()
+					} else {
+						time.Sleep(10 * time.Millisecond)
+					}
+					return input, nil
+				})
+			}
+
+			ctx, cancel := context.WithTimeout(context.Background(), 5*time.Second)
+			defer cancel()
+			if _, err := p.execute(ctx, "in"); err != nil {
+				t.Fatalf("Execute: %v", err)
+			}
+			if got := maxRunning.Load(); got != tt.want {
+				t.Errorf("%d stages ran at once, want %d", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestExecutionFailurePolicies(t *testing.T) {
+	errJammed := errors.New("jammed")
+	tests := []struct {
+		policy        FailurePolicy
+		rollbackErr   error // Returned by c's rollback
+		wantStatuses  map[string]string
+		wantRollbacks []rollbackCall
+	}{
+		{
+			policy:       FailurePolicyHalt,
+			wantStatuses: map[string]string{"a": "Completed", "c": "Completed", "b": "Failed", "d": "Skipped", "e": "Skipped"},
+		},
+		{
+			policy:       FailurePolicyContinue,
+			wantStatuses: map[string]string{"a": "Completed", "c": "Completed", "b": "Failed", "d": "Skipped", "e": "Completed"},
+		},
+		{
+			policy:       FailurePolicyRollback,
+			wantStatuses: map[string]string{"a": "RolledBack", "c": "RolledBack", "b": "Failed", "d": "Skipped", "e": "Skipped"},
+			wantRollbacks: []rollbackCall{
+				{stage: "c", input: "in>a", output: "in>a>c"},
+				{stage: "a", input: "in", output: "in>a"},
+			},
+		},
+		{
+			policy:       FailurePolicyRollback,
+			rollbackErr:  errors.New("stuck"),
+			wantStatuses: map[string]string{"a": "RolledBack", "c": "RollbackFailed", "b": "Failed", "d": "Skipped", "e": "Skipped"},
+			wantRollbacks: []rollbackCall{
+				{stage: "c", input: "in>a", output: "in>a>c"},
+				{stage: "a", input: "in", output: "in>a"},
+			},
+		},
+	}
+	for _, tt := range tests {
+		name := string(tt.policy)
+		if tt.rollbackErr != nil {
+			name += " with failing rollback"
+		}
+		t.Run(name, func(t *testing.T) {
+			p := newPipelineTest(t, models.Pipelines{FailurePolicy: string(tt.policy)})
+			p.addStage("a", appendName("a"))
+			p.addStage("c", appendName("c")).rollbackErr = tt.rollbackErr
+			p.addStage("b", failWith(errJammed))
+			p.addStage("d", appendName("d"), "b")
+			p.addStage("e", func(ctx context.Context, input interface{}) (interface{}, error) {
+				return "e", nil
+			})
+
+			_, err := p.execute(context.Background(), "in")
+			if err == nil || err.Error() != "stage execution failed: jammed" {
+				t.Errorf("Execute returned %v, want the failure of b", err)
+			}
+			p.wantStatuses(tt.wantStatuses)
+			p.wantRunStatus("Failed")
+			if !reflect.DeepEqual(p.rollbacks, tt.wantRollbacks) {
+				t.Errorf("rollbacks = %v, want %v", p.rollbacks, tt.wantRollbacks)
+			}
+			for name := range tt.wantStatuses {
+				if p.repo.stageRun(p.runID, name).FinishedAt == nil {
+					t.Errorf("stage %s has no finished_at", name)
+				}
+			}
+		})
+	}
+}
+
+func TestExecutionCancel(t *testing.T) {
+	for _, isParallel := range []bool{false, true} {
+		t.Run(map[bool]string{false: "sequential", true: "parallel"}[isParallel], func(t *testing.T) {
+			p := newPipelineTest(t, models.Pipelines{IsParallel: isParallel, FailurePolicy: string(FailurePolicyRollback)})
+			started := make(chan struct{})
+			p.addStage("a", waitForCancel(started))
+			p.addStage("b", appendName("b"), "a")
+
+			done := make(chan error, 1)
+			go func() {
+				_, err := p.execute(context.Background(), "in")
+				done <- err
+			}()
+			<-started
+			if err := p.orchestrator.Cancel(p.pipelineID, p.runID, p.userID); err != nil {
+				t.Fatalf("Cancel: %v", err)
+			}
+
+			select {
+			case err := <-done:
+				if !errors.Is(err, context.Canceled) {
+					t.Errorf("Execute returned %v, want %v", err, context.Canceled)
+				}
+			case <-time.After(5 * time.Second):
+				t.Fatal("the run did not stop after Cancel")
+			}
+			p.wantStatuses(map[string]string{"a": "Cancelled", "b": "Skipped"})
+			p.wantRunStatus("Cancelled")
+			if len(p.rollbacks) != 0 {
+				t.Errorf("cancelled run rolled back %v", p.rollbacks)
+			}
+		})
+	}
+}
+
+func TestExecutionTimeouts(t *testing.T) {
+	t.Run("stage", func(t *testing.T) {
+		p := newPipelineTest(t, models.Pipelines{})
+		p.addStage("a", waitForCancel(make(chan struct{}))).Timeout = 20 * time.Millisecond
+		p.addStage("b", appendName("b"))
+
+		if _, err := p.execute(context.Background(), "in"); err == nil {
+			t.Error("Execute returned no error")
+		}
+		p.wantStatuses(map[string]string{"a": "TimedOut", "b": "Skipped"})
+		p.wantRunStatus("Failed")
+	})
+
+	t.Run("pipeline", func(t *testing.T) {
+		p := newPipelineTest(t, models.Pipelines{TimeoutMs: 20})
+		p.addStage("a", waitForCancel(make(chan struct{})))
+		p.addStage("b", appendName("b"))
+
+		if _, err := p.execute(context.Background(), "in"); !errors.Is(err, ErrPipelineTimedOut) {
+			t.Errorf("Execute returned %v, want %v", err, ErrPipelineTimedOut)
+		}
+		p.wantStatuses(map[string]string{"a": "TimedOut", "b": "Skipped"})
+		p.wantRunStatus("TimedOut")
+	})
+}
@@ -0,0 +1,31 @@
+package domain
+
+import (
+	"errors"
+	"fmt"
+)
+
+// FailurePolicy decides what an orchestrator does once a stage fails.
+type FailurePolicy string
+
+const (
+	// FailurePolicyHalt stops starting new stages; stages already running finish.
+	FailurePolicyHalt FailurePolicy = "halt"
+	// FailurePolicyContinue keeps running every stage that does not depend on the failed one.
+	FailurePolicyContinue FailurePolicy = "continue"
+	// FailurePolicyRollback halts and then compensates completed stages in reverse completion order.
+	FailurePolicyRollback FailurePolicy = "rollback"
+)
+
+var ErrInvalidFailurePolicy = errors.New("invalid failure policy")
+
+// ParseFailurePolicy validates a policy name, defaulting to halt when empty.
+func ParseFailurePolicy(policy string) (FailurePolicy, error) {
+	switch FailurePolicy(policy) {
+	case "":
+		return FailurePolicyHalt, nil
+	case FailurePolicyHalt, FailurePolicyContinue, FailurePolicyRollback:
+		return FailurePolicy(policy), nil
+	}
+	return "", fmt.Errorf("%w %q: must be one of halt, continue, rollback", ErrInvalidFailurePolicy, policy)
+}
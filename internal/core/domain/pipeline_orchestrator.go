@@ -2,13 +2,460 @@ package domain
 
 import (
 	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"sync"
+	"time"
 
 	"github.com/google/uuid"
+	"github.com/sarika-p9/my-pipeline-project/internal/core/ports"
+	"github.com/sarika-p9/my-pipeline-project/internal/infrastructure"
+	"github.com/sarika-p9/my-pipeline-project/internal/models"
 )
 
 type PipelineOrchestrator interface {
 	AddStage(stage Stage) error
-	Execute(ctx context.Context, userID uuid.UUID, pipelineID uuid.UUID, input interface{}) (uuid.UUID, interface{}, error)
-	GetStatus(pipelineID uuid.UUID) (string, error)
-	Cancel(pipelineID uuid.UUID, userID uuid.UUID) error
+	AddStageDependency(stageID uuid.UUID, dependsOn uuid.UUID) error
+	// Execute performs the run created for runID and returns its ID and output.
+	Execute(ctx context.Context, userID uuid.UUID, pipelineID uuid.UUID, runID uuid.UUID, input interface{}) (uuid.UUID, interface{}, error)
+	// Resume performs a run that was left unfinished, skipping the stages it
+	// had already completed and reusing their outputs.
+	Resume(ctx context.Context, userID uuid.UUID, pipelineID uuid.UUID, runID uuid.UUID, input interface{}) (uuid.UUID, interface{}, error)
+	// GetStatus and Cancel act on the given run, or on the latest run when
+	// runID is uuid.Nil.
+	GetStatus(pipelineID uuid.UUID, runID uuid.UUID) (string, error)
+	Cancel(pipelineID uuid.UUID, runID uuid.UUID, userID uuid.UUID) error
+}
+
+var (
+	_ PipelineOrchestrator = (*SequentialPipelineOrchestrator)(nil)
+	_ PipelineOrchestrator = (*ParallelPipelineOrchestrator)(nil)
+)
+
+// NewPipelineOrchestrator returns the orchestrator for the requested execution
+// mode. Orchestrators sharing a RunRegistry can cancel each other's runs.
+func NewPipelineOrchestrator(isParallel bool, pipelineID uuid.UUID, dbRepo ports.PipelineRepository, runs *RunRegistry) PipelineOrchestrator {
+	if isParallel {
+		return NewParallelPipelineOrchestrator(pipelineID, dbRepo, runs)
+	}
+	return NewSequentialPipelineOrchestrator(pipelineID, dbRepo, runs)
+}
+
+// runRecorder writes the progress of one pipeline run: to the run's own rows,
+// to the Pipelines and Stages rows that mirror the latest run, and to the
+// WebSocket.
+type runRecorder struct {
+	dbRepo   ports.PipelineRepository
+	pipeline *models.Pipelines
+	runID    uuid.UUID
+}
+
+// beginExecution validates the caller and marks the pipeline and run as
+// Running, creating a Pending stage run for every stage. When resuming, it
+// keeps the stage runs that completed and returns their outputs instead.
+func beginExecution(dbRepo ports.PipelineRepository, userID uuid.UUID, pipelineID uuid.UUID, runID uuid.UUID, stages []Stage, resume bool) (*runRecorder, map[uuid.UUID]interface{}, error) {
+	user, err := dbRepo.GetUserByID(userID)
+	if err != nil {
+		log.Printf("Failed to validate user existence: %v", err)
+		return nil, nil, err
+	}
+	if user == nil {
+		return nil, nil, errors.New("user does not exist")
+	}
+
+	pipeline, err := dbRepo.GetPipelineByID(pipelineID)
+	if err != nil {
+		log.Printf("Failed to fetch pipeline details: %v", err)
+		return nil, nil, err
+	}
+
+	if err := dbRepo.UpdatePipelineRun(runID, map[string]interface{}{
+		"status":     "Running",
+		"started_at": time.Now(),
+	}); err != nil {
+		log.Printf("Failed to update pipeline run status: %v", err)
+		return nil, nil, err
+	}
+
+	var resumed map[uuid.UUID]interface{}
+	if resume {
+		resumed, err = resumeStageRuns(dbRepo, pipelineID, runID, stages)
+		if err != nil {
+			log.Printf("Failed to prepare stage runs for resuming: %v", err)
+			return nil, nil, err
+		}
+	} else {
+		stageRuns := make([]models.StageRun, len(stages))
+		for i, stage := range stages {
+			stageRuns[i] = models.StageRun{
+				StageRunID: uuid.New(),
+				RunID:      runID,
+				StageID:    stage.GetID(),
+				PipelineID: pipelineID,
+				StageName:  stage.GetName(),
+				Position:   i,
+				Status:     "Pending",
+			}
+		}
+		if err := dbRepo.SaveStageRuns(stageRuns); err != nil {
+			log.Printf("Failed to create stage runs: %v", err)
+			return nil, nil, err
+		}
+	}
+
+	if err := dbRepo.UpdatePipelineExecution(&models.Pipelines{
+		PipelineID:   pipelineID,
+		PipelineName: pipeline.PipelineName,
+		Status:       "Running",
+		UpdatedAt:    time.Now(),
+	}); err != nil {
+		log.Printf("Failed to update pipeline execution status: %v", err)
+		return nil, nil, err
+	}
+	reportRunStatus(dbRepo, pipeline, runID, "Running")
+
+	return &runRecorder{dbRepo: dbRepo, pipeline: pipeline, runID: runID}, resumed, nil
+}
+
+// failRun records a run that could not start.
+func failRun(dbRepo ports.PipelineRepository, pipelineID uuid.UUID, runID uuid.UUID, cause error) {
+	if err := dbRepo.UpdatePipelineRun(runID, map[string]interface{}{
+		"status":      "Failed",
+		"error_msg":   cause.Error(),
+		"finished_at": time.Now(),
+	}); err != nil {
+		log.Printf("Failed to record failed start of run %s: %v", runID, err)
+	}
+	if pipeline, err := dbRepo.GetPipelineByID(pipelineID); err == nil {
+		reportRunStatus(dbRepo, pipeline, runID, "Failed")
+	}
+}
+
+// finish records the final status once every stage has been handled. A run
+// that was cancelled, here or through another replica, always ends as
+// Cancelled.
+func (r *runRecorder) finish(runs *RunRegistry, runErr error) string {
+	finalStatus := "Completed"
+	switch {
+	case errors.Is(runErr, ErrPipelineTimedOut):
+		finalStatus = "TimedOut"
+	case runErr != nil:
+		finalStatus = "Failed"
+	}
+	if runs.Finish(r.pipeline.PipelineID) {
+		finalStatus = "Cancelled"
+	}
+
+	updates := map[string]interface{}{"status": finalStatus, "finished_at": time.Now()}
+	if runErr != nil {
+		updates["error_msg"] = runErr.Error()
+	}
+	err := r.dbRepo.UpdatePipelineRun(r.runID, updates)
+	switch {
+	case errors.Is(err, ports.ErrRunAlreadyCancelled):
+		finalStatus = "Cancelled"
+	case err != nil:
+		log.Printf("Failed to update final status of run %s: %v", r.runID, err)
+	}
+
+	if err := r.dbRepo.UpdatePipelineExecution(&models.Pipelines{
+		PipelineID:   r.pipeline.PipelineID,
+		PipelineName: r.pipeline.PipelineName,
+		Status:       finalStatus,
+		UpdatedAt:    time.Now(),
+	}); err != nil {
+		log.Printf("Failed to update final pipeline execution status: %v", err)
+	}
+	reportRunStatus(r.dbRepo, r.pipeline, r.runID, finalStatus)
+
+	return finalStatus
+}
+
+// runStage executes a single stage and reports its Running and terminal
+// status, identically for every mode. Each attempt is bounded by the stage's
+// timeout. Failed or timed out attempts are retried according to the stage's
+// retry policy, and the final failure is passed through the stage's
+// HandleError before being recorded.
+func (r *runRecorder) runStage(ctx context.Context, stage Stage, input interface{}) (interface{}, error) {
+	policy := retryPolicyOf(stage)
+	r.updateStageRun(stage, map[string]interface{}{"started_at": time.Now()})
+	r.reportStageStatus(stage, "Running", "")
+
+	for attempt := 1; ; attempt++ {
+		startedAt := time.Now()
+		result, err := executeAttempt(ctx, stage, r.pipeline.PipelineName, input)
+		r.recordAttempt(ctx, stage, attempt, startedAt, err)
+
+		if err == nil {
+			r.saveStageOutput(stage, result)
+			r.reportStageStatus(stage, "Completed", "")
+			return result, nil
+		}
+		if ctx.Err() != nil {
+			r.reportStageStatus(stage, interruptedStatus(ctx), err.Error())
+			return nil, err
+		}
+
+		if attempt < policy.attempts() && policy.Retryable(err) {
+			backoff := policy.Backoff(attempt)
+			log.Printf("Stage %s attempt %d/%d failed, retrying in %s: %v", stage.GetName(), attempt, policy.attempts(), backoff, err)
+			r.reportStageStatus(stage, "Retrying", err.Error())
+
+			select {
+			case <-time.After(backoff):
+			case <-ctx.Done():
+				r.reportStageStatus(stage, interruptedStatus(ctx), ctx.Err().Error())
+				return nil, ctx.Err()
+			}
+
+			r.reportStageStatus(stage, "Running", "")
+			continue
+		}
+
+		status := "Failed"
+		if errors.Is(err, ErrStageTimedOut) {
+			status = "TimedOut"
+		}
+		if handled := stage.HandleError(ctx, err); handled != nil {
+			err = handled
+		}
+		r.reportStageStatus(stage, status, err.Error())
+		return nil, err
+	}
+}
+
+func (r *runRecorder) recordAttempt(ctx context.Context, stage Stage, attempt int, startedAt time.Time, err error) {
+	entry := &models.StageAttempts{
+		AttemptID:  uuid.New(),
+		StageID:    stage.GetID(),
+		PipelineID: r.pipeline.PipelineID,
+		RunID:      r.runID,
+		Attempt:    attempt,
+		Status:     "Completed",
+		StartedAt:  startedAt,
+		FinishedAt: time.Now(),
+	}
+	if err != nil {
+		switch {
+		case ctx.Err() != nil:
+			entry.Status = interruptedStatus(ctx)
+		case errors.Is(err, ErrStageTimedOut):
+			entry.Status = "TimedOut"
+		default:
+			entry.Status = "Failed"
+		}
+		entry.ErrorMsg = err.Error()
+	}
+
+	if err := r.dbRepo.SaveStageAttempt(entry); err != nil {
+		log.Printf("Failed to save attempt %d of stage %s: %v", attempt, stage.GetName(), err)
+	}
+}
+
+func (r *runRecorder) saveStageOutput(stage Stage, output interface{}) {
+	encoded, err := encodeOutput(output)
+	if err != nil {
+		log.Printf("Failed to encode output of stage %s: %v", stage.GetName(), err)
+		return
+	}
+	r.updateStageRun(stage, map[string]interface{}{"output": encoded})
+	if err := r.dbRepo.UpdateStageOutput(stage.GetID(), encoded); err != nil {
+		log.Printf("Failed to save output of stage %s: %v", stage.GetName(), err)
+	}
+}
+
+func (r *runRecorder) saveOutput(output interface{}) {
+	encoded, err := encodeOutput(output)
+	if err != nil {
+		log.Printf("Failed to encode output of pipeline %s: %v", r.pipeline.PipelineID, err)
+		return
+	}
+	if err := r.dbRepo.UpdatePipelineRun(r.runID, map[string]interface{}{"output": encoded}); err != nil {
+		log.Printf("Failed to save output of run %s: %v", r.runID, err)
+	}
+	if err := r.dbRepo.UpdatePipelineOutput(r.pipeline.PipelineID, encoded); err != nil {
+		log.Printf("Failed to save output of pipeline %s: %v", r.pipeline.PipelineID, err)
+	}
+}
+
+// encodeOutput renders an output as JSON for storage. A nil output is stored
+// as an empty string.
+func encodeOutput(output interface{}) (string, error) {
+	if output == nil {
+		return "", nil
+	}
+	encoded, err := json.Marshal(output)
+	if err != nil {
+		return "", err
+	}
+	return string(encoded), nil
+}
+
+func (r *runRecorder) reportStageStatus(stage Stage, status string, errorMsg string) {
+	if err := r.dbRepo.UpdateStageError(stage.GetID(), status, errorMsg); err != nil {
+		log.Printf("Failed to update stage %s to %s: %v", stage.GetName(), status, err)
+	}
+
+	updates := map[string]interface{}{"status": status, "error_msg": errorMsg}
+	if isStageRunFinished(status) {
+		updates["finished_at"] = time.Now()
+	}
+	r.updateStageRun(stage, updates)
+
+	publishUpdate(r.dbRepo, infrastructure.PipelineUpdate{
+		Type:         infrastructure.StageUpdateType,
+		PipelineID:   r.pipeline.PipelineID,
+		PipelineName: r.pipeline.PipelineName,
+		UserID:       r.pipeline.UserID,
+		RunID:        r.runID,
+		StageID:      stage.GetID(),
+		StageName:    stage.GetName(),
+		Status:       status,
+	})
+}
+
+// reportRunStatus sends the run's new status to the clients following the
+// pipeline.
+func reportRunStatus(dbRepo ports.PipelineRepository, pipeline *models.Pipelines, runID uuid.UUID, status string) {
+	publishUpdate(dbRepo, infrastructure.PipelineUpdate{
+		Type:         infrastructure.RunUpdateType,
+		PipelineID:   pipeline.PipelineID,
+		PipelineName: pipeline.PipelineName,
+		UserID:       pipeline.UserID,
+		RunID:        runID,
+		Status:       status,
+	})
+}
+
+// updateOrder serializes sequencing and sending the updates of a pipeline, so
+// clients receive them in sequence order. Pipelines share the locks by their
+// ID's first byte.
+var updateOrder [64]sync.Mutex
+
+// publishUpdate sequences the update and sends it to the clients following
+// the pipeline. Without a sequence, the update is still sent live but clients
+// can't resume from it.
+func publishUpdate(dbRepo ports.PipelineRepository, update infrastructure.PipelineUpdate) {
+	lock := &updateOrder[int(update.PipelineID[0])%len(updateOrder)]
+	lock.Lock()
+	defer lock.Unlock()
+
+	sequence, err := dbRepo.NextEventSequence(update.PipelineID)
+	if err != nil {
+		log.Printf("Failed to sequence the %s of pipeline %s: %v", update.Type, update.PipelineID, err)
+	}
+	update.Sequence = sequence
+	infrastructure.WebSocket.SendMessage(update)
+}
+
+func (r *runRecorder) updateStageRun(stage Stage, updates map[string]interface{}) {
+	if err := r.dbRepo.UpdateStageRun(r.runID, stage.GetID(), updates); err != nil {
+		log.Printf("Failed to update run of stage %s: %v", stage.GetName(), err)
+	}
+}
+
+func (r *runRecorder) skipStage(stage Stage, reason string) {
+	r.reportStageStatus(stage, "Skipped", reason)
+}
+
+// FindPipelineRun returns the given run of the pipeline, or its latest run
+// when runID is uuid.Nil.
+func FindPipelineRun(dbRepo ports.PipelineRepository, pipelineID uuid.UUID, runID uuid.UUID) (*models.PipelineRun, error) {
+	if runID == uuid.Nil {
+		return dbRepo.GetLatestPipelineRun(pipelineID)
+	}
+
+	run, err := dbRepo.GetPipelineRun(runID)
+	if err != nil {
+		return nil, err
+	}
+	if run.PipelineID != pipelineID {
+		return nil, fmt.Errorf("%w: run %s does not belong to pipeline %s", ports.ErrRunNotFound, runID, pipelineID)
+	}
+	return run, nil
+}
+
+// GetPipelineStatus returns the status of the given run, or of the pipeline's
+// latest run when runID is uuid.Nil. It reads only persisted state.
+func GetPipelineStatus(dbRepo ports.PipelineRepository, pipelineID uuid.UUID, runID uuid.UUID) (string, error) {
+	if runID == uuid.Nil {
+		return dbRepo.GetPipelineStatus(pipelineID.String())
+	}
+
+	run, err := FindPipelineRun(dbRepo, pipelineID, runID)
+	if err != nil {
+		return "", err
+	}
+	return run.Status, nil
+}
+
+// cancelPipeline stops the given run, or the active run when runID is
+// uuid.Nil, and records it as Cancelled. The run itself then skips its pending
+// stages and keeps the Cancelled status when it finishes. A run that is not
+// executing in this process is only marked Cancelled.
+func cancelPipeline(runs *RunRegistry, dbRepo ports.PipelineRepository, pipelineID uuid.UUID, runID uuid.UUID, userID uuid.UUID) error {
+	log.Printf("Cancelling pipeline: %s for user: %s", pipelineID, userID)
+
+	if activeRunID, active := runs.ActiveRun(pipelineID); active && (runID == uuid.Nil || runID == activeRunID) {
+		switch err := runs.Cancel(pipelineID); {
+		case err == nil:
+			log.Printf("Signalled run %s of pipeline %s to stop", activeRunID, pipelineID)
+			return markRunCancelled(dbRepo, pipelineID, activeRunID, true)
+		case errors.Is(err, ErrRunFinishing):
+			log.Printf("Pipeline %s is already finishing, cannot cancel", pipelineID)
+			return err
+		}
+		runID = activeRunID
+	}
+
+	run, err := FindPipelineRun(dbRepo, pipelineID, runID)
+	if err != nil {
+		log.Printf("Error fetching pipeline run: %v", err)
+		return err
+	}
+	if IsRunFinished(run.Status) {
+		log.Printf("Run %s of pipeline %s already finished as %s, cannot cancel", run.RunID, pipelineID, run.Status)
+		return fmt.Errorf("%w: %s", ErrRunFinished, run.Status)
+	}
+
+	latest, err := dbRepo.GetLatestPipelineRun(pipelineID)
+	if err != nil {
+		log.Printf("Error fetching latest pipeline run: %v", err)
+		return err
+	}
+	return markRunCancelled(dbRepo, pipelineID, run.RunID, latest.RunID == run.RunID)
+}
+
+// markRunCancelled records the run as Cancelled, and the pipeline too when the
+// run is its latest.
+func markRunCancelled(dbRepo ports.PipelineRepository, pipelineID uuid.UUID, runID uuid.UUID, isLatest bool) error {
+	log.Printf("Cancelling run %s of pipeline %s...", runID, pipelineID)
+
+	if err := dbRepo.UpdatePipelineRun(runID, map[string]interface{}{
+		"status":      "Cancelled",
+		"finished_at": time.Now(),
+	}); err != nil {
+		log.Printf("Failed to update run status: %v", err)
+		return errors.New("failed to update pipeline run status")
+	}
+	if pipeline, err := dbRepo.GetPipelineByID(pipelineID); err == nil {
+		reportRunStatus(dbRepo, pipeline, runID, "Cancelled")
+	}
+
+	if isLatest {
+		err := dbRepo.UpdatePipelineExecution(&models.Pipelines{
+			PipelineID: pipelineID,
+			Status:     "Cancelled",
+			UpdatedAt:  time.Now(),
+		})
+		if err != nil {
+			log.Printf("Failed to update pipeline status: %v", err)
+			return errors.New("failed to update pipeline status")
+		}
+	}
+
+	log.Printf("Run %s of pipeline %s successfully cancelled", runID, pipelineID)
+	return nil
 }
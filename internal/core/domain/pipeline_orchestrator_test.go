@@ -0,0 +1,74 @@
+package domain
+
+import (
+	"math/rand"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sarika-p9/my-pipeline-project/internal/infrastructure"
+	"github.com/sarika-p9/my-pipeline-project/internal/models"
+)
+
+// slowSequencer takes a while to return each sequence, like a database
+// round trip, so concurrent updates interleave.
+type slowSequencer struct {
+	*memoryRepository
+}
+
+func (r slowSequencer) NextEventSequence(pipelineID uuid.UUID) (int64, error) {
+	sequence, err := r.memoryRepository.NextEventSequence(pipelineID)
+	time.Sleep(time.Duration(rand.Intn(200)) * time.Microsecond)
+	return sequence, err
+}
+
+func TestPublishUpdateDeliversInSequenceOrder(t *testing.T) {
+	repo := newMemoryRepository()
+	pipelineID := uuid.New()
+	repo.addPipeline(models.Pipelines{PipelineID: pipelineID})
+
+	watcher := infrastructure.WebSocket.Watch(pipelineID, 0)
+	defer watcher.Stop()
+
+	const updates = 100
+	var wg sync.WaitGroup
+	for i := 0; i < updates; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			publishUpdate(slowSequencer{repo}, infrastructure.PipelineUpdate{
+				Type:       infrastructure.StageUpdateType,
+				PipelineID: pipelineID,
+				StageID:    uuid.New(),
+				Status:     "Completed",
+			})
+		}()
+	}
+	wg.Wait()
+
+	// A client resuming from any update it received gets every later one.
+	var lastSeq int64
+	for i := 0; i < updates; i++ {
+		select {
+		case update := <-watcher.Updates():
+			if update.Sequence != lastSeq+1 {
+				t.Fatalf("received seq %d after %d, resuming from it would skip updates", update.Sequence, lastSeq)
+			}
+			lastSeq = update.Sequence
+		case <-time.After(5 * time.Second):
+			t.Fatalf("received %d updates, want %d", i, updates)
+		}
+	}
+
+	resumed := infrastructure.WebSocket.Watch(pipelineID, updates/2)
+	defer resumed.Stop()
+	if resumed.Resync {
+		t.Fatal("resuming asked for a resync")
+	}
+	for seq := int64(updates/2 + 1); seq <= updates; seq++ {
+		if update := <-resumed.Updates(); update.Sequence != seq {
+			t.Fatalf("resumed with seq %d, want %d", update.Sequence, seq)
+		}
+	}
+}
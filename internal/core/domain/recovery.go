@@ -0,0 +1,190 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sarika-p9/my-pipeline-project/internal/core/ports"
+	"github.com/sarika-p9/my-pipeline-project/internal/models"
+)
+
+var ErrInvalidRecoveryPolicy = errors.New("invalid recovery policy")
+
+// RecoveryPolicy decides what happens on startup to runs that a previous
+// process left unfinished.
+type RecoveryPolicy string
+
+const (
+	// RecoveryPolicyInterrupt marks unfinished runs and their unfinished
+	// stages as Interrupted.
+	RecoveryPolicyInterrupt RecoveryPolicy = "interrupt"
+	// RecoveryPolicyResume executes unfinished runs again, keeping the
+	// outputs of the stages they had already completed.
+	RecoveryPolicyResume RecoveryPolicy = "resume"
+)
+
+// RunLeaseDuration is how long a process keeps the unfinished runs it
+// executes without renewing their lease. Other processes recover the runs
+// whose lease expired, since their owner must have stopped.
+const RunLeaseDuration = 30 * time.Second
+
+// RunLeaseRenewInterval is how often a process renews its leases, and looks
+// for expired ones.
+const RunLeaseRenewInterval = RunLeaseDuration / 3
+
+// UnfinishedRunStatuses are the statuses of runs that were still executing
+// when their process stopped.
+var UnfinishedRunStatuses = []string{"Pending", "Running"}
+
+// unfinishedStageStatuses are the statuses of stage runs still executing or
+// rolling back.
+var unfinishedStageStatuses = []string{"Pending", "Running", "Retrying", "RollingBack"}
+
+// IsRunFinished reports whether a run reached a status it will not leave.
+func IsRunFinished(status string) bool {
+	return isFinished(status, UnfinishedRunStatuses)
+}
+
+// ParseRecoveryPolicy validates a recovery policy name. An empty name means
+// RecoveryPolicyInterrupt.
+func ParseRecoveryPolicy(name string) (RecoveryPolicy, error) {
+	switch policy := RecoveryPolicy(strings.ToLower(strings.TrimSpace(name))); policy {
+	case "":
+		return RecoveryPolicyInterrupt, nil
+	case RecoveryPolicyInterrupt, RecoveryPolicyResume:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("%w %q, expected interrupt or resume", ErrInvalidRecoveryPolicy, name)
+	}
+}
+
+// isStageRunFinished reports whether a stage run reached a status it will not
+// leave without a new run.
+func isStageRunFinished(status string) bool {
+	return isFinished(status, unfinishedStageStatuses)
+}
+
+// isFinished reports whether status is none of the unfinished statuses.
+func isFinished(status string, unfinished []string) bool {
+	for _, unfinishedStatus := range unfinished {
+		if status == unfinishedStatus {
+			return false
+		}
+	}
+	return true
+}
+
+// InterruptRun records an unfinished run, and its unfinished stages, as
+// Interrupted. The pipeline is marked Interrupted too when the run is its
+// latest.
+func InterruptRun(dbRepo ports.PipelineRepository, run *models.PipelineRun, reason string) error {
+	log.Printf("Marking run %s of pipeline %s as Interrupted: %s", run.RunID, run.PipelineID, reason)
+
+	stageRuns, err := dbRepo.GetStageRuns(run.RunID)
+	if err != nil {
+		return err
+	}
+	now := time.Now()
+	for _, stageRun := range stageRuns {
+		if isStageRunFinished(stageRun.Status) {
+			continue
+		}
+		if err := dbRepo.UpdateStageRun(run.RunID, stageRun.StageID, map[string]interface{}{
+			"status":      "Interrupted",
+			"error_msg":   reason,
+			"finished_at": now,
+		}); err != nil {
+			return err
+		}
+		if err := dbRepo.UpdateStageError(stageRun.StageID, "Interrupted", reason); err != nil {
+			log.Printf("Failed to update stage %s to Interrupted: %v", stageRun.StageName, err)
+		}
+	}
+
+	if err := dbRepo.UpdatePipelineRun(run.RunID, map[string]interface{}{
+		"status":      "Interrupted",
+		"error_msg":   reason,
+		"finished_at": now,
+	}); err != nil {
+		return err
+	}
+	if pipeline, err := dbRepo.GetPipelineByID(run.PipelineID); err == nil {
+		reportRunStatus(dbRepo, pipeline, run.RunID, "Interrupted")
+	}
+
+	latest, err := dbRepo.GetLatestPipelineRun(run.PipelineID)
+	if err != nil {
+		return err
+	}
+	if latest.RunID != run.RunID {
+		return nil
+	}
+	return dbRepo.UpdatePipelineExecution(&models.Pipelines{
+		PipelineID: run.PipelineID,
+		Status:     "Interrupted",
+		UpdatedAt:  now,
+	})
+}
+
+// resumeStageRuns prepares the stage runs of a run that is executed again. It
+// returns the outputs of the stages that had completed, resets every other
+// stage to Pending and creates the stage runs that are missing.
+func resumeStageRuns(dbRepo ports.PipelineRepository, pipelineID uuid.UUID, runID uuid.UUID, stages []Stage) (map[uuid.UUID]interface{}, error) {
+	existing, err := dbRepo.GetStageRuns(runID)
+	if err != nil {
+		return nil, err
+	}
+	byStage := make(map[uuid.UUID]models.StageRun, len(existing))
+	for _, stageRun := range existing {
+		byStage[stageRun.StageID] = stageRun
+	}
+
+	resumed := make(map[uuid.UUID]interface{})
+	var missing []models.StageRun
+	for i, stage := range stages {
+		stageRun, ok := byStage[stage.GetID()]
+		if !ok {
+			missing = append(missing, models.StageRun{
+				StageRunID: uuid.New(),
+				RunID:      runID,
+				StageID:    stage.GetID(),
+				PipelineID: pipelineID,
+				StageName:  stage.GetName(),
+				Position:   i,
+				Status:     "Pending",
+			})
+			continue
+		}
+
+		if stageRun.Status == "Completed" {
+			var output interface{}
+			if stageRun.Output != "" {
+				if err := json.Unmarshal([]byte(stageRun.Output), &output); err != nil {
+					return nil, fmt.Errorf("invalid output stored for stage %s: %w", stage.GetName(), err)
+				}
+			}
+			resumed[stage.GetID()] = output
+			continue
+		}
+
+		if err := dbRepo.UpdateStageRun(runID, stage.GetID(), map[string]interface{}{
+			"status":      "Pending",
+			"error_msg":   "",
+			"started_at":  nil,
+			"finished_at": nil,
+		}); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := dbRepo.SaveStageRuns(missing); err != nil {
+		return nil, err
+	}
+	log.Printf("Resuming run %s of pipeline %s with %d of %d stages already completed", runID, pipelineID, len(resumed), len(stages))
+	return resumed, nil
+}
@@ -0,0 +1,141 @@
+package domain
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sarika-p9/my-pipeline-project/internal/core/ports"
+)
+
+type runContextKey struct{}
+
+type runContext struct {
+	pipelineID uuid.UUID
+	runID      uuid.UUID
+}
+
+// withRun records the run a context belongs to, so that stages can tell
+// which run they are executing for.
+func withRun(ctx context.Context, pipelineID uuid.UUID, runID uuid.UUID) context.Context {
+	return context.WithValue(ctx, runContextKey{}, runContext{pipelineID: pipelineID, runID: runID})
+}
+
+func runFromContext(ctx context.Context) runContext {
+	run, _ := ctx.Value(runContextKey{}).(runContext)
+	return run
+}
+
+// RemoteStage runs a stage of any registered type on a worker. Every attempt
+// is dispatched as a task, and the stage completes when the worker's result
+// arrives. Retries, timeouts and rollback stay with the orchestrator.
+type RemoteStage struct {
+	*BaseStage
+	StageType  string
+	Config     json.RawMessage
+	Dispatcher ports.StageDispatcher
+}
+
+func NewRemoteStage(base *BaseStage, stageType string, config json.RawMessage, dispatcher ports.StageDispatcher) *RemoteStage {
+	if stageType == "" {
+		stageType = DefaultStageType
+	}
+	return &RemoteStage{BaseStage: base, StageType: stageType, Config: config, Dispatcher: dispatcher}
+}
+
+func (s *RemoteStage) Execute(ctx context.Context, pipelineName string, input interface{}) (interface{}, error) {
+	encodedInput, err := json.Marshal(input)
+	if err != nil {
+		return nil, fmt.Errorf("invalid input for stage %s: %w", s.Name, err)
+	}
+
+	run := runFromContext(ctx)
+	task := ports.StageTask{
+		TaskID:       uuid.New(),
+		PipelineID:   run.pipelineID,
+		RunID:        run.runID,
+		StageID:      s.ID,
+		PipelineName: pipelineName,
+		StageName:    s.Name,
+		StageType:    s.StageType,
+		Config:       s.Config,
+		Input:        encodedInput,
+		TimeoutMs:    s.Timeout.Milliseconds(),
+	}
+	log.Printf("Dispatching task %s for stage: %s (%s) of pipeline: %s", task.TaskID, s.Name, s.ID, pipelineName)
+
+	result, err := s.Dispatcher.Dispatch(ctx, task)
+	if err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		return nil, NewStageError(ErrorClassNetwork, fmt.Errorf("dispatching stage %s: %w", s.Name, err))
+	}
+	return decodeStageResult(result, s.Timeout)
+}
+
+// decodeStageResult turns a worker's result back into the stage output, or
+// into an error carrying the class the worker reported.
+func decodeStageResult(result ports.StageResult, timeout time.Duration) (interface{}, error) {
+	if result.Error != "" {
+		class := result.ErrorClass
+		if class == "" {
+			class = ErrorClassUnknown
+		}
+		if result.TimedOut {
+			return nil, NewStageError(class, fmt.Errorf("%w after %s on worker", ErrStageTimedOut, timeout))
+		}
+		return nil, NewStageError(class, errors.New(result.Error))
+	}
+
+	var output interface{}
+	if len(result.Output) > 0 {
+		if err := json.Unmarshal(result.Output, &output); err != nil {
+			return nil, fmt.Errorf("invalid output from worker: %w", err)
+		}
+	}
+	return output, nil
+}
+
+// RunStageTask performs a dispatched task on a worker: it builds the task's
+// stage type from the registry, runs one attempt under the task's timeout and
+// reports the output or the error and its class.
+func RunStageTask(ctx context.Context, registry *StageRegistry, task ports.StageTask) ports.StageResult {
+	result := ports.StageResult{TaskID: task.TaskID}
+	fail := func(err error) ports.StageResult {
+		result.Error = err.Error()
+		result.ErrorClass = ErrorClassOf(err)
+		result.TimedOut = errors.Is(err, ErrStageTimedOut)
+		return result
+	}
+
+	base := NewBaseStageWithID(task.StageID, task.StageName)
+	base.Timeout = time.Duration(task.TimeoutMs) * time.Millisecond
+	stage, err := registry.Build(task.StageType, base, task.Config)
+	if err != nil {
+		return fail(err)
+	}
+
+	var input interface{}
+	if len(task.Input) > 0 {
+		if err := json.Unmarshal(task.Input, &input); err != nil {
+			return fail(fmt.Errorf("invalid input for stage %s: %w", task.StageName, err))
+		}
+	}
+
+	output, err := executeAttempt(withRun(ctx, task.PipelineID, task.RunID), stage, task.PipelineName, input)
+	if err != nil {
+		return fail(err)
+	}
+
+	encoded, err := json.Marshal(output)
+	if err != nil {
+		return fail(fmt.Errorf("invalid output from stage %s: %w", task.StageName, err))
+	}
+	result.Output = encoded
+	return result
+}
@@ -0,0 +1,290 @@
+package domain
+
+import (
+	"context"
+	"fmt"
+	"sort"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sarika-p9/my-pipeline-project/internal/core/ports"
+	"github.com/sarika-p9/my-pipeline-project/internal/models"
+)
+
+// memoryRepository is a PipelineRepository keeping pipelines, runs and their
+// stages in memory, for exercising the orchestrators without a database.
+type memoryRepository struct {
+	mu          sync.Mutex
+	users       map[uuid.UUID]*models.User
+	pipelines   map[uuid.UUID]*models.Pipelines
+	stageStatus map[uuid.UUID]string
+	runs        map[uuid.UUID]*models.PipelineRun
+	stageRuns   map[uuid.UUID][]models.StageRun // By run ID
+	attempts    []models.StageAttempts
+}
+
+var _ ports.PipelineRepository = (*memoryRepository)(nil)
+
+func newMemoryRepository() *memoryRepository {
+	return &memoryRepository{
+		users:       make(map[uuid.UUID]*models.User),
+		pipelines:   make(map[uuid.UUID]*models.Pipelines),
+		stageStatus: make(map[uuid.UUID]string),
+		runs:        make(map[uuid.UUID]*models.PipelineRun),
+		stageRuns:   make(map[uuid.UUID][]models.StageRun),
+	}
+}
+
+// addPipeline stores a pipeline of a new user with a Pending run, and returns
+// the user and run IDs.
+func (r *memoryRepository) addPipeline(pipeline models.Pipelines) (userID uuid.UUID, runID uuid.UUID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	userID, runID = uuid.New(), uuid.New()
+	r.users[userID] = &models.User{UserID: userID, Role: "worker"}
+	pipeline.UserID = userID
+	r.pipelines[pipeline.PipelineID] = &pipeline
+	r.runs[runID] = &models.PipelineRun{RunID: runID, PipelineID: pipeline.PipelineID, UserID: userID, Status: "Pending", CreatedAt: time.Now()}
+	return userID, runID
+}
+
+// stageRun returns the run of the stage with the given name.
+func (r *memoryRepository) stageRun(runID uuid.UUID, name string) models.StageRun {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, stageRun := range r.stageRuns[runID] {
+		if stageRun.StageName == name {
+			return stageRun
+		}
+	}
+	return models.StageRun{}
+}
+
+func (r *memoryRepository) run(runID uuid.UUID) models.PipelineRun {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return *r.runs[runID]
+}
+
+func (r *memoryRepository) SavePipelineExecution(execution *models.Pipelines, stages []models.Stages, deps []models.StageDependencies) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	saved := *execution
+	r.pipelines[execution.PipelineID] = &saved
+	return nil
+}
+
+func (r *memoryRepository) UpdatePipelineExecution(execution *models.Pipelines) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if pipeline, ok := r.pipelines[execution.PipelineID]; ok {
+		pipeline.Status = execution.Status
+	}
+	return nil
+}
+
+func (r *memoryRepository) SaveExecutionLog(logEntry *models.Stages) error { return nil }
+
+func (r *memoryRepository) GetPipelineStatus(pipelineID string) (string, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pipeline, ok := r.pipelines[uuid.MustParse(pipelineID)]
+	if !ok {
+		return "", fmt.Errorf("%w: %s", ports.ErrPipelineNotFound, pipelineID)
+	}
+	return pipeline.Status, nil
+}
+
+func (r *memoryRepository) GetUserByID(userID uuid.UUID) (*models.User, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return r.users[userID], nil
+}
+
+func (r *memoryRepository) SaveUser(user *models.User) error { return nil }
+
+func (r *memoryRepository) UpdateUser(userID uuid.UUID, updates map[string]interface{}) error {
+	return nil
+}
+
+func (r *memoryRepository) GetPipelinesByUser(userID string) ([]models.Pipelines, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) GetPipelinesPage(userID uuid.UUID, offset, limit int) ([]models.Pipelines, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) GetPipelineStages(pipelineID uuid.UUID) ([]models.Stages, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) DeletePipeline(ctx context.Context, pipelineID string) error { return nil }
+
+func (r *memoryRepository) GetPipelineByID(pipelineID uuid.UUID) (*models.Pipelines, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pipeline, ok := r.pipelines[pipelineID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ports.ErrPipelineNotFound, pipelineID)
+	}
+	found := *pipeline
+	return &found, nil
+}
+
+func (r *memoryRepository) UpdateStageStatus(stageID uuid.UUID, status string) error {
+	return r.UpdateStageError(stageID, status, "")
+}
+
+func (r *memoryRepository) UpdateStageError(stageID uuid.UUID, status string, errorMsg string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.stageStatus[stageID] = status
+	return nil
+}
+
+func (r *memoryRepository) GetStageDependencies(pipelineID uuid.UUID) ([]models.StageDependencies, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) SaveStageAttempt(attempt *models.StageAttempts) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.attempts = append(r.attempts, *attempt)
+	return nil
+}
+
+func (r *memoryRepository) UpdateStageOutput(stageID uuid.UUID, output string) error { return nil }
+
+func (r *memoryRepository) UpdatePipelineOutput(pipelineID uuid.UUID, output string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	if pipeline, ok := r.pipelines[pipelineID]; ok {
+		pipeline.Output = output
+	}
+	return nil
+}
+
+func (r *memoryRepository) SavePipelineRun(run *models.PipelineRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	saved := *run
+	r.runs[run.RunID] = &saved
+	return nil
+}
+
+// UpdatePipelineRun keeps a Cancelled status, like the database adapter.
+func (r *memoryRepository) UpdatePipelineRun(runID uuid.UUID, updates map[string]interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	run, ok := r.runs[runID]
+	if !ok {
+		return nil
+	}
+	if status, ok := updates["status"].(string); ok {
+		if run.Status == "Cancelled" && status != "Cancelled" {
+			return fmt.Errorf("%w: %s", ports.ErrRunAlreadyCancelled, runID)
+		}
+		run.Status = status
+	}
+	if errorMsg, ok := updates["error_msg"].(string); ok {
+		run.ErrorMsg = errorMsg
+	}
+	if output, ok := updates["output"].(string); ok {
+		run.Output = output
+	}
+	return nil
+}
+
+func (r *memoryRepository) GetPipelineRun(runID uuid.UUID) (*models.PipelineRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	run, ok := r.runs[runID]
+	if !ok {
+		return nil, fmt.Errorf("%w: %s", ports.ErrRunNotFound, runID)
+	}
+	found := *run
+	return &found, nil
+}
+
+func (r *memoryRepository) GetLatestPipelineRun(pipelineID uuid.UUID) (*models.PipelineRun, error) {
+	runs, _ := r.GetPipelineRuns(pipelineID)
+	if len(runs) == 0 {
+		return nil, fmt.Errorf("%w: pipeline %s has no runs", ports.ErrRunNotFound, pipelineID)
+	}
+	return &runs[0], nil
+}
+
+// GetPipelineRuns returns the pipeline's runs, newest first.
+func (r *memoryRepository) GetPipelineRuns(pipelineID uuid.UUID) ([]models.PipelineRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	var runs []models.PipelineRun
+	for _, run := range r.runs {
+		if run.PipelineID == pipelineID {
+			runs = append(runs, *run)
+		}
+	}
+	sort.Slice(runs, func(i, j int) bool { return runs[i].CreatedAt.After(runs[j].CreatedAt) })
+	return runs, nil
+}
+
+func (r *memoryRepository) ClaimExpiredRuns(owner string, leaseUntil time.Time, statuses ...string) ([]models.PipelineRun, error) {
+	return nil, nil
+}
+
+func (r *memoryRepository) RenewRunLeases(owner string, leaseUntil time.Time, statuses ...string) error {
+	return nil
+}
+
+func (r *memoryRepository) SaveStageRuns(stageRuns []models.StageRun) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	for _, stageRun := range stageRuns {
+		r.stageRuns[stageRun.RunID] = append(r.stageRuns[stageRun.RunID], stageRun)
+	}
+	return nil
+}
+
+func (r *memoryRepository) UpdateStageRun(runID uuid.UUID, stageID uuid.UUID, updates map[string]interface{}) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	stageRuns := r.stageRuns[runID]
+	for i := range stageRuns {
+		if stageRuns[i].StageID != stageID {
+			continue
+		}
+		if status, ok := updates["status"].(string); ok {
+			stageRuns[i].Status = status
+		}
+		if errorMsg, ok := updates["error_msg"].(string); ok {
+			stageRuns[i].ErrorMsg = errorMsg
+		}
+		if output, ok := updates["output"].(string); ok {
+			stageRuns[i].Output = output
+		}
+		if finishedAt, ok := updates["finished_at"].(time.Time); ok {
+			stageRuns[i].FinishedAt = &finishedAt
+		}
+	}
+	return nil
+}
+
+func (r *memoryRepository) GetStageRuns(runID uuid.UUID) ([]models.StageRun, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return append([]models.StageRun(nil), r.stageRuns[runID]...), nil
+}
+
+func (r *memoryRepository) NextEventSequence(pipelineID uuid.UUID) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	pipeline, ok := r.pipelines[pipelineID]
+	if !ok {
+		return 0, fmt.Errorf("%w: %s", ports.ErrPipelineNotFound, pipelineID)
+	}
+	pipeline.EventSequence++
+	return pipeline.EventSequence, nil
+}
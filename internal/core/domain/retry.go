@@ -0,0 +1,139 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"math"
+	"math/rand"
+	"net"
+	"time"
+)
+
+// Error classes a retry policy can opt into.
+const (
+	ErrorClassTransient = "transient"
+	ErrorClassTimeout   = "timeout"
+	ErrorClassNetwork   = "network"
+	ErrorClassUnknown   = "unknown"
+)
+
+var ErrInvalidRetryPolicy = errors.New("invalid retry policy")
+
+// StageError tags a stage failure with an error class so retry policies can
+// tell transient failures from permanent ones.
+type StageError struct {
+	Class string
+	Err   error
+}
+
+func NewStageError(class string, err error) *StageError {
+	return &StageError{Class: class, Err: err}
+}
+
+func (e *StageError) Error() string { return e.Err.Error() }
+func (e *StageError) Unwrap() error { return e.Err }
+
+// ErrorClassOf classifies a stage failure.
+func ErrorClassOf(err error) string {
+	var stageErr *StageError
+	if errors.As(err, &stageErr) {
+		return stageErr.Class
+	}
+	if errors.Is(err, context.DeadlineExceeded) {
+		return ErrorClassTimeout
+	}
+	var netErr net.Error
+	if errors.As(err, &netErr) {
+		if netErr.Timeout() {
+			return ErrorClassTimeout
+		}
+		return ErrorClassNetwork
+	}
+	return ErrorClassUnknown
+}
+
+// RetryPolicy controls how often a failed stage is attempted again and how
+// long to wait in between. The zero value runs a stage exactly once.
+type RetryPolicy struct {
+	MaxAttempts      int      `json:"max_attempts"`
+	InitialBackoffMs int64    `json:"initial_backoff_ms"`
+	Multiplier       float64  `json:"multiplier"`     // Defaults to 2
+	MaxBackoffMs     int64    `json:"max_backoff_ms"` // Zero means no cap
+	Jitter           float64  `json:"jitter"`         // Fraction of the backoff, 0 to 1
+	RetryableErrors  []string `json:"retryable_errors"`
+}
+
+// Validate rejects settings the orchestrator cannot honour.
+func (p RetryPolicy) Validate() error {
+	switch {
+	case p.MaxAttempts < 0:
+		return fmt.Errorf("%w: max_attempts must not be negative", ErrInvalidRetryPolicy)
+	case p.InitialBackoffMs < 0 || p.MaxBackoffMs < 0:
+		return fmt.Errorf("%w: backoff must not be negative", ErrInvalidRetryPolicy)
+	case p.Multiplier != 0 && p.Multiplier < 1:
+		return fmt.Errorf("%w: multiplier must be at least 1", ErrInvalidRetryPolicy)
+	case p.Jitter < 0 || p.Jitter > 1:
+		return fmt.Errorf("%w: jitter must be between 0 and 1", ErrInvalidRetryPolicy)
+	}
+	for _, class := range p.RetryableErrors {
+		switch class {
+		case ErrorClassTransient, ErrorClassTimeout, ErrorClassNetwork, ErrorClassUnknown:
+		default:
+			return fmt.Errorf("%w: unknown error class %q", ErrInvalidRetryPolicy, class)
+		}
+	}
+	return nil
+}
+
+func (p RetryPolicy) attempts() int {
+	if p.MaxAttempts < 1 {
+		return 1
+	}
+	return p.MaxAttempts
+}
+
+// Retryable reports whether err may be retried. An empty RetryableErrors list
+// retries every class of error.
+func (p RetryPolicy) Retryable(err error) bool {
+	if len(p.RetryableErrors) == 0 {
+		return true
+	}
+	class := ErrorClassOf(err)
+	for _, retryable := range p.RetryableErrors {
+		if retryable == class {
+			return true
+		}
+	}
+	return false
+}
+
+// Backoff returns how long to wait after the given failed attempt (1-based).
+// Jitter is applied before the MaxBackoffMs cap, so no wait exceeds it.
+func (p RetryPolicy) Backoff(attempt int) time.Duration {
+	multiplier := p.Multiplier
+	if multiplier == 0 {
+		multiplier = 2
+	}
+
+	backoff := float64(p.InitialBackoffMs) * math.Pow(multiplier, float64(attempt-1))
+	if p.Jitter > 0 {
+		backoff *= 1 + p.Jitter*(2*rand.Float64()-1)
+	}
+	if p.MaxBackoffMs > 0 && backoff > float64(p.MaxBackoffMs) {
+		backoff = float64(p.MaxBackoffMs)
+	}
+	return time.Duration(backoff) * time.Millisecond
+}
+
+// RetryableStage is implemented by stages that carry their own retry policy.
+type RetryableStage interface {
+	GetRetryPolicy() RetryPolicy
+}
+
+func retryPolicyOf(stage Stage) RetryPolicy {
+	if retryable, ok := stage.(RetryableStage); ok {
+		return retryable.GetRetryPolicy()
+	}
+	return RetryPolicy{}
+}
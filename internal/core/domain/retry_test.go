@@ -0,0 +1,38 @@
+package domain
+
+import (
+	"testing"
+	"time"
+)
+
+func TestRetryPolicyBackoffStaysUnderCapWithJitter(t *testing.T) {
+	policy := RetryPolicy{InitialBackoffMs: 100, Multiplier: 2, MaxBackoffMs: 1000, Jitter: 0.5}
+	for attempt := 1; attempt <= 10; attempt++ {
+		for i := 0; i < 100; i++ {
+			if backoff := policy.Backoff(attempt); backoff > time.Second {
+				t.Fatalf("Backoff(%d) = %s, want at most %s", attempt, backoff, time.Second)
+			}
+		}
+	}
+}
+
+func TestRetryPolicyBackoff(t *testing.T) {
+	tests := []struct {
+		name    string
+		policy  RetryPolicy
+		attempt int
+		want    time.Duration
+	}{
+		{"first attempt", RetryPolicy{InitialBackoffMs: 100}, 1, 100 * time.Millisecond},
+		{"doubles by default", RetryPolicy{InitialBackoffMs: 100}, 3, 400 * time.Millisecond},
+		{"multiplier", RetryPolicy{InitialBackoffMs: 100, Multiplier: 3}, 3, 900 * time.Millisecond},
+		{"capped", RetryPolicy{InitialBackoffMs: 100, MaxBackoffMs: 250}, 3, 250 * time.Millisecond},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.policy.Backoff(tt.attempt); got != tt.want {
+				t.Errorf("Backoff(%d) = %s, want %s", tt.attempt, got, tt.want)
+			}
+		})
+	}
+}
@@ -0,0 +1,177 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"os"
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+var (
+	ErrRunInProgress = errors.New("pipeline is already running")
+	ErrRunNotActive  = errors.New("pipeline has no active run")
+	ErrRunFinishing  = errors.New("pipeline run is already finishing")
+	ErrRunCancelled  = errors.New("pipeline run was cancelled before it began")
+	ErrRunFinished   = errors.New("pipeline run has already finished")
+)
+
+type activeRun struct {
+	runID     uuid.UUID
+	cancel    context.CancelFunc // Nil while the run is only reserved
+	cancelled bool
+	finished  bool
+}
+
+// RunRegistry tracks the active run of every pipeline so a cancel request can
+// reach the goroutines executing its stages. Finish and Cancel are decided
+// under one lock, so a run ends either Cancelled or with its own outcome,
+// never both.
+type RunRegistry struct {
+	// Owner identifies this process on the runs it leases.
+	Owner string
+
+	mu   sync.Mutex
+	runs map[uuid.UUID]*activeRun
+}
+
+func NewRunRegistry() *RunRegistry {
+	return &RunRegistry{Owner: newOwnerID(), runs: make(map[uuid.UUID]*activeRun)}
+}
+
+// newOwnerID names this process after its host, which is the pod name on
+// Kubernetes, made unique across restarts.
+func newOwnerID() string {
+	hostname, err := os.Hostname()
+	if err != nil {
+		hostname = "backend"
+	}
+	return hostname + "-" + uuid.NewString()[:8]
+}
+
+// Reserve claims the pipeline for a run that is about to be saved and
+// executed in the background. It is the check that the pipeline is not
+// already running, so of two concurrent starts only one succeeds, and a
+// cancel that arrives before the run executes is kept for Start.
+func (r *RunRegistry) Reserve(pipelineID uuid.UUID, runID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, exists := r.runs[pipelineID]; exists {
+		return ErrRunInProgress
+	}
+	r.runs[pipelineID] = &activeRun{runID: runID}
+	return nil
+}
+
+// Start registers a run for the pipeline, or takes over its reservation, and
+// returns the context its stages must execute under. A reserved run that was
+// cancelled meanwhile is forgotten and ErrRunCancelled is returned.
+func (r *RunRegistry) Start(ctx context.Context, pipelineID uuid.UUID, runID uuid.UUID) (context.Context, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	run, exists := r.runs[pipelineID]
+	switch {
+	case !exists:
+		run = &activeRun{runID: runID}
+		r.runs[pipelineID] = run
+	case run.runID != runID || run.cancel != nil:
+		return nil, ErrRunInProgress
+	case run.cancelled:
+		delete(r.runs, pipelineID)
+		return nil, ErrRunCancelled
+	}
+
+	runCtx, cancel := context.WithCancel(ctx)
+	run.cancel = cancel
+	return runCtx, nil
+}
+
+// Cancel stops the active run of the pipeline. It returns ErrRunNotActive if
+// nothing is running and ErrRunFinishing if the run has already settled on
+// its final status.
+func (r *RunRegistry) Cancel(pipelineID uuid.UUID) error {
+	return r.CancelRun(pipelineID, uuid.Nil)
+}
+
+// CancelRun is Cancel for the given run only; it returns ErrRunNotActive when
+// another run of the pipeline is active. uuid.Nil matches any run.
+func (r *RunRegistry) CancelRun(pipelineID uuid.UUID, runID uuid.UUID) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	run, exists := r.runs[pipelineID]
+	if !exists || (runID != uuid.Nil && run.runID != runID) {
+		return ErrRunNotActive
+	}
+	if run.finished {
+		return ErrRunFinishing
+	}
+
+	run.cancelled = true
+	if run.cancel != nil {
+		run.cancel()
+	}
+	return nil
+}
+
+// Finish marks the run as settled and reports whether it was cancelled first.
+func (r *RunRegistry) Finish(pipelineID uuid.UUID) (cancelled bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	run, exists := r.runs[pipelineID]
+	if !exists {
+		return false
+	}
+	run.finished = true
+	return run.cancelled
+}
+
+// Remove forgets the run once its final status has been written.
+func (r *RunRegistry) Remove(pipelineID uuid.UUID) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if run, exists := r.runs[pipelineID]; exists {
+		if run.cancel != nil {
+			run.cancel()
+		}
+		delete(r.runs, pipelineID)
+	}
+}
+
+// ActiveRun returns the ID of the pipeline's active run, if any.
+func (r *RunRegistry) ActiveRun(pipelineID uuid.UUID) (uuid.UUID, bool) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	run, exists := r.runs[pipelineID]
+	if !exists {
+		return uuid.Nil, false
+	}
+	return run.runID, true
+}
+
+// ActiveRuns returns the ID of the active run of every pipeline, by pipeline
+// ID.
+func (r *RunRegistry) ActiveRuns() map[uuid.UUID]uuid.UUID {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	active := make(map[uuid.UUID]uuid.UUID, len(r.runs))
+	for pipelineID, run := range r.runs {
+		active[pipelineID] = run.runID
+	}
+	return active
+}
+
+func (r *RunRegistry) IsRunning(pipelineID uuid.UUID) bool {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	_, exists := r.runs[pipelineID]
+	return exists
+}
@@ -0,0 +1,124 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+
+	"github.com/google/uuid"
+)
+
+func TestRunRegistryReserveAllowsOneRun(t *testing.T) {
+	runs := NewRunRegistry()
+	pipelineID := uuid.New()
+
+	var wg sync.WaitGroup
+	var mu sync.Mutex
+	reserved := 0
+	for i := 0; i < 20; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			err := runs.Reserve(pipelineID, uuid.New())
+			if err != nil && !errors.Is(err, ErrRunInProgress) {
+				t.Errorf("Reserve returned %v", err)
+			}
+			if err == nil {
+				mu.Lock()
+				reserved++
+				mu.Unlock()
+			}
+		}()
+	}
+	wg.Wait()
+
+	if reserved != 1 {
+		t.Errorf("%d concurrent reservations succeeded, want 1", reserved)
+	}
+}
+
+func TestRunRegistryStartTakesOverReservation(t *testing.T) {
+	runs := NewRunRegistry()
+	pipelineID, runID := uuid.New(), uuid.New()
+
+	if err := runs.Reserve(pipelineID, runID); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	if _, err := runs.Start(context.Background(), pipelineID, uuid.New()); !errors.Is(err, ErrRunInProgress) {
+		t.Errorf("Start of another run returned %v, want %v", err, ErrRunInProgress)
+	}
+
+	runCtx, err := runs.Start(context.Background(), pipelineID, runID)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if _, err := runs.Start(context.Background(), pipelineID, runID); !errors.Is(err, ErrRunInProgress) {
+		t.Errorf("second Start returned %v, want %v", err, ErrRunInProgress)
+	}
+
+	if err := runs.Cancel(pipelineID); err != nil {
+		t.Fatalf("Cancel: %v", err)
+	}
+	if runCtx.Err() == nil {
+		t.Error("run context not cancelled")
+	}
+	if !runs.Finish(pipelineID) {
+		t.Error("Finish did not report the cancel")
+	}
+	runs.Remove(pipelineID)
+	if runs.IsRunning(pipelineID) {
+		t.Error("pipeline still running after Remove")
+	}
+}
+
+func TestRunRegistryKeepsCancelOfReservedRun(t *testing.T) {
+	runs := NewRunRegistry()
+	pipelineID, runID := uuid.New(), uuid.New()
+
+	if err := runs.Reserve(pipelineID, runID); err != nil {
+		t.Fatalf("Reserve: %v", err)
+	}
+	if activeRunID, ok := runs.ActiveRun(pipelineID); !ok || activeRunID != runID {
+		t.Errorf("ActiveRun = %s, %t, want %s", activeRunID, ok, runID)
+	}
+	if err := runs.Cancel(pipelineID); err != nil {
+		t.Fatalf("Cancel of reserved run: %v", err)
+	}
+
+	if _, err := runs.Start(context.Background(), pipelineID, runID); !errors.Is(err, ErrRunCancelled) {
+		t.Errorf("Start returned %v, want %v", err, ErrRunCancelled)
+	}
+	if runs.IsRunning(pipelineID) {
+		t.Error("cancelled reservation still held")
+	}
+	if err := runs.Reserve(pipelineID, uuid.New()); err != nil {
+		t.Errorf("Reserve after the cancelled run: %v", err)
+	}
+}
+
+func TestRunRegistryCancelRunMatchesTheRun(t *testing.T) {
+	runs := NewRunRegistry()
+	pipelineID, runID := uuid.New(), uuid.New()
+
+	runCtx, err := runs.Start(context.Background(), pipelineID, runID)
+	if err != nil {
+		t.Fatalf("Start: %v", err)
+	}
+	if active := runs.ActiveRuns(); len(active) != 1 || active[pipelineID] != runID {
+		t.Errorf("ActiveRuns = %v, want %s for %s", active, runID, pipelineID)
+	}
+
+	if err := runs.CancelRun(pipelineID, uuid.New()); !errors.Is(err, ErrRunNotActive) {
+		t.Errorf("CancelRun of another run returned %v, want %v", err, ErrRunNotActive)
+	}
+	if runCtx.Err() != nil {
+		t.Fatal("run cancelled by CancelRun of another run")
+	}
+	if err := runs.CancelRun(pipelineID, runID); err != nil {
+		t.Fatalf("CancelRun: %v", err)
+	}
+	if runCtx.Err() == nil {
+		t.Error("run context not cancelled")
+	}
+}
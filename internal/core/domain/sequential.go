@@ -0,0 +1,81 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"sync"
+
+	"github.com/google/uuid"
+	"github.com/sarika-p9/my-pipeline-project/internal/core/ports"
+)
+
+type SequentialPipelineOrchestrator struct {
+	PipelineID uuid.UUID
+	UserID     uuid.UUID
+	Stages     []Stage
+	graph      stageGraph
+	mu         sync.Mutex
+	dbRepo     ports.PipelineRepository
+	runs       *RunRegistry
+}
+
+func NewSequentialPipelineOrchestrator(pipelineID uuid.UUID, dbRepo ports.PipelineRepository, runs *RunRegistry) *SequentialPipelineOrchestrator {
+	return &SequentialPipelineOrchestrator{
+		PipelineID: pipelineID,
+		dbRepo:     dbRepo,
+		runs:       runs,
+		Stages:     []Stage{},
+		graph:      newStageGraph(),
+	}
+}
+
+func (s *SequentialPipelineOrchestrator) AddStage(stage Stage) error {
+	if stage == nil {
+		return errors.New("stage cannot be nil")
+	}
+	s.mu.Lock()
+	s.Stages = append(s.Stages, stage)
+	s.mu.Unlock()
+	return nil
+}
+
+func (s *SequentialPipelineOrchestrator) AddStageDependency(stageID uuid.UUID, dependsOn uuid.UUID) error {
+	if stageID == dependsOn {
+		return errors.New("stage cannot depend on itself")
+	}
+	s.mu.Lock()
+	s.graph.addDependency(stageID, dependsOn)
+	s.mu.Unlock()
+	return nil
+}
+
+// Execute runs the stages one at a time in dependency order, keeping the
+// order they were added among independent stages. Each stage receives the
+// output of the one before it.
+func (s *SequentialPipelineOrchestrator) Execute(ctx context.Context, userID uuid.UUID, pipelineID uuid.UUID, runID uuid.UUID, input interface{}) (uuid.UUID, interface{}, error) {
+	s.mu.Lock()
+	stages := append([]Stage(nil), s.Stages...)
+	graph := s.graph.clone()
+	s.mu.Unlock()
+
+	output, err := execute(ctx, s.runs, s.dbRepo, userID, pipelineID, runID, stages, graph, input, 1, false)
+	return runID, output, err
+}
+
+func (s *SequentialPipelineOrchestrator) Resume(ctx context.Context, userID uuid.UUID, pipelineID uuid.UUID, runID uuid.UUID, input interface{}) (uuid.UUID, interface{}, error) {
+	s.mu.Lock()
+	stages := append([]Stage(nil), s.Stages...)
+	graph := s.graph.clone()
+	s.mu.Unlock()
+
+	output, err := execute(ctx, s.runs, s.dbRepo, userID, pipelineID, runID, stages, graph, input, 1, true)
+	return runID, output, err
+}
+
+func (s *SequentialPipelineOrchestrator) GetStatus(pipelineID uuid.UUID, runID uuid.UUID) (string, error) {
+	return GetPipelineStatus(s.dbRepo, pipelineID, runID)
+}
+
+func (s *SequentialPipelineOrchestrator) Cancel(pipelineID uuid.UUID, runID uuid.UUID, userID uuid.UUID) error {
+	return cancelPipeline(s.runs, s.dbRepo, pipelineID, runID, userID)
+}
@@ -7,7 +7,6 @@ import (
 	"time"
 
 	"github.com/google/uuid"
-	"github.com/sarika-p9/my-pipeline-project/internal/infrastructure"
 )
 
 type Stage interface {
@@ -15,7 +14,9 @@ type Stage interface {
 	GetName() string
 	Execute(ctx context.Context, pipelineName string, input interface{}) (interface{}, error)
 	HandleError(ctx context.Context, err error) error
-	Rollback(ctx context.Context, input interface{}) error
+	// Rollback compensates a completed stage, given the input it ran with
+	// and the output it produced.
+	Rollback(ctx context.Context, input interface{}, output interface{}) error
 }
 
 type StageStatus struct {
@@ -25,13 +26,21 @@ type StageStatus struct {
 }
 
 type BaseStage struct {
-	ID     uuid.UUID
-	Name   string
-	Status string
+	ID      uuid.UUID
+	Name    string
+	Status  string
+	Retry   RetryPolicy
+	Timeout time.Duration
 }
 
 func NewBaseStage(name string) *BaseStage {
-	return &BaseStage{ID: uuid.New(), Name: name, Status: "Pending"}
+	return NewBaseStageWithID(uuid.New(), name)
+}
+
+// NewBaseStageWithID builds a stage bound to an existing stage row so that
+// status updates land on the persisted record.
+func NewBaseStageWithID(id uuid.UUID, name string) *BaseStage {
+	return &BaseStage{ID: id, Name: name, Status: "Pending"}
 }
 
 func (s *BaseStage) GetID() uuid.UUID {
@@ -42,20 +51,25 @@ func (s *BaseStage) GetName() string {
 	return s.Name
 }
 
-func (s *BaseStage) Execute(ctx context.Context, pipelineName string, input interface{}) (interface{}, error) {
-	log.Printf("Executing stage: %s (%s) for pipeline: %s", s.Name, s.ID, pipelineName)
+func (s *BaseStage) GetRetryPolicy() RetryPolicy {
+	return s.Retry
+}
 
-	if s.Status != "Running" {
-		s.Status = "Running"
-		infrastructure.WebSocket.SendMessage(pipelineName, s.Name, "Running")
-	}
+func (s *BaseStage) GetTimeout() time.Duration {
+	return s.Timeout
+}
 
-	time.Sleep(4 * time.Second)
+func (s *BaseStage) Execute(ctx context.Context, pipelineName string, input interface{}) (interface{}, error) {
+	log.Printf("Executing stage: %s (%s) for pipeline: %s", s.Name, s.ID, pipelineName)
 
-	if s.Status != "Completed" {
-		s.Status = "Completed"
-		infrastructure.WebSocket.SendMessage(pipelineName, s.Name, "Completed")
+	s.Status = "Running"
+	select {
+	case <-time.After(4 * time.Second):
+	case <-ctx.Done():
+		s.Status = "Cancelled"
+		return nil, ctx.Err()
 	}
+	s.Status = "Completed"
 
 	return input, nil
 }
@@ -65,7 +79,7 @@ func (s *BaseStage) HandleError(ctx context.Context, err error) error {
 	return errors.New("stage execution failed: " + err.Error())
 }
 
-func (s *BaseStage) Rollback(ctx context.Context, input interface{}) error {
-	log.Printf("Rolling back stage %s (%s) due to failure. Input: %v", s.Name, s.ID, input)
+func (s *BaseStage) Rollback(ctx context.Context, input interface{}, output interface{}) error {
+	log.Printf("Rolling back stage %s (%s) due to failure. Input: %v, output: %v", s.Name, s.ID, input, output)
 	return nil
 }
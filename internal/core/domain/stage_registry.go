@@ -0,0 +1,105 @@
+package domain
+
+import (
+	"encoding/json"
+	"errors"
+	"fmt"
+	"sort"
+	"sync"
+)
+
+var (
+	ErrUnknownStageType   = errors.New("unknown stage type")
+	ErrInvalidStageConfig = errors.New("invalid stage config")
+)
+
+// DefaultStageType is used for stages created without a type. It keeps the
+// original behaviour of sleeping for a few seconds and passing the input on.
+const DefaultStageType = "sleep"
+
+// StageFactory builds a stage of one type around the stage's identity, retry
+// policy and timeout, configured from the JSON stored with the stage.
+type StageFactory func(base *BaseStage, config json.RawMessage) (Stage, error)
+
+// StageRegistry maps stage type names to the factories that build them.
+type StageRegistry struct {
+	mu        sync.RWMutex
+	factories map[string]StageFactory
+}
+
+// NewStageRegistry returns a registry holding the built-in stage types. The
+// http and shell types reach outside the pipeline, calling any URL or running
+// commands on the server or worker, so their output is capped.
+func NewStageRegistry() *StageRegistry {
+	r := &StageRegistry{factories: make(map[string]StageFactory)}
+	r.Register("sleep", NewSleepStage)
+	r.Register("fail", NewFailStage)
+	r.Register("http", NewHTTPStage)
+	r.Register("shell", NewShellStage)
+	r.Register("transform", NewTransformStage)
+	return r
+}
+
+// Register adds or replaces the factory for a stage type.
+func (r *StageRegistry) Register(stageType string, factory StageFactory) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	r.factories[stageType] = factory
+}
+
+// Types lists the registered stage types in alphabetical order.
+func (r *StageRegistry) Types() []string {
+	r.mu.RLock()
+	defer r.mu.RUnlock()
+
+	types := make([]string, 0, len(r.factories))
+	for stageType := range r.factories {
+		types = append(types, stageType)
+	}
+	sort.Strings(types)
+	return types
+}
+
+// Build returns the stage for the given type and config. An empty type
+// means DefaultStageType.
+func (r *StageRegistry) Build(stageType string, base *BaseStage, config json.RawMessage) (Stage, error) {
+	if stageType == "" {
+		stageType = DefaultStageType
+	}
+
+	r.mu.RLock()
+	factory, ok := r.factories[stageType]
+	r.mu.RUnlock()
+	if !ok {
+		return nil, fmt.Errorf("stage %q: %w %q, expected one of %v", base.Name, ErrUnknownStageType, stageType, r.Types())
+	}
+
+	stage, err := factory(base, config)
+	if err != nil {
+		return nil, fmt.Errorf("stage %q: %w", base.Name, err)
+	}
+	return stage, nil
+}
+
+// ValidateStageTypes checks that every definition names a registered type
+// with a usable config.
+func (r *StageRegistry) ValidateStageTypes(defs []StageDefinition) error {
+	for _, def := range defs {
+		if _, err := r.Build(def.Type, NewBaseStage(def.Name), def.Config); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+// decodeStageConfig fills config from raw, leaving defaults in place when no
+// config was stored.
+func decodeStageConfig(raw json.RawMessage, config interface{}) error {
+	if len(raw) == 0 || string(raw) == "null" {
+		return nil
+	}
+	if err := json.Unmarshal(raw, config); err != nil {
+		return fmt.Errorf("%w: %v", ErrInvalidStageConfig, err)
+	}
+	return nil
+}
@@ -0,0 +1,311 @@
+package domain
+
+import (
+	"bytes"
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"io"
+	"log"
+	"math/rand"
+	"net/http"
+	"os"
+	"os/exec"
+	"sort"
+	"strings"
+	"time"
+)
+
+// MaxStageOutputBytes caps the response body an http stage reads and the
+// stdout and stderr a shell stage keeps.
+const MaxStageOutputBytes = 1 << 20
+
+// SleepConfig configures the "sleep" stage type.
+type SleepConfig struct {
+	DurationMs int64 `json:"duration_ms"` // Defaults to 4000
+}
+
+// SleepStage waits for a fixed duration and passes its input on.
+type SleepStage struct {
+	*BaseStage
+	Config SleepConfig
+}
+
+func NewSleepStage(base *BaseStage, raw json.RawMessage) (Stage, error) {
+	config := SleepConfig{DurationMs: 4000}
+	if err := decodeStageConfig(raw, &config); err != nil {
+		return nil, err
+	}
+	if config.DurationMs < 0 {
+		return nil, fmt.Errorf("%w: duration_ms must not be negative", ErrInvalidStageConfig)
+	}
+	return &SleepStage{BaseStage: base, Config: config}, nil
+}
+
+func (s *SleepStage) Execute(ctx context.Context, pipelineName string, input interface{}) (interface{}, error) {
+	log.Printf("Sleeping in stage: %s (%s) for pipeline: %s", s.Name, s.ID, pipelineName)
+
+	select {
+	case <-time.After(time.Duration(s.Config.DurationMs) * time.Millisecond):
+		return input, nil
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	}
+}
+
+// FailConfig configures the "fail" stage type.
+type FailConfig struct {
+	Probability float64 `json:"probability"` // Chance of failing each attempt, 0 to 1; defaults to 1
+	Message     string  `json:"message"`
+	ErrorClass  string  `json:"error_class"` // Class reported to retry policies; defaults to unknown
+}
+
+// FailStage fails with the configured probability, which makes it useful for
+// exercising retry and failure policies. It passes its input on otherwise.
+type FailStage struct {
+	*BaseStage
+	Config FailConfig
+}
+
+func NewFailStage(base *BaseStage, raw json.RawMessage) (Stage, error) {
+	config := FailConfig{Probability: 1, Message: "simulated failure", ErrorClass: ErrorClassUnknown}
+	if err := decodeStageConfig(raw, &config); err != nil {
+		return nil, err
+	}
+	if config.Probability < 0 || config.Probability > 1 {
+		return nil, fmt.Errorf("%w: probability must be between 0 and 1", ErrInvalidStageConfig)
+	}
+	switch config.ErrorClass {
+	case ErrorClassTransient, ErrorClassTimeout, ErrorClassNetwork, ErrorClassUnknown:
+	default:
+		return nil, fmt.Errorf("%w: unknown error class %q", ErrInvalidStageConfig, config.ErrorClass)
+	}
+	return &FailStage{BaseStage: base, Config: config}, nil
+}
+
+func (s *FailStage) Execute(ctx context.Context, pipelineName string, input interface{}) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+	if rand.Float64() < s.Config.Probability {
+		log.Printf("Stage %s (%s) failing on purpose for pipeline: %s", s.Name, s.ID, pipelineName)
+		return nil, NewStageError(s.Config.ErrorClass, errors.New(s.Config.Message))
+	}
+	return input, nil
+}
+
+// HTTPConfig configures the "http" stage type.
+type HTTPConfig struct {
+	URL     string            `json:"url"`
+	Method  string            `json:"method"` // Defaults to GET
+	Headers map[string]string `json:"headers"`
+	Body    json.RawMessage   `json:"body"`
+}
+
+// HTTPStage calls a URL. Any 2xx response completes the stage with the status
+// code and response body; 429 and 5xx responses fail as transient errors, and
+// bodies over MaxStageOutputBytes fail the stage.
+type HTTPStage struct {
+	*BaseStage
+	Config HTTPConfig
+	Client *http.Client
+}
+
+func NewHTTPStage(base *BaseStage, raw json.RawMessage) (Stage, error) {
+	config := HTTPConfig{Method: http.MethodGet}
+	if err := decodeStageConfig(raw, &config); err != nil {
+		return nil, err
+	}
+	if config.URL == "" {
+		return nil, fmt.Errorf("%w: url is required", ErrInvalidStageConfig)
+	}
+	config.Method = strings.ToUpper(config.Method)
+	if _, err := http.NewRequest(config.Method, config.URL, nil); err != nil {
+		return nil, fmt.Errorf("%w: %v", ErrInvalidStageConfig, err)
+	}
+	return &HTTPStage{BaseStage: base, Config: config, Client: http.DefaultClient}, nil
+}
+
+func (s *HTTPStage) Execute(ctx context.Context, pipelineName string, input interface{}) (interface{}, error) {
+	log.Printf("Calling %s %s in stage: %s (%s) for pipeline: %s", s.Config.Method, s.Config.URL, s.Name, s.ID, pipelineName)
+
+	var body io.Reader
+	if len(s.Config.Body) > 0 {
+		body = bytes.NewReader(s.Config.Body)
+	}
+	req, err := http.NewRequestWithContext(ctx, s.Config.Method, s.Config.URL, body)
+	if err != nil {
+		return nil, err
+	}
+	if body != nil {
+		req.Header.Set("Content-Type", "application/json")
+	}
+	for key, value := range s.Config.Headers {
+		req.Header.Set(key, value)
+	}
+
+	resp, err := s.Client.Do(req)
+	if err != nil {
+		return nil, err
+	}
+	defer resp.Body.Close()
+
+	respBody, err := io.ReadAll(io.LimitReader(resp.Body, MaxStageOutputBytes+1))
+	if err != nil {
+		return nil, err
+	}
+	if len(respBody) > MaxStageOutputBytes {
+		return nil, fmt.Errorf("%s %s returned more than %d bytes", s.Config.Method, s.Config.URL, MaxStageOutputBytes)
+	}
+
+	if resp.StatusCode < 200 || resp.StatusCode > 299 {
+		err := fmt.Errorf("%s %s returned %s", s.Config.Method, s.Config.URL, resp.Status)
+		if resp.StatusCode == http.StatusTooManyRequests || resp.StatusCode >= 500 {
+			return nil, NewStageError(ErrorClassTransient, err)
+		}
+		return nil, err
+	}
+
+	var decoded interface{} = string(respBody)
+	var parsed interface{}
+	if json.Unmarshal(respBody, &parsed) == nil {
+		decoded = parsed
+	}
+	return map[string]interface{}{"status_code": resp.StatusCode, "body": decoded}, nil
+}
+
+// ShellConfig configures the "shell" stage type.
+type ShellConfig struct {
+	Command string            `json:"command"`
+	Args    []string          `json:"args"`
+	Env     map[string]string `json:"env"` // Added to the server's environment
+	Dir     string            `json:"dir"` // Defaults to the server's working directory
+}
+
+// ShellStage runs a local command. A non-zero exit fails the stage. Only the
+// first MaxStageOutputBytes of stdout and of stderr are kept.
+type ShellStage struct {
+	*BaseStage
+	Config ShellConfig
+}
+
+func NewShellStage(base *BaseStage, raw json.RawMessage) (Stage, error) {
+	var config ShellConfig
+	if err := decodeStageConfig(raw, &config); err != nil {
+		return nil, err
+	}
+	if config.Command == "" {
+		return nil, fmt.Errorf("%w: command is required", ErrInvalidStageConfig)
+	}
+	return &ShellStage{BaseStage: base, Config: config}, nil
+}
+
+func (s *ShellStage) Execute(ctx context.Context, pipelineName string, input interface{}) (interface{}, error) {
+	log.Printf("Running %q in stage: %s (%s) for pipeline: %s", s.Config.Command, s.Name, s.ID, pipelineName)
+
+	cmd := exec.CommandContext(ctx, s.Config.Command, s.Config.Args...)
+	cmd.Dir = s.Config.Dir
+	if len(s.Config.Env) > 0 {
+		keys := make([]string, 0, len(s.Config.Env))
+		for key := range s.Config.Env {
+			keys = append(keys, key)
+		}
+		sort.Strings(keys)
+
+		cmd.Env = os.Environ()
+		for _, key := range keys {
+			cmd.Env = append(cmd.Env, key+"="+s.Config.Env[key])
+		}
+	}
+
+	var stdout, stderr cappedBuffer
+	cmd.Stdout = &stdout
+	cmd.Stderr = &stderr
+	if err := cmd.Run(); err != nil {
+		if ctx.Err() != nil {
+			return nil, ctx.Err()
+		}
+		if output := strings.TrimSpace(stderr.String()); output != "" {
+			return nil, fmt.Errorf("command %q failed: %w: %s", s.Config.Command, err, output)
+		}
+		return nil, fmt.Errorf("command %q failed: %w", s.Config.Command, err)
+	}
+
+	return map[string]interface{}{"stdout": stdout.String(), "stderr": stderr.String()}, nil
+}
+
+// cappedBuffer keeps the first MaxStageOutputBytes written to it and
+// discards the rest, so that a chatty command can't exhaust memory.
+type cappedBuffer struct {
+	bytes.Buffer
+}
+
+func (b *cappedBuffer) Write(p []byte) (int, error) {
+	if room := MaxStageOutputBytes - b.Len(); room > 0 {
+		if len(p) > room {
+			b.Buffer.Write(p[:room])
+		} else {
+			b.Buffer.Write(p)
+		}
+	}
+	return len(p), nil
+}
+
+// TransformConfig configures the "transform" stage type. Operations apply in
+// the order rename, remove, set, and renames in alphabetical order of the keys
+// they rename.
+type TransformConfig struct {
+	Rename map[string]string      `json:"rename"`
+	Remove []string               `json:"remove"`
+	Set    map[string]interface{} `json:"set"`
+}
+
+// TransformStage reshapes a JSON object input. Any other input is treated as
+// an object holding it under the "input" key.
+type TransformStage struct {
+	*BaseStage
+	Config TransformConfig
+}
+
+func NewTransformStage(base *BaseStage, raw json.RawMessage) (Stage, error) {
+	var config TransformConfig
+	if err := decodeStageConfig(raw, &config); err != nil {
+		return nil, err
+	}
+	return &TransformStage{BaseStage: base, Config: config}, nil
+}
+
+func (s *TransformStage) Execute(ctx context.Context, pipelineName string, input interface{}) (interface{}, error) {
+	if err := ctx.Err(); err != nil {
+		return nil, err
+	}
+
+	output := make(map[string]interface{})
+	if fields, ok := input.(map[string]interface{}); ok {
+		for key, value := range fields {
+			output[key] = value
+		}
+	} else if input != nil {
+		output["input"] = input
+	}
+
+	renamed := make([]string, 0, len(s.Config.Rename))
+	for from := range s.Config.Rename {
+		renamed = append(renamed, from)
+	}
+	sort.Strings(renamed)
+	for _, from := range renamed {
+		if value, ok := output[from]; ok {
+			delete(output, from)
+			output[s.Config.Rename[from]] = value
+		}
+	}
+	for _, key := range s.Config.Remove {
+		delete(output, key)
+	}
+	for key, value := range s.Config.Set {
+		output[key] = value
+	}
+	return output, nil
+}
@@ -0,0 +1,71 @@
+package domain
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"time"
+)
+
+var (
+	ErrInvalidTimeout   = errors.New("invalid timeout")
+	ErrStageTimedOut    = errors.New("stage timed out")
+	ErrPipelineTimedOut = errors.New("pipeline timed out")
+)
+
+// TimedStage is implemented by stages that bound how long a single attempt
+// may run. A zero timeout means no limit.
+type TimedStage interface {
+	GetTimeout() time.Duration
+}
+
+func timeoutOf(stage Stage) time.Duration {
+	if timed, ok := stage.(TimedStage); ok {
+		return timed.GetTimeout()
+	}
+	return 0
+}
+
+// interruptedStatus names the status of work stopped by ctx: TimedOut when a
+// deadline passed, Cancelled otherwise.
+func interruptedStatus(ctx context.Context) string {
+	if errors.Is(ctx.Err(), context.DeadlineExceeded) {
+		return "TimedOut"
+	}
+	return "Cancelled"
+}
+
+// executeAttempt runs one attempt of the stage under its timeout. The
+// orchestrator stops waiting once the deadline passes, even if the stage
+// ignores its context.
+func executeAttempt(ctx context.Context, stage Stage, pipelineName string, input interface{}) (interface{}, error) {
+	timeout := timeoutOf(stage)
+	if timeout <= 0 {
+		return stage.Execute(ctx, pipelineName, input)
+	}
+
+	attemptCtx, cancel := context.WithTimeout(ctx, timeout)
+	defer cancel()
+
+	type attemptResult struct {
+		result interface{}
+		err    error
+	}
+	done := make(chan attemptResult, 1)
+	go func() {
+		result, err := stage.Execute(attemptCtx, pipelineName, input)
+		done <- attemptResult{result: result, err: err}
+	}()
+
+	var outcome attemptResult
+	select {
+	case outcome = <-done:
+	case <-attemptCtx.Done():
+		outcome.err = attemptCtx.Err()
+	}
+
+	if outcome.err != nil && ctx.Err() == nil && errors.Is(attemptCtx.Err(), context.DeadlineExceeded) {
+		return nil, NewStageError(ErrorClassTimeout, fmt.Errorf("%w after %s", ErrStageTimedOut, timeout))
+	}
+	return outcome.result, outcome.err
+}
@@ -0,0 +1,45 @@
+package ports
+
+import (
+	"context"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// EventLogQuery selects the events read from an EventLog. Reading starts at
+// FromSequence when it is set, else at FromTime when it is set, else at the
+// oldest retained event.
+type EventLogQuery struct {
+	PipelineID   uuid.UUID // uuid.Nil reads the events of every pipeline
+	FromSequence uint64
+	FromTime     time.Time
+	Follow       bool // Keep waiting for new events once the log is read
+}
+
+// LoggedEvent is an event with its position in the log.
+type LoggedEvent struct {
+	Sequence uint64
+	Event    Event
+}
+
+// EventLogHandler handles one event read from an EventLog.
+type EventLogHandler func(ctx context.Context, event LoggedEvent) error
+
+// EventLog is a durable log of pipeline events, kept within retention
+// limits, that can be read again from any retained position.
+type EventLog interface {
+	// Append adds the event to the pipeline's events and returns its
+	// sequence. Appending an event ID again shortly after is a no-op.
+	Append(ctx context.Context, pipelineID uuid.UUID, event Event) (uint64, error)
+	// Read passes the selected events to handler in order. It returns once
+	// the log is read, unless the query follows it, and stops at the first
+	// handler error.
+	Read(ctx context.Context, query EventLogQuery, handler EventLogHandler) error
+	// Consume follows the selected events under a durable name, so a
+	// consumer that restarts with the same name continues after the last
+	// event its handler accepted. The query's start applies only when the
+	// name is first used. An event whose handler fails is delivered again.
+	// Consume blocks until ctx is done.
+	Consume(ctx context.Context, name string, query EventLogQuery, handler EventLogHandler) error
+}
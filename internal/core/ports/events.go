@@ -0,0 +1,178 @@
+package ports
+
+import (
+	"encoding/json"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// CloudEventsSpecVersion is the CloudEvents version of Event.
+const CloudEventsSpecVersion = "1.0"
+
+// The pipeline event catalog. Run and stage events are named after the status
+// they report; see RunEventType and StageEventType.
+const (
+	EventPipelineCreated       = "pipeline.created"
+	EventPipelineDeleted       = "pipeline.deleted"
+	EventPipelineStatusChanged = "pipeline.status_changed" // Mirrors the latest run
+
+	EventRunQueued      = "run.queued"
+	EventRunStarted     = "run.started"
+	EventRunCompleted   = "run.completed"
+	EventRunFailed      = "run.failed"
+	EventRunCancelled   = "run.cancelled"
+	EventRunTimedOut    = "run.timed_out"
+	EventRunInterrupted = "run.interrupted"
+
+	EventStagePending        = "stage.pending"
+	EventStageStarted        = "stage.started"
+	EventStageRetrying       = "stage.retrying"
+	EventStageCompleted      = "stage.completed"
+	EventStageFailed         = "stage.failed"
+	EventStageTimedOut       = "stage.timed_out"
+	EventStageSkipped        = "stage.skipped"
+	EventStageCancelled      = "stage.cancelled"
+	EventStageInterrupted    = "stage.interrupted"
+	EventStageRollingBack    = "stage.rolling_back"
+	EventStageRolledBack     = "stage.rolled_back"
+	EventStageRollbackFailed = "stage.rollback_failed"
+)
+
+var runEventTypes = map[string]string{
+	"Pending":     EventRunQueued,
+	"Running":     EventRunStarted,
+	"Completed":   EventRunCompleted,
+	"Failed":      EventRunFailed,
+	"Cancelled":   EventRunCancelled,
+	"TimedOut":    EventRunTimedOut,
+	"Interrupted": EventRunInterrupted,
+}
+
+var stageEventTypes = map[string]string{
+	"Pending":        EventStagePending,
+	"Running":        EventStageStarted,
+	"Retrying":       EventStageRetrying,
+	"Completed":      EventStageCompleted,
+	"Failed":         EventStageFailed,
+	"TimedOut":       EventStageTimedOut,
+	"Skipped":        EventStageSkipped,
+	"Cancelled":      EventStageCancelled,
+	"Interrupted":    EventStageInterrupted,
+	"RollingBack":    EventStageRollingBack,
+	"RolledBack":     EventStageRolledBack,
+	"RollbackFailed": EventStageRollbackFailed,
+}
+
+// RunEventType returns the type of the event reporting that a run reached
+// status, such as run.started for Running.
+func RunEventType(status string) string {
+	return eventType("run", runEventTypes, status)
+}
+
+// StageEventType returns the type of the event reporting that a stage reached
+// status, such as stage.started for Running.
+func StageEventType(status string) string {
+	return eventType("stage", stageEventTypes, status)
+}
+
+// eventType falls back to the status in snake case for statuses missing from
+// the catalog.
+func eventType(kind string, types map[string]string, status string) string {
+	if t, ok := types[status]; ok {
+		return t
+	}
+	var name strings.Builder
+	for i, r := range status {
+		if i > 0 && r >= 'A' && r <= 'Z' {
+			name.WriteByte('_')
+		}
+		name.WriteRune(r)
+	}
+	return kind + "." + strings.ToLower(name.String())
+}
+
+// Event is a pipeline event in the CloudEvents 1.0 JSON format. Source is the
+// pipeline, and Subject the run or stage of a run the event is about.
+type Event struct {
+	SpecVersion     string          `json:"specversion"`
+	ID              string          `json:"id"`
+	Source          string          `json:"source"`
+	Type            string          `json:"type"`
+	Time            time.Time       `json:"time"`
+	Subject         string          `json:"subject,omitempty"`
+	DataContentType string          `json:"datacontenttype"`
+	Data            json.RawMessage `json:"data"`
+}
+
+// PipelineEventData is the data of pipeline.* events.
+type PipelineEventData struct {
+	PipelineID   uuid.UUID `json:"pipeline_id"`
+	PipelineName string    `json:"pipeline_name,omitempty"`
+	UserID       string    `json:"user_id,omitempty"`
+	Status       string    `json:"status,omitempty"`
+	RunID        string    `json:"run_id,omitempty"` // The latest run, if any
+}
+
+// RunEventData is the data of run.* events.
+type RunEventData struct {
+	PipelineID uuid.UUID `json:"pipeline_id"`
+	RunID      uuid.UUID `json:"run_id"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// StageEventData is the data of stage.* events.
+type StageEventData struct {
+	PipelineID uuid.UUID `json:"pipeline_id"`
+	RunID      string    `json:"run_id,omitempty"` // The pipeline's latest run
+	StageID    uuid.UUID `json:"stage_id"`
+	StageName  string    `json:"stage_name"`
+	Status     string    `json:"status"`
+	Error      string    `json:"error,omitempty"`
+}
+
+// NewEvent returns an event about the pipeline with a new ID.
+func NewEvent(eventType string, pipelineID uuid.UUID, subject string, data interface{}) (Event, error) {
+	encoded, err := json.Marshal(data)
+	if err != nil {
+		return Event{}, err
+	}
+	return Event{
+		SpecVersion:     CloudEventsSpecVersion,
+		ID:              uuid.NewString(),
+		Source:          EventSource(pipelineID),
+		Type:            eventType,
+		Time:            time.Now().UTC(),
+		Subject:         subject,
+		DataContentType: "application/json",
+		Data:            encoded,
+	}, nil
+}
+
+// EventSource is the CloudEvents source of a pipeline's events.
+func EventSource(pipelineID uuid.UUID) string {
+	return "/pipelines/" + pipelineID.String()
+}
+
+// RunSubject is the subject of events about a run.
+func RunSubject(runID uuid.UUID) string {
+	return "runs/" + runID.String()
+}
+
+// StageSubject is the subject of events about a stage in a run. Without a run
+// it names the stage alone.
+func StageSubject(runID string, stageID uuid.UUID) string {
+	if runID == "" {
+		return "stages/" + stageID.String()
+	}
+	return fmt.Sprintf("runs/%s/stages/%s", runID, stageID)
+}
+
+// PipelineEventsTopic is the topic a pipeline's events are published on.
+// Subscribe to pipelines.*.events for the events of every pipeline.
+func PipelineEventsTopic(pipelineID uuid.UUID) string {
+	return "pipelines." + pipelineID.String() + ".events"
+}
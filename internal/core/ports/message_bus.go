@@ -0,0 +1,70 @@
+package ports
+
+import (
+	"context"
+	"errors"
+	"time"
+)
+
+// ErrRejectMessage is returned by a MessageHandler for a queued message that
+// can never be handled, such as one that does not decode. The message is
+// dead-lettered at once instead of being retried.
+var ErrRejectMessage = errors.New("message rejected")
+
+// ErrDeadLettersUnsupported is returned when the configured message bus does
+// not keep dead letters.
+var ErrDeadLettersUnsupported = errors.New("message bus does not keep dead letters")
+
+// Message is the unit carried by a MessageBus.
+type Message struct {
+	ID            string // Set by Enqueue when empty
+	Data          []byte
+	ReplyTo       string // Topic the receiver should publish its reply on
+	CorrelationID string // Ties a reply to its request
+	Retries       int    // Times a queued message has been retried
+}
+
+// MessageHandler handles one delivered message.
+type MessageHandler func(ctx context.Context, msg Message) error
+
+// Subscription stops the deliveries of a Subscribe call.
+type Subscription interface {
+	Unsubscribe() error
+}
+
+// MessageBus carries messages between the parts of the system, in one process
+// or across brokers.
+//
+// Topics fan out: every subscriber receives each message published while it
+// is subscribed, at most once. Queues distribute: each enqueued message goes
+// to one of the queue's consumers and is acknowledged when the handler
+// returns nil. A handler error retries the message after a delay that grows
+// with each retry; once the retries run out, or at once when the error wraps
+// ErrRejectMessage, the message is dead-lettered.
+type MessageBus interface {
+	Publish(ctx context.Context, topic string, msg Message) error
+	Subscribe(topic string, handler MessageHandler) (Subscription, error)
+	Enqueue(ctx context.Context, queue string, msg Message) error
+	// Consume handles the queue's messages with up to concurrency handlers
+	// at once. It blocks until ctx is done or the bus is closed.
+	Consume(ctx context.Context, queue string, concurrency int, handler MessageHandler) error
+	Close() error
+}
+
+// DeadLetter is a queued message that was given up on.
+type DeadLetter struct {
+	Queue   string
+	Message Message
+	Reason  string // The last handler error
+	DeadAt  time.Time
+}
+
+// DeadLetterStore is implemented by message buses that keep dead letters for
+// inspection. Replay and Purge act on the messages with the given IDs, or on
+// every dead letter of the queue when ids is empty, and return how many they
+// handled.
+type DeadLetterStore interface {
+	DeadLetters(ctx context.Context, queue string) ([]DeadLetter, error)
+	ReplayDeadLetters(ctx context.Context, queue string, ids []string) (int, error)
+	PurgeDeadLetters(ctx context.Context, queue string, ids []string) (int, error)
+}
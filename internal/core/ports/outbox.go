@@ -0,0 +1,40 @@
+package ports
+
+import (
+	"time"
+
+	"github.com/sarika-p9/my-pipeline-project/internal/models"
+)
+
+// OutboxRepository is implemented by repositories that write an event to the
+// outbox in the same transaction as every change it reports, so no change is
+// committed without its event.
+type OutboxRepository interface {
+	// RelayOutboxEvents claims up to limit unpublished events that are due,
+	// oldest first, passes each to publish outside of any transaction, and
+	// marks those it accepts as published. Rejected events are retried
+	// later as retry says. Events claimed by another relay are skipped. It
+	// returns how many events were published.
+	RelayOutboxEvents(limit int, retry OutboxRetry, publish func(event models.OutboxEvent) error) (int, error)
+	// DeleteOutboxEvents removes events published before the given time.
+	DeleteOutboxEvents(publishedBefore time.Time) (int64, error)
+}
+
+// OutboxRetry decides when a relay tries an event it failed to publish again.
+type OutboxRetry struct {
+	MaxAttempts int           // Events failing this often are parked as failed
+	Backoff     time.Duration // Wait after the first failure, doubled after each one
+	MaxBackoff  time.Duration
+}
+
+// Delay returns the wait after the given number of failed attempts.
+func (r OutboxRetry) Delay(attempts int) time.Duration {
+	delay := r.Backoff
+	for i := 1; i < attempts && delay < r.MaxBackoff; i++ {
+		delay *= 2
+	}
+	if r.MaxBackoff > 0 && delay > r.MaxBackoff {
+		delay = r.MaxBackoff
+	}
+	return delay
+}
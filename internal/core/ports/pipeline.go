@@ -2,13 +2,32 @@ package ports
 
 import (
 	"context"
+	"errors"
+	"time"
 
 	"github.com/google/uuid"
 	"github.com/sarika-p9/my-pipeline-project/internal/models"
 )
 
+// ErrPipelineNotFound is returned by repositories when no pipeline has the
+// requested ID.
+var ErrPipelineNotFound = errors.New("pipeline not found")
+
+// ErrRunNotFound is returned by repositories when no run matches the request.
+var ErrRunNotFound = errors.New("pipeline run not found")
+
+// ErrRunAlreadyCancelled is returned by repositories when a run that was
+// cancelled would be given another status. Cancelled runs keep their status,
+// whichever replica executes them.
+var ErrRunAlreadyCancelled = errors.New("pipeline run was cancelled")
+
+// ErrPermissionDenied is returned when the user may not perform the request.
+var ErrPermissionDenied = errors.New("permission denied")
+
 type PipelineRepository interface {
-	SavePipelineExecution(execution *models.Pipelines) error
+	// SavePipelineExecution saves a new pipeline with its stages and their
+	// dependencies, all or nothing.
+	SavePipelineExecution(execution *models.Pipelines, stages []models.Stages, deps []models.StageDependencies) error
 	UpdatePipelineExecution(execution *models.Pipelines) error
 	SaveExecutionLog(logEntry *models.Stages) error
 	GetPipelineStatus(pipelineID string) (string, error)
@@ -16,8 +35,36 @@ type PipelineRepository interface {
 	SaveUser(user *models.User) error
 	UpdateUser(userID uuid.UUID, updates map[string]interface{}) error
 	GetPipelinesByUser(userID string) ([]models.Pipelines, error)
+	// GetPipelinesPage returns up to limit of the user's pipelines, oldest
+	// first, skipping the first offset.
+	GetPipelinesPage(userID uuid.UUID, offset, limit int) ([]models.Pipelines, error)
 	GetPipelineStages(pipelineID uuid.UUID) ([]models.Stages, error)
 	DeletePipeline(ctx context.Context, pipelineID string) error
 	GetPipelineByID(pipelineID uuid.UUID) (*models.Pipelines, error)
 	UpdateStageStatus(stageID uuid.UUID, status string) error
+	UpdateStageError(stageID uuid.UUID, status string, errorMsg string) error
+	GetStageDependencies(pipelineID uuid.UUID) ([]models.StageDependencies, error)
+	SaveStageAttempt(attempt *models.StageAttempts) error
+	UpdateStageOutput(stageID uuid.UUID, output string) error
+	UpdatePipelineOutput(pipelineID uuid.UUID, output string) error
+	SavePipelineRun(run *models.PipelineRun) error
+	UpdatePipelineRun(runID uuid.UUID, updates map[string]interface{}) error
+	GetPipelineRun(runID uuid.UUID) (*models.PipelineRun, error)
+	GetLatestPipelineRun(pipelineID uuid.UUID) (*models.PipelineRun, error)
+	GetPipelineRuns(pipelineID uuid.UUID) ([]models.PipelineRun, error)
+	// ClaimExpiredRuns makes owner the owner of the runs in the given
+	// statuses whose lease has expired, or that never had one, with a lease
+	// until leaseUntil. It returns the claimed runs, oldest first.
+	ClaimExpiredRuns(owner string, leaseUntil time.Time, statuses ...string) ([]models.PipelineRun, error)
+	// RenewRunLeases extends the lease of owner's runs in the given statuses.
+	RenewRunLeases(owner string, leaseUntil time.Time, statuses ...string) error
+	SaveStageRuns(stageRuns []models.StageRun) error
+	// UpdateStageRun records a stage's progress in a run. It writes no
+	// outbox event: stage events come from UpdateStageStatus and
+	// UpdateStageError, which every stage status change goes through.
+	UpdateStageRun(runID uuid.UUID, stageID uuid.UUID, updates map[string]interface{}) error
+	GetStageRuns(runID uuid.UUID) ([]models.StageRun, error)
+	// NextEventSequence advances the pipeline's live update sequence and
+	// returns the new value.
+	NextEventSequence(pipelineID uuid.UUID) (int64, error)
 }
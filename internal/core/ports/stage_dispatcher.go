@@ -0,0 +1,47 @@
+package ports
+
+import (
+	"context"
+	"encoding/json"
+	"time"
+
+	"github.com/google/uuid"
+)
+
+// StageTask asks a worker to run one attempt of a stage.
+type StageTask struct {
+	TaskID       uuid.UUID       `json:"task_id"`
+	PipelineID   uuid.UUID       `json:"pipeline_id"`
+	RunID        uuid.UUID       `json:"run_id"`
+	StageID      uuid.UUID       `json:"stage_id"`
+	PipelineName string          `json:"pipeline_name"`
+	StageName    string          `json:"stage_name"`
+	StageType    string          `json:"stage_type"`
+	Config       json.RawMessage `json:"config,omitempty"`
+	Input        json.RawMessage `json:"input,omitempty"` // JSON encoded stage input
+	TimeoutMs    int64           `json:"timeout_ms,omitempty"`
+	Deadline     *time.Time      `json:"deadline,omitempty"` // Nobody waits for the result after it
+}
+
+// StageTaskCancel tells the workers to stop a task whose result nobody waits
+// for anymore.
+type StageTaskCancel struct {
+	TaskID uuid.UUID `json:"task_id"`
+}
+
+// StageResult is a worker's reply to a StageTask. Error is empty when the
+// attempt succeeded.
+type StageResult struct {
+	TaskID     uuid.UUID       `json:"task_id"`
+	Output     json.RawMessage `json:"output,omitempty"` // JSON encoded stage output
+	Error      string          `json:"error,omitempty"`
+	ErrorClass string          `json:"error_class,omitempty"`
+	TimedOut   bool            `json:"timed_out,omitempty"` // The attempt exceeded the stage timeout
+}
+
+// StageDispatcher runs stage attempts on workers outside this process.
+type StageDispatcher interface {
+	// Dispatch publishes the task and waits for its result, or for ctx to
+	// be done. The workers stop the task when Dispatch gives up on it.
+	Dispatch(ctx context.Context, task StageTask) (StageResult, error)
+}
@@ -47,9 +47,32 @@ func migrateDatabase() {
 	migrateTable(&models.User{})
 	migrateTable(&models.Pipelines{})
 	migrateTable(&models.Stages{})
+	migrateTable(&models.StageDependencies{})
+	migrateTable(&models.StageAttempts{})
+	migrateTable(&models.PipelineRun{})
+	migrateTable(&models.StageRun{})
+	migrateTable(&models.OutboxEvent{})
+	migrateColumns(&models.Pipelines{}, "IsParallel", "FailurePolicy", "TimeoutMs", "Output", "EventSequence")
+	migrateColumns(&models.Stages{}, "Position", "RetryPolicy", "TimeoutMs", "StageType", "Config", "Output")
+	migrateColumns(&models.StageAttempts{}, "RunID")
+	migrateColumns(&models.PipelineRun{}, "Owner", "LeaseExpiresAt")
 	log.Println("Database migration completed successfully.")
 }
 
+// migrateColumns adds fields introduced after a table was first created,
+// since migrateTable leaves existing tables untouched.
+func migrateColumns(model interface{}, fields ...string) {
+	for _, field := range fields {
+		if DB.Migrator().HasColumn(model, field) {
+			continue
+		}
+		if err := DB.Migrator().AddColumn(model, field); err != nil {
+			log.Fatalf("Failed to add column %s to %T: %v", field, model, err)
+		}
+		log.Printf("Successfully added column %s to %T", field, model)
+	}
+}
+
 func migrateTable(model interface{}) {
 	tableName := DB.NamingStrategy.TableName(getTableName(model))
 
@@ -1,50 +0,0 @@
-package infrastructure
-
-import (
-	"log"
-
-	"github.com/streadway/amqp"
-)
-
-type RabbitMQ struct {
-	Conn    *amqp.Connection
-	Channel *amqp.Channel
-	Queue   amqp.Queue
-}
-
-func NewRabbitMQ(url, queueName string) (*RabbitMQ, error) {
-	conn, err := amqp.Dial(url)
-	if err != nil {
-		return nil, err
-	}
-
-	ch, err := conn.Channel()
-	if err != nil {
-		return nil, err
-	}
-
-	q, err := ch.QueueDeclare(
-		queueName,
-		true,  // Durable
-		false, // Auto-delete
-		false, // Exclusive
-		false, // No-wait
-		nil,   // Arguments
-	)
-	if err != nil {
-		return nil, err
-	}
-
-	log.Printf("✅ Connected to RabbitMQ and declared queue '%s'", queueName)
-
-	return &RabbitMQ{
-		Conn:    conn,
-		Channel: ch,
-		Queue:   q,
-	}, nil
-}
-
-func (r *RabbitMQ) Close() {
-	r.Channel.Close()
-	r.Conn.Close()
-}
@@ -1,6 +1,8 @@
 package infrastructure
 
 import (
+	"context"
+	"errors"
 	// "log"
 	"os"
 
@@ -20,3 +22,15 @@ func InitSupabaseClient() *supabase.Client {
 
 	return supabase.CreateClient(url, key)
 }
+
+// VerifyToken returns the ID of the user a bearer token was issued to.
+func VerifyToken(ctx context.Context, token string) (string, error) {
+	user, err := InitSupabaseClient().Auth.User(ctx, token)
+	if err != nil {
+		return "", err
+	}
+	if user == nil {
+		return "", errors.New("no user for token")
+	}
+	return user.ID, nil
+}
@@ -0,0 +1,73 @@
+package infrastructure
+
+import (
+	"sync"
+
+	"github.com/google/uuid"
+)
+
+// Watcher follows the updates of one pipeline, for transports other than
+// WebSockets.
+type Watcher struct {
+	// Resync is set when the updates missed since the last sequence are no
+	// longer kept, so the watcher should reload the pipeline's state.
+	Resync bool
+
+	wm         *WebSocketManager
+	pipelineID uuid.UUID
+	updates    chan PipelineUpdate
+	done       chan struct{}
+	once       sync.Once
+}
+
+// Watch follows the pipeline's updates. When lastSeq is not 0, the updates
+// since lastSeq come first. Like a WebSocket client, a watcher that falls
+// SendQueueSize updates behind is stopped; it can watch again from the last
+// sequence it received.
+func (wm *WebSocketManager) Watch(pipelineID uuid.UUID, lastSeq int64) *Watcher {
+	w := &Watcher{
+		wm:         wm,
+		pipelineID: pipelineID,
+		updates:    make(chan PipelineUpdate, wm.SendQueueSize),
+		done:       make(chan struct{}),
+	}
+
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	if lastSeq != 0 {
+		missed, ok := wm.missedUpdates(pipelineID, lastSeq)
+		if !ok || len(missed) > cap(w.updates) {
+			w.Resync = true
+		} else {
+			for _, update := range missed {
+				w.updates <- update
+			}
+		}
+	}
+	wm.watchers[w] = true
+	return w
+}
+
+// Updates delivers the pipeline's updates.
+func (w *Watcher) Updates() <-chan PipelineUpdate {
+	return w.updates
+}
+
+// Done is closed once the watcher is stopped.
+func (w *Watcher) Done() <-chan struct{} {
+	return w.done
+}
+
+// Stop stops the deliveries.
+func (w *Watcher) Stop() {
+	w.wm.mu.Lock()
+	delete(w.wm.watchers, w)
+	w.wm.mu.Unlock()
+	w.close()
+}
+
+func (w *Watcher) close() {
+	w.once.Do(func() {
+		close(w.done)
+	})
+}
@@ -1,81 +1,496 @@
 package infrastructure
 
 import (
+	"context"
 	"encoding/json"
+	"expvar"
+	"fmt"
 	"log"
 	"net/http"
+	"strings"
 	"sync"
+	"time"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/gorilla/websocket"
+	"github.com/sarika-p9/my-pipeline-project/internal/core/ports"
 )
 
+// Types of PipelineUpdate.
+const (
+	StageUpdateType = "stage_update"
+	RunUpdateType   = "run_update"
+)
+
+// PipelineUpdate reports a stage or a run of a pipeline reaching a status.
+// Run updates have no stage.
+type PipelineUpdate struct {
+	Type         string    `json:"type"`
+	Sequence     int64     `json:"seq"` // Per pipeline, 0 when unknown
+	PipelineID   uuid.UUID `json:"pipeline_id"`
+	PipelineName string    `json:"pipeline_name"`
+	UserID       uuid.UUID `json:"user_id"` // Owner of the pipeline
+	RunID        uuid.UUID `json:"run_id"`
+	StageID      uuid.UUID `json:"stage_id"`
+	StageName    string    `json:"stage_name"`
+	Status       string    `json:"status"`
+}
+
+// PipelineUpdatesTopic carries updates between the replicas serving
+// WebSockets.
+const PipelineUpdatesTopic = "websocket.pipeline_updates"
+
+// WebSocketManager streams pipeline updates to authenticated clients. A client
+// receives only the updates of the pipelines it subscribed to, and of its own
+// pipelines when it subscribed to them all.
+//
+// Clients connect to /ws with a bearer token, in the Authorization header or,
+// for browsers, the token query parameter, and then send control messages:
+//
+//	{"action": "subscribe", "pipeline_ids": ["<id>", ...]}
+//	{"action": "subscribe", "mine": true}
+//	{"action": "unsubscribe", "pipeline_ids": ["<id>", ...]}
+//	{"action": "unsubscribe", "mine": true}
+//
+// Each control message is answered with the client's subscriptions, or with
+// an error for pipelines the user may not view.
+//
+// Updates carry a sequence number that increases with each update of the
+// pipeline. A client that reconnects sends the last sequence it saw for each
+// pipeline it subscribes to again, and first receives the updates it missed,
+// from the RecentUpdates kept for the pipeline:
+//
+//	{"action": "subscribe", "pipeline_ids": ["<id>"], "last_seq": {"<id>": 41}}
+//
+// When they are no longer kept, it receives {"type": "resync"} for the
+// pipeline instead, and should reload the pipeline's state.
+//
+// Every client has its own bounded send queue drained by a writer goroutine,
+// so SendMessage never waits on a client. When a client's queue is full, the
+// message is dropped or the client is disconnected, per SlowClientPolicy.
+// Clients are pinged every PingInterval and disconnected when they don't
+// answer within PongWait.
+//
+// Watch follows a pipeline's updates without a WebSocket, for other
+// transports.
+//
+// By default a manager delivers the updates sent in its own process. Once
+// Relay is called, updates go through a message bus topic instead, so clients
+// of every replica receive the updates of every replica.
 type WebSocketManager struct {
-	clients   map[*websocket.Conn]bool
-	broadcast chan []byte
-	mu        sync.Mutex
-	upgrader  websocket.Upgrader
+	// Authenticate returns the user a token was issued to.
+	Authenticate func(ctx context.Context, token string) (string, error)
+	// CanViewPipeline reports whether the user may follow the pipeline.
+	CanViewPipeline func(userID uuid.UUID, pipelineID uuid.UUID) error
+	// AllowedOrigins are the browser origins allowed to connect. Clients that
+	// send no Origin header, such as CLIs, are always allowed.
+	AllowedOrigins map[string]bool
+	// SendQueueSize is how many messages may wait for a client.
+	SendQueueSize int
+	// SlowClientPolicy is applied when a client's send queue is full.
+	SlowClientPolicy SlowClientPolicy
+	// PingInterval is how often clients are pinged, and PongWait how long a
+	// client may stay silent before it is disconnected. PingInterval must be
+	// shorter than PongWait.
+	PingInterval time.Duration
+	PongWait     time.Duration
+	// WriteWait bounds a single write to a client.
+	WriteWait time.Duration
+	// RecentUpdates is how many updates are kept per pipeline for clients
+	// catching up, and RecentUpdatesTTL how long they are kept once the
+	// pipeline goes quiet.
+	RecentUpdates    int
+	RecentUpdatesTTL time.Duration
+
+	clients  map[*wsClient]bool
+	mu       sync.RWMutex
+	upgrader websocket.Upgrader
+	relay    ports.MessageBus
+	recent   map[uuid.UUID]*pipelineUpdates
+	watchers map[*Watcher]bool
 }
 
-var WebSocket = &WebSocketManager{
-	clients:   make(map[*websocket.Conn]bool),
-	broadcast: make(chan []byte),
-	upgrader: websocket.Upgrader{
-		CheckOrigin: func(r *http.Request) bool { return true },
-	},
+// SlowClientPolicy is what to do with a client that can't keep up.
+type SlowClientPolicy string
+
+const (
+	// DropMessages drops messages for the client until its queue drains.
+	DropMessages SlowClientPolicy = "drop"
+	// DisconnectSlowClients closes the client's connection, so it can
+	// reconnect and catch up.
+	DisconnectSlowClients SlowClientPolicy = "disconnect"
+)
+
+// ParseSlowClientPolicy validates a slow client policy name. An empty name
+// means DropMessages.
+func ParseSlowClientPolicy(name string) (SlowClientPolicy, error) {
+	switch policy := SlowClientPolicy(strings.ToLower(strings.TrimSpace(name))); policy {
+	case "":
+		return DropMessages, nil
+	case DropMessages, DisconnectSlowClients:
+		return policy, nil
+	default:
+		return "", fmt.Errorf("unknown slow client policy %q, expected drop or disconnect", name)
+	}
+}
+
+const (
+	DefaultWebSocketSendQueueSize = 256
+	DefaultWebSocketPongWait      = 60 * time.Second
+	DefaultWebSocketPingInterval  = DefaultWebSocketPongWait * 9 / 10
+	DefaultWebSocketWriteWait     = 10 * time.Second
+	DefaultRecentUpdates          = 100
+	DefaultRecentUpdatesTTL       = time.Hour
+
+	// maxControlMessageSize bounds the messages clients send.
+	maxControlMessageSize = 64 << 10
+)
+
+// WebSocketMetrics are published on /debug/vars as "websocket":
+// connected_clients, dropped_messages and slow_client_disconnects.
+var WebSocketMetrics = expvar.NewMap("websocket")
+
+var WebSocket = NewWebSocketManager()
+
+func NewWebSocketManager() *WebSocketManager {
+	wm := &WebSocketManager{
+		Authenticate:     VerifyToken,
+		SendQueueSize:    DefaultWebSocketSendQueueSize,
+		SlowClientPolicy: DropMessages,
+		PingInterval:     DefaultWebSocketPingInterval,
+		PongWait:         DefaultWebSocketPongWait,
+		WriteWait:        DefaultWebSocketWriteWait,
+		RecentUpdates:    DefaultRecentUpdates,
+		RecentUpdatesTTL: DefaultRecentUpdatesTTL,
+		clients:          make(map[*wsClient]bool),
+		recent:           make(map[uuid.UUID]*pipelineUpdates),
+		watchers:         make(map[*Watcher]bool),
+	}
+	wm.upgrader = websocket.Upgrader{CheckOrigin: wm.checkOrigin}
+	return wm
+}
+
+type wsClient struct {
+	conn   *websocket.Conn
+	userID uuid.UUID
+	send   chan []byte   // Messages waiting for the writer goroutine
+	done   chan struct{} // Closed when the client is closed
+	once   sync.Once
+
+	mu        sync.Mutex // Guards the subscriptions
+	pipelines map[uuid.UUID]bool
+	mine      bool
+}
+
+type wsControlMessage struct {
+	Action      string   `json:"action"`
+	PipelineIDs []string `json:"pipeline_ids"`
+	Mine        bool     `json:"mine"`
+	// LastSeq is the last sequence seen of pipelines in PipelineIDs.
+	LastSeq map[string]int64 `json:"last_seq"`
+}
+
+func (wm *WebSocketManager) checkOrigin(r *http.Request) bool {
+	origin := r.Header.Get("Origin")
+	return origin == "" || wm.AllowedOrigins[origin]
 }
 
 func (wm *WebSocketManager) HandleConnections(c *gin.Context) {
+	token := c.Query("token")
+	if header := c.GetHeader("Authorization"); header != "" {
+		token = strings.TrimPrefix(header, "Bearer ")
+	}
+	if token == "" {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Authorization token is required"})
+		return
+	}
+	subject, err := wm.Authenticate(c.Request.Context(), token)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
+		return
+	}
+	userID, err := uuid.Parse(subject)
+	if err != nil {
+		c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID"})
+		return
+	}
+
 	conn, err := wm.upgrader.Upgrade(c.Writer, c.Request, nil)
 	if err != nil {
 		log.Println("WebSocket upgrade failed:", err)
 		return
 	}
-	defer conn.Close()
-
+	client := &wsClient{
+		conn:      conn,
+		userID:    userID,
+		send:      make(chan []byte, wm.SendQueueSize),
+		done:      make(chan struct{}),
+		pipelines: make(map[uuid.UUID]bool),
+	}
 	wm.mu.Lock()
-	wm.clients[conn] = true
+	wm.clients[client] = true
 	wm.mu.Unlock()
+	WebSocketMetrics.Add("connected_clients", 1)
+
+	defer func() {
+		wm.mu.Lock()
+		delete(wm.clients, client)
+		wm.mu.Unlock()
+		WebSocketMetrics.Add("connected_clients", -1)
+		client.close()
+	}()
 
+	go wm.writePump(client)
+
+	conn.SetReadLimit(maxControlMessageSize)
+	conn.SetReadDeadline(time.Now().Add(wm.PongWait))
+	conn.SetPongHandler(func(string) error {
+		return conn.SetReadDeadline(time.Now().Add(wm.PongWait))
+	})
 	for {
-		_, msg, err := conn.ReadMessage()
+		_, data, err := conn.ReadMessage()
 		if err != nil {
-			wm.mu.Lock()
-			delete(wm.clients, conn)
-			wm.mu.Unlock()
-			break
+			return
+		}
+		conn.SetReadDeadline(time.Now().Add(wm.PongWait))
+		var msg wsControlMessage
+		if err := json.Unmarshal(data, &msg); err != nil {
+			wm.reply(client, gin.H{"type": "error", "error": "Invalid control message"})
+			continue
 		}
-		wm.broadcast <- msg
+		wm.handleControl(client, msg)
 	}
 }
 
-func (wm *WebSocketManager) StartBroadcaster() {
+// writePump writes the client's queued messages and pings it, until the
+// client is closed or a write fails.
+func (wm *WebSocketManager) writePump(client *wsClient) {
+	ticker := time.NewTicker(wm.PingInterval)
+	defer ticker.Stop()
+	defer client.close()
+
 	for {
-		msg := <-wm.broadcast
-		wm.mu.Lock()
-		for client := range wm.clients {
-			err := client.WriteMessage(websocket.TextMessage, msg)
-			if err != nil {
-				client.Close()
-				delete(wm.clients, client)
+		select {
+		case <-client.done:
+			return
+		case data := <-client.send:
+			client.conn.SetWriteDeadline(time.Now().Add(wm.WriteWait))
+			if err := client.conn.WriteMessage(websocket.TextMessage, data); err != nil {
+				return
+			}
+		case <-ticker.C:
+			client.conn.SetWriteDeadline(time.Now().Add(wm.WriteWait))
+			if err := client.conn.WriteMessage(websocket.PingMessage, nil); err != nil {
+				return
 			}
 		}
-		wm.mu.Unlock()
 	}
 }
 
-func (wm *WebSocketManager) SendMessage(pipelineName, stageName, status string) {
-	message := map[string]string{
-		"pipeline_name": pipelineName,
-		"stage_name":    stageName,
-		"status":        status,
+// handleControl applies a subscribe or unsubscribe message.
+func (wm *WebSocketManager) handleControl(client *wsClient, msg wsControlMessage) {
+	if msg.Action != "subscribe" && msg.Action != "unsubscribe" {
+		wm.reply(client, gin.H{"type": "error", "error": "Unknown action, expected subscribe or unsubscribe"})
+		return
+	}
+
+	pipelineIDs := make([]uuid.UUID, 0, len(msg.PipelineIDs))
+	for _, id := range msg.PipelineIDs {
+		pipelineID, err := uuid.Parse(id)
+		if err != nil {
+			wm.reply(client, gin.H{"type": "error", "error": "Invalid pipeline ID " + id})
+			return
+		}
+		if msg.Action == "subscribe" && wm.CanViewPipeline != nil {
+			if err := wm.CanViewPipeline(client.userID, pipelineID); err != nil {
+				wm.reply(client, gin.H{"type": "error", "error": err.Error()})
+				return
+			}
+		}
+		pipelineIDs = append(pipelineIDs, pipelineID)
+	}
+
+	lastSeqs := make(map[uuid.UUID]int64, len(msg.LastSeq))
+	for id, sequence := range msg.LastSeq {
+		pipelineID, err := uuid.Parse(id)
+		if err != nil || msg.Action != "subscribe" || !containsUUID(pipelineIDs, pipelineID) {
+			wm.reply(client, gin.H{"type": "error", "error": "last_seq is only accepted for the pipelines being subscribed to"})
+			return
+		}
+		lastSeqs[pipelineID] = sequence
 	}
 
-	jsonMessage, err := json.Marshal(message)
+	// Live updates wait until the client has caught up.
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+
+	client.mu.Lock()
+	for _, pipelineID := range pipelineIDs {
+		if msg.Action == "subscribe" {
+			client.pipelines[pipelineID] = true
+		} else {
+			delete(client.pipelines, pipelineID)
+		}
+	}
+	if msg.Mine {
+		client.mine = msg.Action == "subscribe"
+	}
+	subscribed := make([]string, 0, len(client.pipelines))
+	for pipelineID := range client.pipelines {
+		subscribed = append(subscribed, pipelineID.String())
+	}
+	mine := client.mine
+	client.mu.Unlock()
+
+	wm.reply(client, gin.H{"type": "subscriptions", "pipeline_ids": subscribed, "mine": mine})
+	for _, pipelineID := range pipelineIDs {
+		if lastSeq, ok := lastSeqs[pipelineID]; ok {
+			wm.catchUp(client, pipelineID, lastSeq)
+		}
+	}
+}
+
+func containsUUID(ids []uuid.UUID, id uuid.UUID) bool {
+	for _, candidate := range ids {
+		if candidate == id {
+			return true
+		}
+	}
+	return false
+}
+
+func (c *wsClient) wants(update PipelineUpdate) bool {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.pipelines[update.PipelineID] || (c.mine && update.UserID == c.userID)
+}
+
+// close closes the client's connection, which ends both its reader and its
+// writer.
+func (c *wsClient) close() {
+	c.once.Do(func() {
+		close(c.done)
+		c.conn.Close()
+	})
+}
+
+// reply queues a response to one of the client's control messages.
+func (wm *WebSocketManager) reply(client *wsClient, message interface{}) {
+	data, err := json.Marshal(message)
+	if err != nil {
+		log.Println("WebSocket reply encoding failed:", err)
+		return
+	}
+	wm.enqueue(client, data)
+}
+
+// enqueue queues a message for the client without waiting, applying the
+// SlowClientPolicy when the client's queue is full.
+func (wm *WebSocketManager) enqueue(client *wsClient, data []byte) {
+	select {
+	case client.send <- data:
+		return
+	case <-client.done:
+		return
+	default:
+	}
+
+	WebSocketMetrics.Add("dropped_messages", 1)
+	if wm.SlowClientPolicy == DisconnectSlowClients {
+		log.Printf("Disconnecting slow WebSocket client of user %s", client.userID)
+		WebSocketMetrics.Add("slow_client_disconnects", 1)
+		client.close()
+	}
+}
+
+// Relay makes the manager exchange updates with the other replicas over the
+// bus: SendMessage publishes on PipelineUpdatesTopic, and clients receive every
+// update published there, this replica's included.
+func (wm *WebSocketManager) Relay(bus ports.MessageBus) (ports.Subscription, error) {
+	sub, err := bus.Subscribe(PipelineUpdatesTopic, func(ctx context.Context, msg ports.Message) error {
+		var update PipelineUpdate
+		if err := json.Unmarshal(msg.Data, &update); err != nil {
+			return fmt.Errorf("decoding pipeline update: %w", err)
+		}
+		wm.deliver(update)
+		return nil
+	})
+	if err != nil {
+		return nil, err
+	}
+	wm.mu.Lock()
+	wm.relay = bus
+	wm.mu.Unlock()
+	return sub, nil
+}
+
+// SendMessage sends the update to every client subscribed to it, on every
+// replica when relaying. It never waits on clients.
+func (wm *WebSocketManager) SendMessage(update PipelineUpdate) {
+	wm.mu.RLock()
+	relay := wm.relay
+	wm.mu.RUnlock()
+	if relay == nil {
+		wm.deliver(update)
+		return
+	}
+
+	data, err := json.Marshal(update)
+	if err != nil {
+		log.Println("WebSocket update encoding failed:", err)
+		return
+	}
+	if err := relay.Publish(context.Background(), PipelineUpdatesTopic, ports.Message{Data: data}); err != nil {
+		// Other replicas miss the update, but this one's clients still get it.
+		log.Println("WebSocket update relay failed:", err)
+		wm.deliver(update)
+	}
+}
+
+// deliver queues the update for this replica's clients subscribed to it.
+func (wm *WebSocketManager) deliver(update PipelineUpdate) {
+	data, err := update.Message()
 	if err != nil {
-		log.Println("Error marshalling WebSocket message:", err)
+		log.Println("WebSocket update encoding failed:", err)
 		return
 	}
 
-	wm.broadcast <- jsonMessage
+	wm.mu.Lock()
+	defer wm.mu.Unlock()
+	wm.remember(update)
+	for client := range wm.clients {
+		if client.wants(update) {
+			wm.enqueue(client, data)
+		}
+	}
+	for watcher := range wm.watchers {
+		if watcher.pipelineID != update.PipelineID {
+			continue
+		}
+		select {
+		case watcher.updates <- update:
+		default:
+			WebSocketMetrics.Add("dropped_messages", 1)
+			delete(wm.watchers, watcher)
+			watcher.close()
+		}
+	}
+}
+
+// Message encodes the update as it is sent to clients.
+func (u PipelineUpdate) Message() ([]byte, error) {
+	message := gin.H{
+		"type":          u.Type,
+		"seq":           u.Sequence,
+		"pipeline_id":   u.PipelineID,
+		"pipeline_name": u.PipelineName,
+		"run_id":        u.RunID,
+		"status":        u.Status,
+	}
+	if u.Type == StageUpdateType {
+		message["stage_id"] = u.StageID
+		message["stage_name"] = u.StageName
+	}
+	return json.Marshal(message)
 }
@@ -0,0 +1,84 @@
+package infrastructure
+
+import (
+	"log"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
+)
+
+// pipelineUpdates are the latest sequenced updates of a pipeline.
+type pipelineUpdates struct {
+	updates []PipelineUpdate // Ordered by sequence
+	touched time.Time
+}
+
+// remember keeps a sequenced update for clients catching up. Updates of
+// parallel stages can arrive out of order, so they are inserted in sequence
+// order. The caller holds wm.mu.
+func (wm *WebSocketManager) remember(update PipelineUpdate) {
+	if update.Sequence == 0 || wm.RecentUpdates < 1 {
+		return
+	}
+
+	now := time.Now()
+	recent := wm.recent[update.PipelineID]
+	if recent == nil {
+		for pipelineID, idle := range wm.recent {
+			if now.Sub(idle.touched) > wm.RecentUpdatesTTL {
+				delete(wm.recent, pipelineID)
+			}
+		}
+		recent = &pipelineUpdates{}
+		wm.recent[update.PipelineID] = recent
+	}
+	recent.touched = now
+
+	i := len(recent.updates)
+	for ; i > 0 && recent.updates[i-1].Sequence >= update.Sequence; i-- {
+		if recent.updates[i-1].Sequence == update.Sequence {
+			return
+		}
+	}
+	recent.updates = append(recent.updates, PipelineUpdate{})
+	copy(recent.updates[i+1:], recent.updates[i:])
+	recent.updates[i] = update
+	if len(recent.updates) > wm.RecentUpdates {
+		recent.updates = append(recent.updates[:0], recent.updates[1:]...)
+	}
+}
+
+// missedUpdates returns the pipeline's updates after lastSeq. ok is false when
+// some of them are no longer kept.
+func (wm *WebSocketManager) missedUpdates(pipelineID uuid.UUID, lastSeq int64) (missed []PipelineUpdate, ok bool) {
+	recent := wm.recent[pipelineID]
+	if recent == nil || recent.updates[0].Sequence > lastSeq+1 {
+		return nil, false
+	}
+	for i, update := range recent.updates {
+		if update.Sequence > lastSeq {
+			return append([]PipelineUpdate(nil), recent.updates[i:]...), true
+		}
+	}
+	return nil, true
+}
+
+// catchUp queues the pipeline's updates the client missed since lastSeq, or
+// tells it to resync when they are no longer kept or don't fit its queue. The
+// caller holds wm.mu.
+func (wm *WebSocketManager) catchUp(client *wsClient, pipelineID uuid.UUID, lastSeq int64) {
+	missed, ok := wm.missedUpdates(pipelineID, lastSeq)
+	if !ok || len(missed) > cap(client.send)-len(client.send) {
+		wm.reply(client, gin.H{"type": "resync", "pipeline_id": pipelineID})
+		return
+	}
+	for _, update := range missed {
+		data, err := update.Message()
+		if err != nil {
+			log.Println("WebSocket update encoding failed:", err)
+			continue
+		}
+		wm.enqueue(client, data)
+	}
+}
@@ -0,0 +1,88 @@
+package messaging
+
+import (
+	"errors"
+	"fmt"
+	"strings"
+	"time"
+
+	"github.com/sarika-p9/my-pipeline-project/internal/core/ports"
+	"github.com/sarika-p9/my-pipeline-project/internal/infrastructure"
+)
+
+// Transports that NewMessageBus can connect to.
+const (
+	TransportMemory   = "memory"
+	TransportRabbitMQ = "rabbitmq"
+	TransportNATS     = "nats"
+)
+
+// DefaultRetryDelays are the waits before each retry of a failed queued
+// message. A message that still fails after the last one is dead-lettered.
+var DefaultRetryDelays = []time.Duration{time.Second, 10 * time.Second, time.Minute}
+
+// nextRetry returns the delay before retrying a message that failed with err,
+// or false when the message should be dead-lettered instead.
+func nextRetry(delays []time.Duration, msg ports.Message, err error) (time.Duration, bool) {
+	if errors.Is(err, ports.ErrRejectMessage) || msg.Retries >= len(delays) {
+		return 0, false
+	}
+	return delays[msg.Retries], true
+}
+
+// selectIDs reports whether ids selects id; an empty ids selects everything.
+func selectIDs(ids []string) func(id string) bool {
+	selected := make(map[string]bool, len(ids))
+	for _, id := range ids {
+		selected[id] = true
+	}
+	return func(id string) bool {
+		return len(ids) == 0 || selected[id]
+	}
+}
+
+// NewMessageBus returns the bus for the named transport, connecting to the
+// broker at the matching URL. An empty name means TransportRabbitMQ.
+func NewMessageBus(transport, rabbitURL, natsURL string) (ports.MessageBus, error) {
+	switch strings.ToLower(strings.TrimSpace(transport)) {
+	case TransportMemory:
+		return NewMemoryBus(), nil
+	case TransportRabbitMQ, "":
+		return NewRabbitMQBus(rabbitURL)
+	case TransportNATS:
+		return NewNATSBus(infrastructure.ConnectNATS(natsURL)), nil
+	default:
+		return nil, fmt.Errorf("unknown message bus %q, expected %s, %s or %s", transport, TransportMemory, TransportRabbitMQ, TransportNATS)
+	}
+}
+
+// NewEventBus returns the bus the outbox relay publishes pipeline events on:
+// NATS at natsURL, whatever bus carries the tasks, so event subscribers
+// always find them on the pipelines.<id>.events subjects. It reuses bus when
+// that is a NATS bus, or the in-memory bus of a deployment without brokers.
+func NewEventBus(bus ports.MessageBus, natsURL string) ports.MessageBus {
+	switch bus.(type) {
+	case *NATSBus, *MemoryBus:
+		return bus
+	default:
+		return NewNATSBus(infrastructure.ConnectNATS(natsURL))
+	}
+}
+
+// NewWebSocketFanout returns the bus that carries WebSocket updates between
+// replicas for the given kind, or nil when kind is empty and each replica
+// serves only its own updates. The only kind is "nats", which reuses bus when
+// it is a NATS bus.
+func NewWebSocketFanout(kind string, bus ports.MessageBus, natsURL string) (ports.MessageBus, error) {
+	switch strings.ToLower(strings.TrimSpace(kind)) {
+	case "":
+		return nil, nil
+	case TransportNATS:
+		if _, isNATS := bus.(*NATSBus); isNATS {
+			return bus, nil
+		}
+		return NewNATSBus(infrastructure.ConnectNATS(natsURL)), nil
+	default:
+		return nil, fmt.Errorf("unknown WebSocket fan-out %q, expected %s", kind, TransportNATS)
+	}
+}
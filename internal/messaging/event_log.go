@@ -0,0 +1,232 @@
+package messaging
+
+import (
+	"context"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"log"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/nats-io/nats.go/jetstream"
+	"github.com/sarika-p9/my-pipeline-project/internal/core/ports"
+	"github.com/sarika-p9/my-pipeline-project/internal/infrastructure"
+)
+
+// EventLogJetStream selects the JetStream event log in NewEventLog.
+const EventLogJetStream = "jetstream"
+
+// EventStream is the JetStream stream holding the events of every pipeline.
+const EventStream = "PIPELINE_EVENTS"
+
+const allPipelineEvents = "pipelines.*.events"
+
+// EventLogRetention bounds how much of the event log is kept. The oldest
+// events are dropped first once any limit is reached; zero means no limit.
+type EventLogRetention struct {
+	MaxAge   time.Duration
+	MaxMsgs  int64
+	MaxBytes int64
+}
+
+// DefaultEventLogRetention keeps a week of events, up to a million events or
+// 1 GiB.
+var DefaultEventLogRetention = EventLogRetention{
+	MaxAge:   7 * 24 * time.Hour,
+	MaxMsgs:  1_000_000,
+	MaxBytes: 1 << 30,
+}
+
+// JetStreamEventLog is an EventLog on a NATS JetStream stream capturing the
+// pipelines.*.events subjects. Events published there on core NATS, such as
+// by the NATS message bus, are captured too; the stream drops the copies by
+// their Nats-Msg-Id, which is the event ID.
+type JetStreamEventLog struct {
+	js jetstream.JetStream
+}
+
+var _ ports.EventLog = (*JetStreamEventLog)(nil)
+
+// NewJetStreamEventLog creates the event stream, or updates its retention.
+func NewJetStreamEventLog(ctx context.Context, conn *nats.Conn, retention EventLogRetention) (*JetStreamEventLog, error) {
+	js, err := jetstream.New(conn)
+	if err != nil {
+		return nil, err
+	}
+
+	_, err = js.CreateOrUpdateStream(ctx, jetstream.StreamConfig{
+		Name:        EventStream,
+		Description: "Events of every pipeline, run and stage",
+		Subjects:    []string{allPipelineEvents},
+		Retention:   jetstream.LimitsPolicy,
+		Discard:     jetstream.DiscardOld,
+		MaxAge:      retention.MaxAge,
+		MaxMsgs:     limitOrUnlimited(retention.MaxMsgs),
+		MaxBytes:    limitOrUnlimited(retention.MaxBytes),
+		Storage:     jetstream.FileStorage,
+		Duplicates:  2 * time.Minute,
+	})
+	if err != nil {
+		return nil, fmt.Errorf("failed to set up stream %s: %w", EventStream, err)
+	}
+	return &JetStreamEventLog{js: js}, nil
+}
+
+// OpenJetStreamEventLog reads and appends to the event stream that a backend
+// has set up, leaving its configuration alone.
+func OpenJetStreamEventLog(conn *nats.Conn) (*JetStreamEventLog, error) {
+	js, err := jetstream.New(conn)
+	if err != nil {
+		return nil, err
+	}
+	return &JetStreamEventLog{js: js}, nil
+}
+
+// NewEventLog connects the event log of the given kind. It returns nil when
+// kind is empty, since the event log is optional.
+func NewEventLog(ctx context.Context, kind string, natsURL string, retention EventLogRetention) (ports.EventLog, error) {
+	switch kind {
+	case "":
+		return nil, nil
+	case EventLogJetStream:
+		eventLog, err := NewJetStreamEventLog(ctx, infrastructure.ConnectNATS(natsURL), retention)
+		if err != nil {
+			return nil, err
+		}
+		return eventLog, nil
+	default:
+		return nil, fmt.Errorf("unknown event log %q, expected %s", kind, EventLogJetStream)
+	}
+}
+
+func (l *JetStreamEventLog) Append(ctx context.Context, pipelineID uuid.UUID, event ports.Event) (uint64, error) {
+	data, err := json.Marshal(event)
+	if err != nil {
+		return 0, err
+	}
+
+	m := nats.NewMsg(ports.PipelineEventsTopic(pipelineID))
+	m.Data = data
+	m.Header.Set(nats.MsgIdHdr, event.ID)
+	ack, err := l.js.PublishMsg(ctx, m)
+	if err != nil {
+		return 0, err
+	}
+	return ack.Sequence, nil
+}
+
+func (l *JetStreamEventLog) Read(ctx context.Context, query ports.EventLogQuery, handler ports.EventLogHandler) error {
+	config := jetstream.OrderedConsumerConfig{FilterSubjects: []string{eventSubject(query)}}
+	config.DeliverPolicy, config.OptStartSeq, config.OptStartTime = deliverFrom(query)
+	consumer, err := l.js.OrderedConsumer(ctx, EventStream, config)
+	if err != nil {
+		return err
+	}
+
+	for ctx.Err() == nil {
+		batch, err := consumer.Fetch(100, jetstream.FetchMaxWait(time.Second))
+		if err != nil {
+			return err
+		}
+
+		caughtUp := true
+		for msg := range batch.Messages() {
+			event, err := loggedEvent(msg)
+			if err != nil {
+				return err
+			}
+			if err := handler(ctx, event); err != nil {
+				return err
+			}
+			metadata, _ := msg.Metadata()
+			caughtUp = metadata != nil && metadata.NumPending == 0
+		}
+		if err := batch.Error(); err != nil && !errors.Is(err, nats.ErrTimeout) {
+			return err
+		}
+		if caughtUp && !query.Follow {
+			return nil
+		}
+	}
+	return ctx.Err()
+}
+
+func (l *JetStreamEventLog) Consume(ctx context.Context, name string, query ports.EventLogQuery, handler ports.EventLogHandler) error {
+	consumer, err := l.js.Consumer(ctx, EventStream, name)
+	if errors.Is(err, jetstream.ErrConsumerNotFound) {
+		config := jetstream.ConsumerConfig{
+			Durable:       name,
+			FilterSubject: eventSubject(query),
+			AckPolicy:     jetstream.AckExplicitPolicy,
+			AckWait:       30 * time.Second,
+		}
+		config.DeliverPolicy, config.OptStartSeq, config.OptStartTime = deliverFrom(query)
+		consumer, err = l.js.CreateConsumer(ctx, EventStream, config)
+	}
+	if err != nil {
+		return err
+	}
+
+	consumeContext, err := consumer.Consume(func(msg jetstream.Msg) {
+		event, err := loggedEvent(msg)
+		if err != nil {
+			log.Printf("❌ Dropping undecodable event on '%s': %v", msg.Subject(), err)
+			msg.Term()
+			return
+		}
+		if err := handler(ctx, event); err != nil {
+			log.Printf("⚠️ Handler of consumer '%s' failed on event %d, delivering it again: %v", name, event.Sequence, err)
+			msg.Nak()
+			return
+		}
+		msg.Ack()
+	})
+	if err != nil {
+		return err
+	}
+	defer consumeContext.Stop()
+
+	<-ctx.Done()
+	return ctx.Err()
+}
+
+func eventSubject(query ports.EventLogQuery) string {
+	if query.PipelineID == uuid.Nil {
+		return allPipelineEvents
+	}
+	return ports.PipelineEventsTopic(query.PipelineID)
+}
+
+func deliverFrom(query ports.EventLogQuery) (jetstream.DeliverPolicy, uint64, *time.Time) {
+	switch {
+	case query.FromSequence > 0:
+		return jetstream.DeliverByStartSequencePolicy, query.FromSequence, nil
+	case !query.FromTime.IsZero():
+		from := query.FromTime
+		return jetstream.DeliverByStartTimePolicy, 0, &from
+	default:
+		return jetstream.DeliverAllPolicy, 0, nil
+	}
+}
+
+func loggedEvent(msg jetstream.Msg) (ports.LoggedEvent, error) {
+	metadata, err := msg.Metadata()
+	if err != nil {
+		return ports.LoggedEvent{}, err
+	}
+
+	var event ports.Event
+	if err := json.Unmarshal(msg.Data(), &event); err != nil {
+		return ports.LoggedEvent{}, err
+	}
+	return ports.LoggedEvent{Sequence: metadata.Sequence.Stream, Event: event}, nil
+}
+
+func limitOrUnlimited(limit int64) int64 {
+	if limit <= 0 {
+		return -1
+	}
+	return limit
+}
@@ -0,0 +1,297 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats-server/v2/server"
+	"github.com/nats-io/nats.go"
+	"github.com/sarika-p9/my-pipeline-project/internal/core/ports"
+)
+
+// runJetStream starts an in-process NATS server with JetStream and returns a
+// connection to it. Both are shut down when the test ends.
+func runJetStream(t *testing.T) *nats.Conn {
+	t.Helper()
+
+	srv, err := server.NewServer(&server.Options{
+		Host:      "127.0.0.1",
+		Port:      -1,
+		JetStream: true,
+		StoreDir:  t.TempDir(),
+		NoLog:     true,
+		NoSigs:    true,
+	})
+	if err != nil {
+		t.Fatalf("failed to create NATS server: %v", err)
+	}
+	go srv.Start()
+	if !srv.ReadyForConnections(5 * time.Second) {
+		t.Fatal("NATS server did not start")
+	}
+	t.Cleanup(srv.Shutdown)
+
+	conn, err := nats.Connect(srv.ClientURL())
+	if err != nil {
+		t.Fatalf("failed to connect to NATS: %v", err)
+	}
+	t.Cleanup(conn.Close)
+	return conn
+}
+
+func newTestEventLog(t *testing.T) *JetStreamEventLog {
+	t.Helper()
+	eventLog, err := NewJetStreamEventLog(context.Background(), runJetStream(t), DefaultEventLogRetention)
+	if err != nil {
+		t.Fatalf("NewJetStreamEventLog: %v", err)
+	}
+	return eventLog
+}
+
+func testEvent(t *testing.T, pipelineID uuid.UUID, eventType string) ports.Event {
+	t.Helper()
+	event, err := ports.NewEvent(eventType, pipelineID, "", ports.PipelineEventData{PipelineID: pipelineID})
+	if err != nil {
+		t.Fatalf("NewEvent: %v", err)
+	}
+	return event
+}
+
+func appendEvents(t *testing.T, eventLog *JetStreamEventLog, pipelineID uuid.UUID, eventTypes ...string) []uint64 {
+	t.Helper()
+	var sequences []uint64
+	for _, eventType := range eventTypes {
+		seq, err := eventLog.Append(context.Background(), pipelineID, testEvent(t, pipelineID, eventType))
+		if err != nil {
+			t.Fatalf("Append %s: %v", eventType, err)
+		}
+		sequences = append(sequences, seq)
+	}
+	return sequences
+}
+
+func readTypes(t *testing.T, eventLog *JetStreamEventLog, query ports.EventLogQuery) []string {
+	t.Helper()
+	ctx, cancel := context.WithTimeout(context.Background(), 10*time.Second)
+	defer cancel()
+
+	var types []string
+	err := eventLog.Read(ctx, query, func(ctx context.Context, event ports.LoggedEvent) error {
+		types = append(types, event.Event.Type)
+		return nil
+	})
+	if err != nil {
+		t.Fatalf("Read: %v", err)
+	}
+	return types
+}
+
+func equalTypes(got, want []string) bool {
+	if len(got) != len(want) {
+		return false
+	}
+	for i := range got {
+		if got[i] != want[i] {
+			return false
+		}
+	}
+	return true
+}
+
+func TestJetStreamEventLogAppend(t *testing.T) {
+	eventLog := newTestEventLog(t)
+	pipelineID := uuid.New()
+
+	sequences := appendEvents(t, eventLog, pipelineID, ports.EventPipelineCreated, ports.EventRunStarted)
+	if sequences[0] == 0 || sequences[1] <= sequences[0] {
+		t.Fatalf("sequences = %v, want increasing and non-zero", sequences)
+	}
+
+	// Appending an event ID again is dropped as a duplicate.
+	event := testEvent(t, pipelineID, ports.EventRunCompleted)
+	first, err := eventLog.Append(context.Background(), pipelineID, event)
+	if err != nil {
+		t.Fatalf("Append: %v", err)
+	}
+	again, err := eventLog.Append(context.Background(), pipelineID, event)
+	if err != nil {
+		t.Fatalf("Append duplicate: %v", err)
+	}
+	if again != first {
+		t.Errorf("duplicate got sequence %d, want %d", again, first)
+	}
+
+	got := readTypes(t, eventLog, ports.EventLogQuery{PipelineID: pipelineID})
+	if want := []string{ports.EventPipelineCreated, ports.EventRunStarted, ports.EventRunCompleted}; !equalTypes(got, want) {
+		t.Errorf("read %v, want %v", got, want)
+	}
+}
+
+func TestJetStreamEventLogRead(t *testing.T) {
+	eventLog := newTestEventLog(t)
+	first, second := uuid.New(), uuid.New()
+
+	appendEvents(t, eventLog, first, ports.EventPipelineCreated)
+	appendEvents(t, eventLog, second, ports.EventPipelineCreated)
+	sequences := appendEvents(t, eventLog, first, ports.EventRunStarted, ports.EventRunCompleted)
+
+	tests := []struct {
+		name  string
+		query ports.EventLogQuery
+		want  []string
+	}{
+		{"one pipeline", ports.EventLogQuery{PipelineID: first}, []string{ports.EventPipelineCreated, ports.EventRunStarted, ports.EventRunCompleted}},
+		{"every pipeline", ports.EventLogQuery{}, []string{ports.EventPipelineCreated, ports.EventPipelineCreated, ports.EventRunStarted, ports.EventRunCompleted}},
+		{"from sequence", ports.EventLogQuery{PipelineID: first, FromSequence: sequences[1]}, []string{ports.EventRunCompleted}},
+		{"from time", ports.EventLogQuery{FromTime: time.Now().Add(time.Hour)}, nil},
+		{"no events", ports.EventLogQuery{PipelineID: uuid.New()}, nil},
+	}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := readTypes(t, eventLog, tt.query); !equalTypes(got, tt.want) {
+				t.Errorf("read %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestJetStreamEventLogReadStopsOnHandlerError(t *testing.T) {
+	eventLog := newTestEventLog(t)
+	pipelineID := uuid.New()
+	appendEvents(t, eventLog, pipelineID, ports.EventPipelineCreated, ports.EventRunStarted)
+
+	errStop := errors.New("stop")
+	calls := 0
+	err := eventLog.Read(context.Background(), ports.EventLogQuery{PipelineID: pipelineID}, func(ctx context.Context, event ports.LoggedEvent) error {
+		calls++
+		return errStop
+	})
+	if !errors.Is(err, errStop) || calls != 1 {
+		t.Errorf("Read returned %v after %d calls, want %v after 1", err, calls, errStop)
+	}
+}
+
+// consumed collects the events a Consume handler accepts.
+type consumed struct {
+	mu     sync.Mutex
+	types  []string
+	notify chan struct{}
+}
+
+func newConsumed() *consumed {
+	return &consumed{notify: make(chan struct{}, 100)}
+}
+
+func (c *consumed) add(eventType string) {
+	c.mu.Lock()
+	c.types = append(c.types, eventType)
+	c.mu.Unlock()
+	c.notify <- struct{}{}
+}
+
+func (c *consumed) waitFor(t *testing.T, count int) []string {
+	t.Helper()
+	timeout := time.After(10 * time.Second)
+	for {
+		c.mu.Lock()
+		types := append([]string(nil), c.types...)
+		c.mu.Unlock()
+		if len(types) >= count {
+			return types
+		}
+		select {
+		case <-c.notify:
+		case <-timeout:
+			t.Fatalf("consumed %v, want %d events", types, count)
+		}
+	}
+}
+
+// consume runs Consume in the background until the returned stop is called.
+func consume(t *testing.T, eventLog *JetStreamEventLog, name string, query ports.EventLogQuery, handler ports.EventLogHandler) (stop func()) {
+	t.Helper()
+	ctx, cancel := context.WithCancel(context.Background())
+	done := make(chan error, 1)
+	go func() { done <- eventLog.Consume(ctx, name, query, handler) }()
+	return func() {
+		cancel()
+		if err := <-done; !errors.Is(err, context.Canceled) {
+			t.Errorf("Consume returned %v, want %v", err, context.Canceled)
+		}
+	}
+}
+
+func TestJetStreamEventLogConsumeResumes(t *testing.T) {
+	eventLog := newTestEventLog(t)
+	pipelineID := uuid.New()
+	query := ports.EventLogQuery{PipelineID: pipelineID}
+	appendEvents(t, eventLog, pipelineID, ports.EventPipelineCreated, ports.EventRunStarted)
+
+	first := newConsumed()
+	stop := consume(t, eventLog, "test-consumer", query, func(ctx context.Context, event ports.LoggedEvent) error {
+		first.add(event.Event.Type)
+		return nil
+	})
+	if got, want := first.waitFor(t, 2), []string{ports.EventPipelineCreated, ports.EventRunStarted}; !equalTypes(got, want) {
+		t.Fatalf("consumed %v, want %v", got, want)
+	}
+	stop()
+
+	// Events appended while no one consumes are picked up after a restart,
+	// without the ones already accepted.
+	completed := appendEvents(t, eventLog, pipelineID, ports.EventRunCompleted)
+	second := newConsumed()
+	stop = consume(t, eventLog, "test-consumer", query, func(ctx context.Context, event ports.LoggedEvent) error {
+		second.add(event.Event.Type)
+		return nil
+	})
+	defer stop()
+	if got, want := second.waitFor(t, 1), []string{ports.EventRunCompleted}; !equalTypes(got, want) {
+		t.Fatalf("consumed %v after restarting, want %v", got, want)
+	}
+
+	// Other names start from the query.
+	other := newConsumed()
+	stopOther := consume(t, eventLog, "other-consumer", ports.EventLogQuery{PipelineID: pipelineID, FromSequence: completed[0]}, func(ctx context.Context, event ports.LoggedEvent) error {
+		other.add(event.Event.Type)
+		return nil
+	})
+	defer stopOther()
+	if got, want := other.waitFor(t, 1), []string{ports.EventRunCompleted}; !equalTypes(got, want) {
+		t.Fatalf("new consumer consumed %v, want %v", got, want)
+	}
+}
+
+func TestJetStreamEventLogConsumeRedeliversFailedEvents(t *testing.T) {
+	eventLog := newTestEventLog(t)
+	pipelineID := uuid.New()
+	appendEvents(t, eventLog, pipelineID, ports.EventPipelineCreated)
+
+	accepted := newConsumed()
+	var mu sync.Mutex
+	failures := 0
+	stop := consume(t, eventLog, "flaky-consumer", ports.EventLogQuery{PipelineID: pipelineID}, func(ctx context.Context, event ports.LoggedEvent) error {
+		mu.Lock()
+		defer mu.Unlock()
+		if failures < 2 {
+			failures++
+			return errors.New("not yet")
+		}
+		accepted.add(event.Event.Type)
+		return nil
+	})
+	defer stop()
+
+	if got, want := accepted.waitFor(t, 1), []string{ports.EventPipelineCreated}; !equalTypes(got, want) {
+		t.Fatalf("consumed %v, want %v", got, want)
+	}
+	mu.Lock()
+	defer mu.Unlock()
+	if failures != 2 {
+		t.Errorf("handler failed %d times, want 2", failures)
+	}
+}
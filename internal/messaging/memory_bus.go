@@ -0,0 +1,264 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sarika-p9/my-pipeline-project/internal/core/ports"
+)
+
+// ErrBusClosed is returned by a MessageBus that has been closed.
+var ErrBusClosed = errors.New("message bus closed")
+
+const (
+	memorySubscriptionBuffer = 256
+	memoryQueueBuffer        = 1024
+)
+
+// MemoryBus is a MessageBus inside one process, built on channels. It needs
+// no broker, so the whole system can run, and be tested, in a single process.
+// Messages and dead letters are lost when the process stops.
+type MemoryBus struct {
+	RetryDelays []time.Duration
+
+	mu          sync.Mutex
+	subscribers map[string]map[*memorySubscription]struct{}
+	queues      map[string]chan ports.Message
+	deadLetters map[string][]ports.DeadLetter
+	closed      chan struct{}
+	closeOnce   sync.Once
+}
+
+var (
+	_ ports.MessageBus      = (*MemoryBus)(nil)
+	_ ports.DeadLetterStore = (*MemoryBus)(nil)
+)
+
+func NewMemoryBus() *MemoryBus {
+	return &MemoryBus{
+		RetryDelays: DefaultRetryDelays,
+		subscribers: make(map[string]map[*memorySubscription]struct{}),
+		queues:      make(map[string]chan ports.Message),
+		deadLetters: make(map[string][]ports.DeadLetter),
+		closed:      make(chan struct{}),
+	}
+}
+
+type memorySubscription struct {
+	bus      *MemoryBus
+	topic    string
+	messages chan ports.Message
+	done     chan struct{}
+	once     sync.Once
+}
+
+func (s *memorySubscription) Unsubscribe() error {
+	s.bus.mu.Lock()
+	delete(s.bus.subscribers[s.topic], s)
+	s.bus.mu.Unlock()
+	s.once.Do(func() { close(s.done) })
+	return nil
+}
+
+// Publish hands the message to every subscriber of the topic. A subscriber
+// that has fallen memorySubscriptionBuffer messages behind misses it.
+func (b *MemoryBus) Publish(ctx context.Context, topic string, msg ports.Message) error {
+	if b.isClosed() {
+		return ErrBusClosed
+	}
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	for sub := range b.subscribers[topic] {
+		select {
+		case sub.messages <- msg:
+		default:
+			log.Printf("⚠️ Subscriber of '%s' is too slow, dropping message", topic)
+		}
+	}
+	return nil
+}
+
+func (b *MemoryBus) Subscribe(topic string, handler ports.MessageHandler) (ports.Subscription, error) {
+	if b.isClosed() {
+		return nil, ErrBusClosed
+	}
+
+	sub := &memorySubscription{
+		bus:      b,
+		topic:    topic,
+		messages: make(chan ports.Message, memorySubscriptionBuffer),
+		done:     make(chan struct{}),
+	}
+	b.mu.Lock()
+	if b.subscribers[topic] == nil {
+		b.subscribers[topic] = make(map[*memorySubscription]struct{})
+	}
+	b.subscribers[topic][sub] = struct{}{}
+	b.mu.Unlock()
+
+	go func() {
+		for {
+			select {
+			case msg := <-sub.messages:
+				if err := handler(context.Background(), msg); err != nil {
+					log.Printf("❌ Handler for '%s' failed: %v", topic, err)
+				}
+			case <-sub.done:
+				return
+			case <-b.closed:
+				return
+			}
+		}
+	}()
+	return sub, nil
+}
+
+// Enqueue adds the message to the queue, waiting while the queue is full.
+func (b *MemoryBus) Enqueue(ctx context.Context, queue string, msg ports.Message) error {
+	if b.isClosed() {
+		return ErrBusClosed
+	}
+	if msg.ID == "" {
+		msg.ID = uuid.NewString()
+	}
+
+	select {
+	case b.queue(queue) <- msg:
+		return nil
+	case <-ctx.Done():
+		return ctx.Err()
+	case <-b.closed:
+		return ErrBusClosed
+	}
+}
+
+func (b *MemoryBus) Consume(ctx context.Context, queue string, concurrency int, handler ports.MessageHandler) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+	messages := b.queue(queue)
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case msg := <-messages:
+					if err := handler(ctx, msg); err != nil {
+						b.retryOrDeadLetter(queue, msg, err)
+					}
+				case <-ctx.Done():
+					return
+				case <-b.closed:
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return ErrBusClosed
+}
+
+func (b *MemoryBus) retryOrDeadLetter(queue string, msg ports.Message, err error) {
+	delay, retry := nextRetry(b.RetryDelays, msg, err)
+	if !retry {
+		log.Printf("❌ Dead-lettering message %s on queue '%s' after %d retries: %v", msg.ID, queue, msg.Retries, err)
+		b.mu.Lock()
+		b.deadLetters[queue] = append(b.deadLetters[queue], ports.DeadLetter{
+			Queue:   queue,
+			Message: msg,
+			Reason:  err.Error(),
+			DeadAt:  time.Now(),
+		})
+		b.mu.Unlock()
+		return
+	}
+
+	log.Printf("⚠️ Handler for queue '%s' failed, retrying message %s in %s: %v", queue, msg.ID, delay, err)
+	msg.Retries++
+	time.AfterFunc(delay, func() {
+		if err := b.Enqueue(context.Background(), queue, msg); err != nil {
+			log.Printf("❌ Failed to retry message %s on queue '%s': %v", msg.ID, queue, err)
+		}
+	})
+}
+
+func (b *MemoryBus) DeadLetters(ctx context.Context, queue string) ([]ports.DeadLetter, error) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return append([]ports.DeadLetter(nil), b.deadLetters[queue]...), nil
+}
+
+// ReplayDeadLetters enqueues the selected dead letters again with their
+// retries reset.
+func (b *MemoryBus) ReplayDeadLetters(ctx context.Context, queue string, ids []string) (int, error) {
+	replayed := b.takeDeadLetters(queue, ids)
+	for i, deadLetter := range replayed {
+		msg := deadLetter.Message
+		msg.Retries = 0
+		if err := b.Enqueue(ctx, queue, msg); err != nil {
+			b.mu.Lock()
+			b.deadLetters[queue] = append(b.deadLetters[queue], replayed[i:]...)
+			b.mu.Unlock()
+			return i, err
+		}
+	}
+	return len(replayed), nil
+}
+
+func (b *MemoryBus) PurgeDeadLetters(ctx context.Context, queue string, ids []string) (int, error) {
+	return len(b.takeDeadLetters(queue, ids)), nil
+}
+
+func (b *MemoryBus) takeDeadLetters(queue string, ids []string) []ports.DeadLetter {
+	selected := selectIDs(ids)
+
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	var taken, kept []ports.DeadLetter
+	for _, deadLetter := range b.deadLetters[queue] {
+		if selected(deadLetter.Message.ID) {
+			taken = append(taken, deadLetter)
+		} else {
+			kept = append(kept, deadLetter)
+		}
+	}
+	b.deadLetters[queue] = kept
+	return taken
+}
+
+func (b *MemoryBus) Close() error {
+	b.closeOnce.Do(func() { close(b.closed) })
+	return nil
+}
+
+func (b *MemoryBus) queue(name string) chan ports.Message {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	queue, ok := b.queues[name]
+	if !ok {
+		queue = make(chan ports.Message, memoryQueueBuffer)
+		b.queues[name] = queue
+	}
+	return queue
+}
+
+func (b *MemoryBus) isClosed() bool {
+	select {
+	case <-b.closed:
+		return true
+	default:
+		return false
+	}
+}
@@ -0,0 +1,260 @@
+package messaging
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"sync"
+	"testing"
+	"time"
+
+	"github.com/sarika-p9/my-pipeline-project/internal/core/ports"
+)
+
+// received collects the messages handed to a handler.
+type received struct {
+	mu       sync.Mutex
+	messages []ports.Message
+	notify   chan struct{}
+}
+
+func newReceived() *received {
+	return &received{notify: make(chan struct{}, 1024)}
+}
+
+func (r *received) handler(ctx context.Context, msg ports.Message) error {
+	r.mu.Lock()
+	r.messages = append(r.messages, msg)
+	r.mu.Unlock()
+	r.notify <- struct{}{}
+	return nil
+}
+
+func (r *received) waitFor(t *testing.T, count int) []ports.Message {
+	t.Helper()
+	timeout := time.After(5 * time.Second)
+	for {
+		r.mu.Lock()
+		messages := append([]ports.Message(nil), r.messages...)
+		r.mu.Unlock()
+		if len(messages) >= count {
+			return messages
+		}
+		select {
+		case <-r.notify:
+		case <-timeout:
+			t.Fatalf("received %d messages, want %d", len(messages), count)
+		}
+	}
+}
+
+func (r *received) count() int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+	return len(r.messages)
+}
+
+func TestMemoryBusPublishReachesEverySubscriber(t *testing.T) {
+	bus := NewMemoryBus()
+	defer bus.Close()
+
+	first, second, other := newReceived(), newReceived(), newReceived()
+	subscription, err := bus.Subscribe("updates", first.handler)
+	if err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if _, err := bus.Subscribe("updates", second.handler); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+	if _, err := bus.Subscribe("other", other.handler); err != nil {
+		t.Fatalf("Subscribe: %v", err)
+	}
+
+	if err := bus.Publish(context.Background(), "updates", ports.Message{Data: []byte("one")}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	for _, r := range []*received{first, second} {
+		if got := string(r.waitFor(t, 1)[0].Data); got != "one" {
+			t.Errorf("received %q, want %q", got, "one")
+		}
+	}
+
+	// Unsubscribed handlers get nothing more.
+	if err := subscription.Unsubscribe(); err != nil {
+		t.Fatalf("Unsubscribe: %v", err)
+	}
+	if err := bus.Publish(context.Background(), "updates", ports.Message{Data: []byte("two")}); err != nil {
+		t.Fatalf("Publish: %v", err)
+	}
+	second.waitFor(t, 2)
+	if first.count() != 1 {
+		t.Errorf("unsubscribed handler received %d messages, want 1", first.count())
+	}
+	if other.count() != 0 {
+		t.Errorf("subscriber of another topic received %d messages, want 0", other.count())
+	}
+}
+
+func TestMemoryBusQueueDeliversEachMessageOnce(t *testing.T) {
+	bus := NewMemoryBus()
+	defer bus.Close()
+
+	const messages = 50
+	for i := 0; i < messages; i++ {
+		if err := bus.Enqueue(context.Background(), "tasks", ports.Message{Data: []byte(fmt.Sprint(i))}); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+
+	ctx, cancel := context.WithCancel(context.Background())
+	r := newReceived()
+	done := make(chan error, 1)
+	go func() { done <- bus.Consume(ctx, "tasks", 4, r.handler) }()
+
+	seen := make(map[string]bool)
+	for _, msg := range r.waitFor(t, messages) {
+		if msg.ID == "" {
+			t.Error("queued message has no ID")
+		}
+		if seen[string(msg.Data)] {
+			t.Errorf("message %s delivered twice", msg.Data)
+		}
+		seen[string(msg.Data)] = true
+	}
+
+	cancel()
+	if err := <-done; !errors.Is(err, context.Canceled) {
+		t.Errorf("Consume returned %v, want %v", err, context.Canceled)
+	}
+}
+
+func TestMemoryBusRetriesThenDeadLetters(t *testing.T) {
+	bus := NewMemoryBus()
+	bus.RetryDelays = []time.Duration{time.Millisecond, time.Millisecond}
+	defer bus.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	attempts := make(map[string]int)
+	go bus.Consume(ctx, "tasks", 1, func(ctx context.Context, msg ports.Message) error {
+		mu.Lock()
+		defer mu.Unlock()
+		attempts[string(msg.Data)]++
+		if string(msg.Data) == "rejected" {
+			return fmt.Errorf("%w: bad payload", ports.ErrRejectMessage)
+		}
+		return errors.New("always fails")
+	})
+
+	for _, data := range []string{"failing", "rejected"} {
+		if err := bus.Enqueue(ctx, "tasks", ports.Message{Data: []byte(data)}); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+
+	deadLetters := waitForDeadLetters(t, bus, "tasks", 2)
+	mu.Lock()
+	if attempts["failing"] != 3 || attempts["rejected"] != 1 {
+		t.Errorf("attempts = %v, want failing 3 times and rejected once", attempts)
+	}
+	mu.Unlock()
+	for _, deadLetter := range deadLetters {
+		if deadLetter.Reason == "" || deadLetter.Queue != "tasks" {
+			t.Errorf("dead letter %+v has no reason or the wrong queue", deadLetter)
+		}
+	}
+}
+
+func TestMemoryBusReplayAndPurgeDeadLetters(t *testing.T) {
+	bus := NewMemoryBus()
+	bus.RetryDelays = nil
+	defer bus.Close()
+
+	ctx, cancel := context.WithCancel(context.Background())
+	defer cancel()
+
+	var mu sync.Mutex
+	fail := true
+	r := newReceived()
+	go bus.Consume(ctx, "tasks", 1, func(ctx context.Context, msg ports.Message) error {
+		mu.Lock()
+		failing := fail
+		mu.Unlock()
+		if failing {
+			return errors.New("not yet")
+		}
+		return r.handler(ctx, msg)
+	})
+
+	for _, data := range []string{"replayed", "purged"} {
+		if err := bus.Enqueue(ctx, "tasks", ports.Message{Data: []byte(data)}); err != nil {
+			t.Fatalf("Enqueue: %v", err)
+		}
+	}
+	deadLetters := waitForDeadLetters(t, bus, "tasks", 2)
+	ids := make(map[string]string)
+	for _, deadLetter := range deadLetters {
+		ids[string(deadLetter.Message.Data)] = deadLetter.Message.ID
+	}
+
+	mu.Lock()
+	fail = false
+	mu.Unlock()
+
+	purged, err := bus.PurgeDeadLetters(ctx, "tasks", []string{ids["purged"]})
+	if err != nil || purged != 1 {
+		t.Fatalf("PurgeDeadLetters = %d, %v, want 1", purged, err)
+	}
+	replayed, err := bus.ReplayDeadLetters(ctx, "tasks", nil)
+	if err != nil || replayed != 1 {
+		t.Fatalf("ReplayDeadLetters = %d, %v, want 1", replayed, err)
+	}
+
+	msg := r.waitFor(t, 1)[0]
+	if string(msg.Data) != "replayed" || msg.Retries != 0 {
+		t.Errorf("replayed %q with %d retries, want %q with 0", msg.Data, msg.Retries, "replayed")
+	}
+	if left, _ := bus.DeadLetters(ctx, "tasks"); len(left) != 0 {
+		t.Errorf("%d dead letters left, want 0", len(left))
+	}
+}
+
+func TestMemoryBusClose(t *testing.T) {
+	bus := NewMemoryBus()
+
+	done := make(chan error, 1)
+	go func() {
+		done <- bus.Consume(context.Background(), "tasks", 1, func(ctx context.Context, msg ports.Message) error { return nil })
+	}()
+	bus.Close()
+
+	if err := <-done; !errors.Is(err, ErrBusClosed) {
+		t.Errorf("Consume returned %v, want %v", err, ErrBusClosed)
+	}
+	if err := bus.Publish(context.Background(), "updates", ports.Message{}); !errors.Is(err, ErrBusClosed) {
+		t.Errorf("Publish returned %v, want %v", err, ErrBusClosed)
+	}
+	if err := bus.Enqueue(context.Background(), "tasks", ports.Message{}); !errors.Is(err, ErrBusClosed) {
+		t.Errorf("Enqueue returned %v, want %v", err, ErrBusClosed)
+	}
+}
+
+func waitForDeadLetters(t *testing.T, bus *MemoryBus, queue string, count int) []ports.DeadLetter {
+	t.Helper()
+	deadline := time.Now().Add(5 * time.Second)
+	for {
+		deadLetters, err := bus.DeadLetters(context.Background(), queue)
+		if err != nil {
+			t.Fatalf("DeadLetters: %v", err)
+		}
+		if len(deadLetters) >= count {
+			return deadLetters
+		}
+		if time.Now().After(deadline) {
+			t.Fatalf("%d dead letters on %s, want %d", len(deadLetters), queue, count)
+		}
+		time.Sleep(10 * time.Millisecond)
+	}
+}
@@ -0,0 +1,161 @@
+package messaging
+
+import (
+	"context"
+	"log"
+	"strconv"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/nats-io/nats.go"
+	"github.com/sarika-p9/my-pipeline-project/internal/core/ports"
+)
+
+const (
+	natsCorrelationHeader = "Correlation-Id"
+	natsMessageIDHeader   = nats.MsgIdHdr // Lets JetStream drop duplicates
+	natsRetryCountHeader  = "Retry-Count"
+	natsErrorHeader       = "Error"
+)
+
+// NATSBus is a MessageBus on core NATS. Topics and queues are both subjects;
+// the consumers of a queue form a queue group named after it. Core NATS keeps
+// nothing, so a queued message published while no consumer is listening is
+// lost, and a failed message is retried by publishing it anew after the
+// delay. Dead letters are published on the subject <queue>.dead for whoever
+// listens there; the bus does not keep them.
+type NATSBus struct {
+	RetryDelays []time.Duration
+
+	conn *nats.Conn
+}
+
+var _ ports.MessageBus = (*NATSBus)(nil)
+
+func NewNATSBus(conn *nats.Conn) *NATSBus {
+	return &NATSBus{RetryDelays: DefaultRetryDelays, conn: conn}
+}
+
+func (b *NATSBus) Publish(ctx context.Context, topic string, msg ports.Message) error {
+	return b.conn.PublishMsg(natsMsg(topic, msg))
+}
+
+func (b *NATSBus) Subscribe(topic string, handler ports.MessageHandler) (ports.Subscription, error) {
+	return b.conn.Subscribe(topic, func(m *nats.Msg) {
+		if err := handler(context.Background(), natsMessage(m)); err != nil {
+			log.Printf("❌ Handler for '%s' failed: %v", topic, err)
+		}
+	})
+}
+
+func (b *NATSBus) Enqueue(ctx context.Context, queue string, msg ports.Message) error {
+	if msg.ID == "" {
+		msg.ID = uuid.NewString()
+	}
+	return b.conn.PublishMsg(natsMsg(queue, msg))
+}
+
+func (b *NATSBus) Consume(ctx context.Context, queue string, concurrency int, handler ports.MessageHandler) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	consumeCtx, stop := context.WithCancel(ctx)
+	defer stop()
+	closed := b.conn.StatusChanged(nats.CLOSED)
+	if b.conn.IsClosed() {
+		return ErrBusClosed
+	}
+
+	messages := make(chan *nats.Msg, concurrency)
+	sub, err := b.conn.ChanQueueSubscribe(queue, queue, messages)
+	if err != nil {
+		return err
+	}
+	defer sub.Unsubscribe()
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case m := <-messages:
+					msg := natsMessage(m)
+					if err := handler(consumeCtx, msg); err != nil {
+						b.retryOrDeadLetter(queue, msg, err)
+					}
+				case <-consumeCtx.Done():
+					return
+				}
+			}
+		}()
+	}
+
+	select {
+	case <-ctx.Done():
+	case <-closed:
+	}
+	stop()
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return ErrBusClosed
+}
+
+func (b *NATSBus) retryOrDeadLetter(queue string, msg ports.Message, err error) {
+	delay, retry := nextRetry(b.RetryDelays, msg, err)
+	if !retry {
+		log.Printf("❌ Dead-lettering message %s on queue '%s' after %d retries: %v", msg.ID, queue, msg.Retries, err)
+		m := natsMsg(queue+".dead", msg)
+		m.Header.Set(natsErrorHeader, err.Error())
+		if err := b.conn.PublishMsg(m); err != nil {
+			log.Printf("❌ Failed to dead-letter message %s on queue '%s': %v", msg.ID, queue, err)
+		}
+		return
+	}
+
+	log.Printf("⚠️ Handler for queue '%s' failed, retrying message %s in %s: %v", queue, msg.ID, delay, err)
+	msg.Retries++
+	time.AfterFunc(delay, func() {
+		if err := b.Enqueue(context.Background(), queue, msg); err != nil {
+			log.Printf("❌ Failed to retry message %s on queue '%s': %v", msg.ID, queue, err)
+		}
+	})
+}
+
+func (b *NATSBus) Close() error {
+	b.conn.Close()
+	return nil
+}
+
+func natsMsg(subject string, msg ports.Message) *nats.Msg {
+	m := nats.NewMsg(subject)
+	m.Data = msg.Data
+	m.Reply = msg.ReplyTo
+	if msg.CorrelationID != "" {
+		m.Header.Set(natsCorrelationHeader, msg.CorrelationID)
+	}
+	if msg.ID != "" {
+		m.Header.Set(natsMessageIDHeader, msg.ID)
+	}
+	if msg.Retries > 0 {
+		m.Header.Set(natsRetryCountHeader, strconv.Itoa(msg.Retries))
+	}
+	return m
+}
+
+func natsMessage(m *nats.Msg) ports.Message {
+	retries, _ := strconv.Atoi(m.Header.Get(natsRetryCountHeader))
+	return ports.Message{
+		ID:            m.Header.Get(natsMessageIDHeader),
+		Data:          m.Data,
+		ReplyTo:       m.Reply,
+		CorrelationID: m.Header.Get(natsCorrelationHeader),
+		Retries:       retries,
+	}
+}
@@ -0,0 +1,412 @@
+package messaging
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"sync"
+	"time"
+
+	"github.com/google/uuid"
+	"github.com/sarika-p9/my-pipeline-project/internal/core/ports"
+	"github.com/streadway/amqp"
+)
+
+const (
+	// RabbitMQTopicExchange is the topic exchange that Publish sends to.
+	RabbitMQTopicExchange = "pipeline.topics"
+	// RabbitMQDeadLetterExchange routes the dead letters of queue q, with
+	// routing key q, to the queue q.dead.
+	RabbitMQDeadLetterExchange = "pipeline.dlx"
+
+	rabbitMQRetryCountHeader = "x-retry-count"
+	rabbitMQErrorHeader      = "x-error"
+)
+
+// RabbitMQBus is a MessageBus on RabbitMQ. Topics are routing keys on
+// RabbitMQTopicExchange, and each Subscribe binds its own exclusive queue.
+// Queues are durable queues on the default exchange, and messages are
+// acknowledged only after their handler returns.
+//
+// Each queue q comes with a retry queue q.retry.<n> per retry delay, whose
+// message TTL sends a failed message back to q once the delay has passed,
+// and a dead-letter queue q.dead. The retry count travels in the
+// x-retry-count header and the last error in x-error.
+type RabbitMQBus struct {
+	RetryDelays []time.Duration
+
+	conn *amqp.Connection
+
+	mu       sync.Mutex // Guards channel and declared
+	channel  *amqp.Channel
+	declared map[string]bool
+}
+
+var (
+	_ ports.MessageBus      = (*RabbitMQBus)(nil)
+	_ ports.DeadLetterStore = (*RabbitMQBus)(nil)
+)
+
+// NewRabbitMQBus connects to the broker at url and declares the topic exchange.
+func NewRabbitMQBus(url string) (*RabbitMQBus, error) {
+	conn, ch, err := ConnectRabbitMQ(url)
+	if err != nil {
+		return nil, err
+	}
+
+	if err := ch.ExchangeDeclare(RabbitMQTopicExchange, amqp.ExchangeTopic, true, false, false, false, nil); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	if err := ch.ExchangeDeclare(RabbitMQDeadLetterExchange, amqp.ExchangeDirect, true, false, false, false, nil); err != nil {
+		conn.Close()
+		return nil, err
+	}
+	return &RabbitMQBus{RetryDelays: DefaultRetryDelays, conn: conn, channel: ch, declared: make(map[string]bool)}, nil
+}
+
+func (b *RabbitMQBus) Publish(ctx context.Context, topic string, msg ports.Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+	return b.channel.Publish(RabbitMQTopicExchange, topic, false, false, rabbitMQPublishing(msg, amqp.Transient))
+}
+
+func (b *RabbitMQBus) Subscribe(topic string, handler ports.MessageHandler) (ports.Subscription, error) {
+	ch, err := b.conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	queue, err := ch.QueueDeclare(
+		"",    // Name chosen by the broker
+		false, // Durable
+		true,  // Auto-delete
+		true,  // Exclusive
+		false, // No-wait
+		nil,   // Arguments
+	)
+	if err != nil {
+		ch.Close()
+		return nil, err
+	}
+	if err := ch.QueueBind(queue.Name, topic, RabbitMQTopicExchange, false, nil); err != nil {
+		ch.Close()
+		return nil, err
+	}
+
+	deliveries, err := ch.Consume(queue.Name, "", true, true, false, false, nil)
+	if err != nil {
+		ch.Close()
+		return nil, err
+	}
+
+	go func() {
+		for d := range deliveries {
+			if err := handler(context.Background(), rabbitMQMessage(d)); err != nil {
+				log.Printf("❌ Handler for '%s' failed: %v", topic, err)
+			}
+		}
+	}()
+	return &rabbitMQSubscription{channel: ch}, nil
+}
+
+type rabbitMQSubscription struct {
+	channel *amqp.Channel
+}
+
+func (s *rabbitMQSubscription) Unsubscribe() error {
+	return s.channel.Close()
+}
+
+// Enqueue declares the queue on first use and publishes a persistent message
+// to it.
+func (b *RabbitMQBus) Enqueue(ctx context.Context, queue string, msg ports.Message) error {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if err := b.declare(b.channel, queue); err != nil {
+		return err
+	}
+	if msg.ID == "" {
+		msg.ID = uuid.NewString()
+	}
+	return b.channel.Publish("", queue, false, false, rabbitMQPublishing(msg, amqp.Persistent))
+}
+
+// declare declares the queue with its retry and dead-letter queues, once per
+// bus. Callers hold b.mu.
+func (b *RabbitMQBus) declare(ch *amqp.Channel, queue string) error {
+	if b.declared[queue] {
+		return nil
+	}
+	if err := declareTaskQueue(ch, queue, b.RetryDelays); err != nil {
+		return err
+	}
+	b.declared[queue] = true
+	return nil
+}
+
+func (b *RabbitMQBus) Consume(ctx context.Context, queue string, concurrency int, handler ports.MessageHandler) error {
+	if concurrency < 1 {
+		concurrency = 1
+	}
+
+	ch, err := b.conn.Channel()
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	b.mu.Lock()
+	err = b.declare(ch, queue)
+	b.mu.Unlock()
+	if err != nil {
+		return err
+	}
+	if err := ch.Qos(concurrency, 0, false); err != nil {
+		return err
+	}
+	deliveries, err := ch.Consume(queue, "", false, false, false, false, nil)
+	if err != nil {
+		return err
+	}
+
+	var wg sync.WaitGroup
+	for i := 0; i < concurrency; i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for {
+				select {
+				case d, ok := <-deliveries:
+					if !ok {
+						return
+					}
+					msg := rabbitMQMessage(d)
+					if err := handler(ctx, msg); err != nil {
+						b.retryOrDeadLetter(ch, queue, msg, d, err)
+						continue
+					}
+					d.Ack(false)
+				case <-ctx.Done():
+					return
+				}
+			}
+		}()
+	}
+	wg.Wait()
+
+	if ctx.Err() != nil {
+		return ctx.Err()
+	}
+	return ErrBusClosed
+}
+
+// retryOrDeadLetter moves a failed delivery to its next retry queue, or to the
+// dead-letter exchange, and acknowledges it. When that publish fails the
+// delivery is rejected, which the queue's own dead-letter arguments route to
+// the dead-letter queue without a reason.
+func (b *RabbitMQBus) retryOrDeadLetter(ch *amqp.Channel, queue string, msg ports.Message, d amqp.Delivery, err error) {
+	publishing := rabbitMQPublishing(msg, amqp.Persistent)
+	exchange, key := RabbitMQDeadLetterExchange, queue
+
+	if delay, retry := nextRetry(b.RetryDelays, msg, err); retry {
+		log.Printf("⚠️ Handler for queue '%s' failed, retrying message %s in %s: %v", queue, msg.ID, delay, err)
+		publishing.Headers[rabbitMQRetryCountHeader] = int32(msg.Retries + 1)
+		exchange, key = "", rabbitMQRetryQueue(queue, msg.Retries+1)
+	} else {
+		log.Printf("❌ Dead-lettering message %s on queue '%s' after %d retries: %v", msg.ID, queue, msg.Retries, err)
+		publishing.Headers[rabbitMQErrorHeader] = err.Error()
+		publishing.Timestamp = time.Now()
+	}
+
+	if err := ch.Publish(exchange, key, false, false, publishing); err != nil {
+		log.Printf("❌ Failed to move message %s off queue '%s': %v", msg.ID, queue, err)
+		d.Nack(false, false)
+		return
+	}
+	d.Ack(false)
+}
+
+// DeadLetters reads the dead-letter queue without acknowledging, so the
+// messages stay on it when the channel closes.
+func (b *RabbitMQBus) DeadLetters(ctx context.Context, queue string) ([]ports.DeadLetter, error) {
+	var deadLetters []ports.DeadLetter
+	err := b.walkDeadLetters(queue, func(ch *amqp.Channel, d amqp.Delivery) error {
+		deadLetters = append(deadLetters, rabbitMQDeadLetter(queue, d))
+		return nil
+	})
+	return deadLetters, err
+}
+
+// ReplayDeadLetters sends the selected dead letters back to the queue with
+// their retry count reset.
+func (b *RabbitMQBus) ReplayDeadLetters(ctx context.Context, queue string, ids []string) (int, error) {
+	selected := selectIDs(ids)
+	replayed := 0
+	err := b.walkDeadLetters(queue, func(ch *amqp.Channel, d amqp.Delivery) error {
+		msg := rabbitMQMessage(d)
+		if !selected(msg.ID) {
+			return nil
+		}
+		msg.Retries = 0
+		if err := ch.Publish("", queue, false, false, rabbitMQPublishing(msg, amqp.Persistent)); err != nil {
+			return err
+		}
+		replayed++
+		return d.Ack(false)
+	})
+	return replayed, err
+}
+
+func (b *RabbitMQBus) PurgeDeadLetters(ctx context.Context, queue string, ids []string) (int, error) {
+	if len(ids) == 0 {
+		ch, err := b.deadLetterChannel(queue)
+		if err != nil {
+			return 0, err
+		}
+		defer ch.Close()
+		return ch.QueuePurge(rabbitMQDeadLetterQueue(queue), false)
+	}
+
+	selected := selectIDs(ids)
+	purged := 0
+	err := b.walkDeadLetters(queue, func(ch *amqp.Channel, d amqp.Delivery) error {
+		if !selected(d.MessageId) {
+			return nil
+		}
+		purged++
+		return d.Ack(false)
+	})
+	return purged, err
+}
+
+// walkDeadLetters gets every message on the queue's dead-letter queue and
+// passes it to visit on a channel of its own. Messages visit does not
+// acknowledge go back on the queue when the channel closes.
+func (b *RabbitMQBus) walkDeadLetters(queue string, visit func(ch *amqp.Channel, d amqp.Delivery) error) error {
+	ch, err := b.deadLetterChannel(queue)
+	if err != nil {
+		return err
+	}
+	defer ch.Close()
+
+	deadQueue := rabbitMQDeadLetterQueue(queue)
+	inspected, err := ch.QueueInspect(deadQueue)
+	if err != nil {
+		return err
+	}
+	for i := 0; i < inspected.Messages; i++ {
+		d, ok, err := ch.Get(deadQueue, false)
+		if err != nil {
+			return err
+		}
+		if !ok {
+			break
+		}
+		if err := visit(ch, d); err != nil {
+			return err
+		}
+	}
+	return nil
+}
+
+func (b *RabbitMQBus) deadLetterChannel(queue string) (*amqp.Channel, error) {
+	ch, err := b.conn.Channel()
+	if err != nil {
+		return nil, err
+	}
+
+	b.mu.Lock()
+	err = b.declare(ch, queue)
+	b.mu.Unlock()
+	if err != nil {
+		ch.Close()
+		return nil, err
+	}
+	return ch, nil
+}
+
+func (b *RabbitMQBus) Close() error {
+	return b.conn.Close()
+}
+
+// declareTaskQueue declares the queue, its dead-letter queue and one retry
+// queue per delay. A queue declared before it had dead-letter arguments must
+// be deleted once, since RabbitMQ refuses to change a queue's arguments.
+func declareTaskQueue(ch *amqp.Channel, queue string, delays []time.Duration) error {
+	deadQueue := rabbitMQDeadLetterQueue(queue)
+	if _, err := declareDurableQueue(ch, deadQueue, nil); err != nil {
+		return err
+	}
+	if err := ch.QueueBind(deadQueue, queue, RabbitMQDeadLetterExchange, false, nil); err != nil {
+		return err
+	}
+
+	for i, delay := range delays {
+		_, err := declareDurableQueue(ch, rabbitMQRetryQueue(queue, i+1), amqp.Table{
+			"x-message-ttl":             delay.Milliseconds(),
+			"x-dead-letter-exchange":    "",
+			"x-dead-letter-routing-key": queue,
+		})
+		if err != nil {
+			return err
+		}
+	}
+
+	_, err := declareDurableQueue(ch, queue, amqp.Table{
+		"x-dead-letter-exchange":    RabbitMQDeadLetterExchange,
+		"x-dead-letter-routing-key": queue,
+	})
+	return err
+}
+
+func declareDurableQueue(ch *amqp.Channel, name string, args amqp.Table) (amqp.Queue, error) {
+	return ch.QueueDeclare(
+		name,
+		true,  // Durable
+		false, // Auto-delete
+		false, // Exclusive
+		false, // No-wait
+		args,
+	)
+}
+
+func rabbitMQRetryQueue(queue string, retry int) string {
+	return fmt.Sprintf("%s.retry.%d", queue, retry)
+}
+
+func rabbitMQDeadLetterQueue(queue string) string {
+	return queue + ".dead"
+}
+
+func rabbitMQPublishing(msg ports.Message, deliveryMode uint8) amqp.Publishing {
+	return amqp.Publishing{
+		ContentType:   "application/json",
+		DeliveryMode:  deliveryMode,
+		MessageId:     msg.ID,
+		CorrelationId: msg.CorrelationID,
+		ReplyTo:       msg.ReplyTo,
+		Headers:       amqp.Table{rabbitMQRetryCountHeader: int32(msg.Retries)},
+		Body:          msg.Data,
+	}
+}
+
+func rabbitMQMessage(d amqp.Delivery) ports.Message {
+	msg := ports.Message{ID: d.MessageId, Data: d.Body, ReplyTo: d.ReplyTo, CorrelationID: d.CorrelationId}
+	switch retries := d.Headers[rabbitMQRetryCountHeader].(type) {
+	case int32:
+		msg.Retries = int(retries)
+	case int64:
+		msg.Retries = int(retries)
+	}
+	return msg
+}
+
+func rabbitMQDeadLetter(queue string, d amqp.Delivery) ports.DeadLetter {
+	reason, _ := d.Headers[rabbitMQErrorHeader].(string)
+	if reason == "" {
+		reason = "rejected"
+	}
+	return ports.DeadLetter{Queue: queue, Message: rabbitMQMessage(d), Reason: reason, DeadAt: d.Timestamp}
+}
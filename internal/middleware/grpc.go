@@ -0,0 +1,100 @@
+package middleware
+
+import (
+	"context"
+	"strings"
+
+	"github.com/google/uuid"
+	authproto "github.com/sarika-p9/my-pipeline-project/api/grpc/proto/authentication"
+	"github.com/sarika-p9/my-pipeline-project/internal/infrastructure"
+	"google.golang.org/grpc"
+	"google.golang.org/grpc/codes"
+	"google.golang.org/grpc/metadata"
+	"google.golang.org/grpc/status"
+)
+
+// PublicMethods are the gRPC methods callable without a token, or with one
+// that no longer verifies.
+var PublicMethods = map[string]bool{
+	authproto.AuthService_Register_FullMethodName: true,
+	authproto.AuthService_Login_FullMethodName:    true,
+	authproto.AuthService_Logout_FullMethodName:   true, // Takes the token to revoke, even an expired one
+}
+
+// isPublicMethod reports whether the method needs no token. Server reflection
+// is public too, so that tools such as grpcurl can list the services.
+func isPublicMethod(fullMethod string) bool {
+	return PublicMethods[fullMethod] || strings.HasPrefix(fullMethod, "/grpc.reflection.")
+}
+
+type userIDKey struct{}
+
+// UserIDFromContext returns the user whose token authenticated the gRPC call.
+func UserIDFromContext(ctx context.Context) (uuid.UUID, bool) {
+	userID, ok := ctx.Value(userIDKey{}).(uuid.UUID)
+	return userID, ok
+}
+
+// authenticate verifies the bearer token in the call's authorization metadata
+// and returns a context carrying the user it was issued to.
+func authenticate(ctx context.Context) (context.Context, error) {
+	md, _ := metadata.FromIncomingContext(ctx)
+	values := md.Get("authorization")
+	if len(values) == 0 {
+		return nil, status.Error(codes.Unauthenticated, "Authorization token is required")
+	}
+
+	tokenParts := strings.Split(values[0], " ")
+	if len(tokenParts) != 2 || tokenParts[0] != "Bearer" {
+		return nil, status.Error(codes.Unauthenticated, "Invalid token format")
+	}
+
+	verified, err := infrastructure.VerifyToken(ctx, tokenParts[1])
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid or expired token")
+	}
+	userID, err := uuid.Parse(verified)
+	if err != nil {
+		return nil, status.Error(codes.Unauthenticated, "Invalid or expired token")
+	}
+	return context.WithValue(ctx, userIDKey{}, userID), nil
+}
+
+// UnaryAuthInterceptor rejects unary calls to non-public methods that don't
+// carry a valid bearer token, and passes the token's user on in the context.
+func UnaryAuthInterceptor() grpc.UnaryServerInterceptor {
+	return func(ctx context.Context, req interface{}, info *grpc.UnaryServerInfo, handler grpc.UnaryHandler) (interface{}, error) {
+		if isPublicMethod(info.FullMethod) {
+			return handler(ctx, req)
+		}
+		ctx, err := authenticate(ctx)
+		if err != nil {
+			return nil, err
+		}
+		return handler(ctx, req)
+	}
+}
+
+// StreamAuthInterceptor is the streaming counterpart of UnaryAuthInterceptor.
+func StreamAuthInterceptor() grpc.StreamServerInterceptor {
+	return func(srv interface{}, stream grpc.ServerStream, info *grpc.StreamServerInfo, handler grpc.StreamHandler) error {
+		if isPublicMethod(info.FullMethod) {
+			return handler(srv, stream)
+		}
+		ctx, err := authenticate(stream.Context())
+		if err != nil {
+			return err
+		}
+		return handler(srv, &authenticatedStream{ServerStream: stream, ctx: ctx})
+	}
+}
+
+// authenticatedStream is a server stream whose context carries the caller.
+type authenticatedStream struct {
+	grpc.ServerStream
+	ctx context.Context
+}
+
+func (s *authenticatedStream) Context() context.Context {
+	return s.ctx
+}
@@ -6,6 +6,7 @@ import (
 	"strings"
 
 	"github.com/gin-gonic/gin"
+	"github.com/google/uuid"
 	"github.com/sarika-p9/my-pipeline-project/internal/infrastructure"
 )
 
@@ -26,14 +27,31 @@ func AuthMiddleware() gin.HandlerFunc {
 		}
 		token := tokenParts[1]
 
-		client := infrastructure.InitSupabaseClient()
-		user, err := client.Auth.User(context.Background(), token)
-		if err != nil || user == nil {
+		userID, err := infrastructure.VerifyToken(context.Background(), token)
+		if err != nil {
 			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid or expired token"})
 			c.Abort()
 			return
 		}
-		c.Set("user_id", user.ID)
+		c.Set("user_id", userID)
+		c.Next()
+	}
+}
+
+// AdminMiddleware lets only admins through. It runs after AuthMiddleware.
+func AdminMiddleware(isAdmin func(userID uuid.UUID) bool) gin.HandlerFunc {
+	return func(c *gin.Context) {
+		userID, err := uuid.Parse(c.GetString("user_id"))
+		if err != nil {
+			c.JSON(http.StatusUnauthorized, gin.H{"error": "Invalid user ID"})
+			c.Abort()
+			return
+		}
+		if !isAdmin(userID) {
+			c.JSON(http.StatusForbidden, gin.H{"error": "Admin access required"})
+			c.Abort()
+			return
+		}
 		c.Next()
 	}
 }
@@ -8,7 +8,7 @@ import (
 
 type User struct {
 	UserID    uuid.UUID `gorm:"column:user_id;type:uuid;primaryKey"`
-	Name      string    `gorm:"type:varchar(100)";not null;default:'Sarika Gautam'`
+	Name      string    `gorm:"type:varchar(100)"`
 	Email     string    `gorm:"type:varchar(100);unique;not null"`
 	Role      string    `gorm:"type:varchar(20);not null;default:'worker';check:role IN ('super_admin', 'admin', 'manager', 'worker')"`
 	CreatedAt time.Time `gorm:"autoCreateTime"`
@@ -22,16 +22,108 @@ type Pipelines struct {
 	UserID        uuid.UUID `gorm:"type:uuid;not null;index"`
 	Status        string    `gorm:"type:varchar(50);not null"`
 	PipelineName  string    `gorm:"type:varchar(255);not null;default:'Untitled Pipeline'"`
+	IsParallel    bool      `gorm:"not null;default:false"`
+	FailurePolicy string    `gorm:"type:varchar(20);not null;default:'halt'"`
+	TimeoutMs     int64     `gorm:"not null;default:0"`
+	Output        string    `gorm:"type:text"`
+	EventSequence int64     `gorm:"not null;default:0"` // Sequence of the latest live update
 	CreatedAt     time.Time `gorm:"autoCreateTime"`
 	UpdatedAt     time.Time `gorm:"autoUpdateTime"`
 	ExecutionLogs []Stages  `gorm:"foreignKey:PipelineID;constraint:OnDelete:CASCADE;"`
+
+	StageDependencies []StageDependencies `gorm:"foreignKey:PipelineID;constraint:OnDelete:CASCADE;"`
+	Runs              []PipelineRun       `gorm:"foreignKey:PipelineID;constraint:OnDelete:CASCADE;"`
 }
 
 type Stages struct {
-	StageID    uuid.UUID `gorm:"type:uuid;primaryKey"`
+	StageID     uuid.UUID `gorm:"type:uuid;primaryKey"`
+	PipelineID  uuid.UUID `gorm:"type:uuid;not null;index"`
+	StageName   string    `gorm:"type:varchar(255);not null;default:'Untitled Stage'"`
+	StageType   string    `gorm:"type:varchar(50);not null;default:'sleep'"`
+	Config      string    `gorm:"type:text"`
+	Position    int       `gorm:"not null;default:0"`
+	Status      string    `gorm:"type:varchar(50);not null"`
+	ErrorMsg    string    `gorm:"type:text"`
+	Output      string    `gorm:"type:text"`
+	RetryPolicy string    `gorm:"type:text"`
+	TimeoutMs   int64     `gorm:"not null;default:0"`
+	Timestamp   time.Time `gorm:"autoCreateTime"`
+
+	Attempts []StageAttempts `gorm:"foreignKey:StageID;constraint:OnDelete:CASCADE;"`
+}
+
+// PipelineRun is one execution of a pipeline. The Status and Output columns
+// of Pipelines and Stages mirror the latest run.
+type PipelineRun struct {
+	RunID      uuid.UUID `gorm:"type:uuid;primaryKey"`
 	PipelineID uuid.UUID `gorm:"type:uuid;not null;index"`
-	StageName  string    `gorm:"type:varchar(255);not null;default:'Untitled Stage'"`
+	UserID     uuid.UUID `gorm:"type:uuid;not null;index"`
+	Status     string    `gorm:"type:varchar(50);not null"`
+	IsParallel bool      `gorm:"not null"`
+	Input      string    `gorm:"type:text"`
+	Output     string    `gorm:"type:text"`
+	ErrorMsg   string    `gorm:"type:text"`
+	CreatedAt  time.Time `gorm:"autoCreateTime"`
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+	// Owner is the process executing the run. It renews LeaseExpiresAt while
+	// the run is unfinished; once the lease expires, other processes recover
+	// the run.
+	Owner          string `gorm:"type:varchar(255);index"`
+	LeaseExpiresAt *time.Time
+
+	StageRuns []StageRun `gorm:"foreignKey:RunID;constraint:OnDelete:CASCADE;"`
+}
+
+type StageRun struct {
+	StageRunID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	RunID      uuid.UUID `gorm:"type:uuid;not null;index"`
+	StageID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	PipelineID uuid.UUID `gorm:"type:uuid;not null"`
+	StageName  string    `gorm:"type:varchar(255);not null"`
+	Position   int       `gorm:"not null;default:0"`
 	Status     string    `gorm:"type:varchar(50);not null"`
 	ErrorMsg   string    `gorm:"type:text"`
-	Timestamp  time.Time `gorm:"autoCreateTime"`
+	Output     string    `gorm:"type:text"`
+	StartedAt  *time.Time
+	FinishedAt *time.Time
+
+	Attempts []StageAttempts `gorm:"-"`
+}
+
+type StageAttempts struct {
+	AttemptID  uuid.UUID `gorm:"type:uuid;primaryKey"`
+	StageID    uuid.UUID `gorm:"type:uuid;not null;index"`
+	PipelineID uuid.UUID `gorm:"type:uuid;not null;index"`
+	RunID      uuid.UUID `gorm:"type:uuid;index"`
+	Attempt    int       `gorm:"not null"`
+	Status     string    `gorm:"type:varchar(50);not null"`
+	ErrorMsg   string    `gorm:"type:text"`
+	StartedAt  time.Time
+	FinishedAt time.Time
+}
+
+type StageDependencies struct {
+	StageID          uuid.UUID `gorm:"type:uuid;primaryKey"`
+	DependsOnStageID uuid.UUID `gorm:"type:uuid;primaryKey"`
+	PipelineID       uuid.UUID `gorm:"type:uuid;not null;index"`
+}
+
+// OutboxEvent is a state-change event written in the same transaction as the
+// change itself and published later by the outbox relay. EventID doubles as
+// the deduplication ID of the published message. An event is not relayed
+// before NextAttemptAt, which backs off failed attempts and keeps events
+// claimed by a relay from other relays. Events that keep failing are parked
+// with FailedAt set.
+type OutboxEvent struct {
+	EventID       uuid.UUID  `gorm:"type:uuid;primaryKey"`
+	PipelineID    uuid.UUID  `gorm:"type:uuid;not null;index"`
+	EventType     string     `gorm:"type:varchar(100);not null"`
+	Payload       string     `gorm:"type:text;not null"`
+	CreatedAt     time.Time  `gorm:"autoCreateTime;index"`
+	PublishedAt   *time.Time `gorm:"index"`
+	Attempts      int        `gorm:"not null;default:0"`
+	LastError     string     `gorm:"type:text"`
+	NextAttemptAt *time.Time
+	FailedAt      *time.Time
 }
@@ -3,48 +3,58 @@
 package producer
 
 import (
+	"context"
 	"encoding/json"
 	"log"
 
-	"github.com/sarika-p9/my-pipeline-project/internal/infrastructure"
-
-	"github.com/streadway/amqp"
+	"github.com/google/uuid"
+	"github.com/sarika-p9/my-pipeline-project/internal/core/ports"
 )
 
-type Task struct {
-	PipelineID string `json:"pipeline_id"`
-	JobType    string `json:"job_type"`
-	Payload    string `json:"payload"`
-}
+// TaskQueue is the queue that workers consume stage tasks from.
+const TaskQueue = "pipeline_tasks"
+
+// CancelTopic is the topic that every worker receives task cancels on.
+const CancelTopic = "pipeline.task_cancels"
+
+// Task asks a worker to run one attempt of a stage.
+type Task = ports.StageTask
 
 type Producer struct {
-	RabbitMQ *infrastructure.RabbitMQ
+	Bus ports.MessageBus
 }
 
-func NewProducer(rabbitMQ *infrastructure.RabbitMQ) *Producer {
-	return &Producer{RabbitMQ: rabbitMQ}
+func NewProducer(bus ports.MessageBus) *Producer {
+	return &Producer{Bus: bus}
 }
 
-func (p *Producer) PublishTask(task Task) error {
+// PublishTask queues a task for the workers. The worker publishes its result
+// on the replyTo topic with the task ID as correlation ID.
+func (p *Producer) PublishTask(ctx context.Context, task Task, replyTo string) error {
 	body, err := json.Marshal(task)
 	if err != nil {
 		return err
 	}
 
-	err = p.RabbitMQ.Channel.Publish(
-		"",                    // Exchange
-		p.RabbitMQ.Queue.Name, // Routing Key
-		false,                 // Mandatory
-		false,                 // Immediate
-		amqp.Publishing{
-			ContentType: "application/json",
-			Body:        body,
-		},
-	)
+	err = p.Bus.Enqueue(ctx, TaskQueue, ports.Message{
+		Data:          body,
+		ReplyTo:       replyTo,
+		CorrelationID: task.TaskID.String(),
+	})
 	if err != nil {
 		return err
 	}
 
-	log.Printf("✅ Published task to queue: %+v", task)
+	log.Printf("✅ Published task %s for stage %s to queue %s", task.TaskID, task.StageName, TaskQueue)
 	return nil
 }
+
+// PublishCancel tells the workers to stop the task, whether it is running or
+// still queued.
+func (p *Producer) PublishCancel(ctx context.Context, taskID uuid.UUID) error {
+	body, err := json.Marshal(ports.StageTaskCancel{TaskID: taskID})
+	if err != nil {
+		return err
+	}
+	return p.Bus.Publish(ctx, CancelTopic, ports.Message{Data: body, CorrelationID: taskID.String()})
+}
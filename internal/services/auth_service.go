@@ -14,6 +14,24 @@ import (
 	"github.com/sarika-p9/my-pipeline-project/internal/models"
 )
 
+// AdminRoles are the user roles with access to every pipeline and to the
+// admin operations.
+var AdminRoles = []string{"super_admin", "admin"}
+
+// isAdmin reports whether the user has one of the AdminRoles.
+func isAdmin(repo ports.PipelineRepository, userID uuid.UUID) bool {
+	user, err := repo.GetUserByID(userID)
+	if err != nil {
+		return false
+	}
+	for _, role := range AdminRoles {
+		if user.Role == role {
+			return true
+		}
+	}
+	return false
+}
+
 type AuthService struct {
 	SupabaseClient *supabase.Client
 	Repo           ports.PipelineRepository
@@ -81,6 +99,11 @@ func (s *AuthService) LoginUser(email, password string) (string, string, string,
 	return session.User.ID, session.User.Email, session.AccessToken, nil
 }
 
+// IsAdmin reports whether the user has one of the AdminRoles.
+func (s *AuthService) IsAdmin(userID uuid.UUID) bool {
+	return isAdmin(s.Repo, userID)
+}
+
 func (s *AuthService) GetUserByID(userID uuid.UUID) (*models.User, error) {
 	return s.Repo.GetUserByID(userID)
 }
@@ -107,18 +130,3 @@ func (s *AuthService) LogoutUser(token string) error {
 
 	return nil
 }
-
-func (s *AuthService) DeletePipeline(pipelineID string) error {
-	if pipelineID == "" {
-		return errors.New("pipeline ID cannot be empty")
-	}
-
-	err := s.Repo.DeletePipeline(context.Background(), pipelineID)
-	if err != nil {
-		log.Printf("[ERROR] Failed to delete pipeline %s: %v", pipelineID, err)
-		return err
-	}
-
-	log.Printf("[INFO] Pipeline %s deleted successfully", pipelineID)
-	return nil
-}
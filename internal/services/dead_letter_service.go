@@ -0,0 +1,77 @@
+package services
+
+import (
+	"context"
+	"fmt"
+
+	"github.com/google/uuid"
+	"github.com/sarika-p9/my-pipeline-project/internal/core/ports"
+	"github.com/sarika-p9/my-pipeline-project/internal/producer"
+)
+
+// DeadLetterService lets admins inspect, replay and purge the messages a
+// queue's consumers gave up on.
+type DeadLetterService struct {
+	Bus        ports.MessageBus
+	Repository ports.PipelineRepository
+}
+
+func NewDeadLetterService(bus ports.MessageBus, repo ports.PipelineRepository) *DeadLetterService {
+	return &DeadLetterService{Bus: bus, Repository: repo}
+}
+
+// ListDeadLetters returns the dead letters of queue, which defaults to the
+// task queue.
+func (s *DeadLetterService) ListDeadLetters(ctx context.Context, userID uuid.UUID, queue string) ([]ports.DeadLetter, error) {
+	store, err := s.store(userID)
+	if err != nil {
+		return nil, err
+	}
+	return store.DeadLetters(ctx, queueOrDefault(queue))
+}
+
+// ReplayDeadLetters enqueues the dead letters with the given IDs again, or all
+// of the queue's dead letters when ids is empty.
+func (s *DeadLetterService) ReplayDeadLetters(ctx context.Context, userID uuid.UUID, queue string, ids []string) (int, error) {
+	store, err := s.store(userID)
+	if err != nil {
+		return 0, err
+	}
+	return store.ReplayDeadLetters(ctx, queueOrDefault(queue), ids)
+}
+
+// PurgeDeadLetters drops the dead letters with the given IDs, or all of the
+// queue's dead letters when ids is empty.
+func (s *DeadLetterService) PurgeDeadLetters(ctx context.Context, userID uuid.UUID, queue string, ids []string) (int, error) {
+	store, err := s.store(userID)
+	if err != nil {
+		return 0, err
+	}
+	return store.PurgeDeadLetters(ctx, queueOrDefault(queue), ids)
+}
+
+// store checks that the user is an admin and returns the bus's dead letters.
+func (s *DeadLetterService) store(userID uuid.UUID) (ports.DeadLetterStore, error) {
+	if err := s.requireAdmin(userID); err != nil {
+		return nil, err
+	}
+	store, ok := s.Bus.(ports.DeadLetterStore)
+	if !ok {
+		return nil, ports.ErrDeadLettersUnsupported
+	}
+	return store, nil
+}
+
+func (s *DeadLetterService) requireAdmin(userID uuid.UUID) error {
+	if !isAdmin(s.Repository, userID) {
+		return fmt.Errorf("%w: dead letters are managed by admins", ports.ErrPermissionDenied)
+	}
+	return nil
+}
+
+func queueOrDefault(queue string) string {
+	if queue == "" {
+		return producer.TaskQueue
+	}
+	return queue
+}
@@ -1,13 +1,14 @@
 package services
 
 import (
+	"context"
 	"log"
 
-	"github.com/nats-io/nats.go"
+	"github.com/sarika-p9/my-pipeline-project/internal/core/ports"
 )
 
-func PublishRealTimeEvent(nc *nats.Conn, subject string, message string) {
-	err := nc.Publish(subject, []byte(message))
+func PublishRealTimeEvent(bus ports.MessageBus, subject string, message string) {
+	err := bus.Publish(context.Background(), subject, ports.Message{Data: []byte(message)})
 	if err != nil {
 		log.Printf("❌ Failed to publish real-time event: %v", err)
 	} else {
@@ -1,14 +1,16 @@
 package services
 
 import (
+	"context"
 	"log"
 
-	"github.com/nats-io/nats.go"
+	"github.com/sarika-p9/my-pipeline-project/internal/core/ports"
 )
 
-func SubscribeToEvents(nc *nats.Conn, subject string) {
-	_, err := nc.Subscribe(subject, func(m *nats.Msg) {
-		log.Printf("📥 Real-time event received on '%s': %s", subject, string(m.Data))
+func SubscribeToEvents(bus ports.MessageBus, subject string) {
+	_, err := bus.Subscribe(subject, func(ctx context.Context, msg ports.Message) error {
+		log.Printf("📥 Real-time event received on '%s': %s", subject, string(msg.Data))
+		return nil
 	})
 	if err != nil {
 		log.Fatalf("❌ Failed to subscribe to events: %v", err)
@@ -0,0 +1,116 @@
+package services
+
+import (
+	"context"
+	"encoding/json"
+	"fmt"
+	"time"
+
+	"github.com/sarika-p9/my-pipeline-project/internal/core/ports"
+	"github.com/sarika-p9/my-pipeline-project/internal/models"
+)
+
+// OutboxRelay publishes the events of the outbox on Bus, each on its
+// pipeline's events topic. The servers give it a NATS bus of its own, see
+// messaging.NewEventBus. An event is marked as published only after the
+// bus accepts it, so every event is published at least once; consumers drop
+// duplicates by the message ID, which is the event ID. When Log is set, an
+// event also has to be appended to it before it counts as published. Events
+// that fail are retried with backoff, without holding up the others, and
+// parked as failed after Retry.MaxAttempts.
+type OutboxRelay struct {
+	Repository ports.OutboxRepository
+	Bus        ports.MessageBus
+	Log        ports.EventLog
+	Interval   time.Duration // Pause between polls once the outbox is drained
+	BatchSize  int
+	Retention  time.Duration // How long published events are kept
+	Retry      ports.OutboxRetry
+}
+
+func NewOutboxRelay(repo ports.OutboxRepository, bus ports.MessageBus) *OutboxRelay {
+	return &OutboxRelay{
+		Repository: repo,
+		Bus:        bus,
+		Interval:   500 * time.Millisecond,
+		BatchSize:  100,
+		Retention:  24 * time.Hour,
+		Retry: ports.OutboxRetry{
+			MaxAttempts: 10,
+			Backoff:     time.Second,
+			MaxBackoff:  5 * time.Minute,
+		},
+	}
+}
+
+// Run relays events until ctx is done.
+func (r *OutboxRelay) Run(ctx context.Context) {
+	fmt.Println("📤 Outbox relay started")
+	ticker := time.NewTicker(r.Interval)
+	defer ticker.Stop()
+	lastCleanup := time.Now()
+
+	for {
+		published, err := r.RelayOnce(ctx)
+		if err != nil {
+			fmt.Printf("❌ Failed to relay outbox events: %v\n", err)
+		}
+
+		if time.Since(lastCleanup) > time.Hour {
+			if deleted, err := r.Repository.DeleteOutboxEvents(time.Now().Add(-r.Retention)); err != nil {
+				fmt.Printf("❌ Failed to delete published outbox events: %v\n", err)
+			} else if deleted > 0 {
+				fmt.Printf("🧹 Deleted %d published outbox events\n", deleted)
+			}
+			lastCleanup = time.Now()
+		}
+
+		// A full batch means more events are waiting.
+		if err == nil && published == r.BatchSize {
+			if ctx.Err() != nil {
+				return
+			}
+			continue
+		}
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// RelayOnce publishes one batch of events and returns how many it published.
+func (r *OutboxRelay) RelayOnce(ctx context.Context) (int, error) {
+	return r.Repository.RelayOutboxEvents(r.BatchSize, r.Retry, func(event models.OutboxEvent) error {
+		err := r.publish(ctx, event)
+		if err == nil {
+			return nil
+		}
+		if attempts := event.Attempts + 1; r.Retry.MaxAttempts > 0 && attempts >= r.Retry.MaxAttempts {
+			fmt.Printf("❌ Parking outbox event %s after %d failed attempts: %v\n", event.EventID, attempts, err)
+		} else {
+			fmt.Printf("⚠️ Failed to publish outbox event %s, retrying in %s: %v\n", event.EventID, r.Retry.Delay(attempts), err)
+		}
+		return err
+	})
+}
+
+// publish sends the event to the bus, then to the event log if there is one.
+func (r *OutboxRelay) publish(ctx context.Context, event models.OutboxEvent) error {
+	err := r.Bus.Publish(ctx, ports.PipelineEventsTopic(event.PipelineID), ports.Message{
+		ID:            event.EventID.String(),
+		Data:          []byte(event.Payload),
+		CorrelationID: event.PipelineID.String(),
+	})
+	if err != nil || r.Log == nil {
+		return err
+	}
+
+	var logged ports.Event
+	if err := json.Unmarshal([]byte(event.Payload), &logged); err != nil {
+		return err
+	}
+	_, err = r.Log.Append(ctx, event.PipelineID, logged)
+	return err
+}
@@ -2,164 +2,520 @@ package services
 
 import (
 	"context"
+	"encoding/json"
 	"errors"
 	"fmt"
 	"log"
+	"strconv"
 	"sync"
 	"time"
 
 	"github.com/google/uuid"
 	"github.com/sarika-p9/my-pipeline-project/internal/core/domain"
 	"github.com/sarika-p9/my-pipeline-project/internal/core/ports"
+	"github.com/sarika-p9/my-pipeline-project/internal/infrastructure"
 	"github.com/sarika-p9/my-pipeline-project/internal/models"
 )
 
 type PipelineService struct {
-	ParallelOrchestrators map[uuid.UUID]*domain.ParallelPipelineOrchestrator
-	Repository            ports.PipelineRepository
-	mu                    sync.RWMutex
+	Orchestrators map[uuid.UUID]domain.PipelineOrchestrator
+	Repository    ports.PipelineRepository
+	Runs          *domain.RunRegistry
+	StageTypes    *domain.StageRegistry
+	// Dispatcher, when set, runs stages on workers instead of in this process.
+	Dispatcher ports.StageDispatcher
+	mu         sync.RWMutex
 }
 
 func NewPipelineService(repo ports.PipelineRepository) *PipelineService {
 	return &PipelineService{
-		ParallelOrchestrators: make(map[uuid.UUID]*domain.ParallelPipelineOrchestrator),
-		Repository:            repo,
+		Orchestrators: make(map[uuid.UUID]domain.PipelineOrchestrator),
+		Repository:    repo,
+		Runs:          domain.NewRunRegistry(),
+		StageTypes:    domain.NewStageRegistry(),
 	}
 }
 
-func (ps *PipelineService) CreatePipeline(userID uuid.UUID, name string, stageCount int, stageNames []string) (uuid.UUID, error) {
+// PipelineOptions holds the execution settings chosen when a pipeline is created.
+type PipelineOptions struct {
+	IsParallel    bool
+	FailurePolicy string // halt, continue or rollback; empty means halt
+	TimeoutMs     int64  // Bounds the whole run; zero means no limit
+}
+
+func (ps *PipelineService) CreatePipeline(userID uuid.UUID, name string, stages []domain.StageDefinition, opts PipelineOptions) (uuid.UUID, error) {
+	if err := domain.ValidateStageDefinitions(stages); err != nil {
+		return uuid.Nil, err
+	}
+	if err := ps.StageTypes.ValidateStageTypes(stages); err != nil {
+		return uuid.Nil, err
+	}
+
+	failurePolicy, err := domain.ParseFailurePolicy(opts.FailurePolicy)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	if opts.TimeoutMs < 0 {
+		return uuid.Nil, fmt.Errorf("%w: timeout_ms must not be negative", domain.ErrInvalidTimeout)
+	}
+
 	pipelineID := uuid.New()
 
-	fmt.Printf("🚀 Creating Pipeline: %s\n", pipelineID)
+	fmt.Printf("🚀 Creating Pipeline: %s (parallel=%t, on failure=%s)\n", pipelineID, opts.IsParallel, failurePolicy)
 
-	err := ps.Repository.SavePipelineExecution(&models.Pipelines{
-		PipelineID:   pipelineID,
-		UserID:       userID,
-		PipelineName: name,
-		Status:       "Created",
-		CreatedAt:    time.Now(),
-		UpdatedAt:    time.Now(),
-	})
+	stageRows, deps, err := pipelineStages(pipelineID, stages)
+	if err != nil {
+		return uuid.Nil, err
+	}
+	err = ps.Repository.SavePipelineExecution(&models.Pipelines{
+		PipelineID:    pipelineID,
+		UserID:        userID,
+		PipelineName:  name,
+		IsParallel:    opts.IsParallel,
+		FailurePolicy: string(failurePolicy),
+		TimeoutMs:     opts.TimeoutMs,
+		Status:        "Created",
+		CreatedAt:     time.Now(),
+		UpdatedAt:     time.Now(),
+	}, stageRows, deps)
 	if err != nil {
+		fmt.Println("❌ Error saving pipeline:", err)
 		return uuid.Nil, err
 	}
 
 	// ✅ Initialize orchestrator for this pipeline
 	ps.mu.Lock()
-	ps.ParallelOrchestrators[pipelineID] = domain.NewParallelPipelineOrchestrator(pipelineID, ps.Repository)
+	ps.Orchestrators[pipelineID] = domain.NewPipelineOrchestrator(opts.IsParallel, pipelineID, ps.Repository, ps.Runs)
 	ps.mu.Unlock()
 	fmt.Printf("✅ Orchestrator initialized for pipeline: %s\n", pipelineID)
 
-	// ✅ Insert pipeline stages
-	if err := ps.InsertPipelineStages(pipelineID, stageNames); err != nil {
-		fmt.Println("❌ Error inserting stages:", err)
-		return uuid.Nil, err
-	}
-
 	return pipelineID, nil
 }
 
-func (ps *PipelineService) InsertPipelineStages(pipelineID uuid.UUID, stageNames []string) error {
-	fmt.Printf("🔄 Inserting stages for pipeline: %s, Total stages: %d\n", pipelineID, len(stageNames))
-
-	for _, stageName := range stageNames {
-		fmt.Printf("🛠️ Inserting Stage: %s\n", stageName)
-
+// pipelineStages builds the stage rows of a new pipeline, in definition
+// order, and their dependencies.
+func pipelineStages(pipelineID uuid.UUID, stages []domain.StageDefinition) ([]models.Stages, []models.StageDependencies, error) {
+	rows := make([]models.Stages, 0, len(stages))
+	stageIDs := make(map[string]uuid.UUID, len(stages))
+	for i, def := range stages {
 		stage := models.Stages{
 			StageID:    uuid.New(),
 			PipelineID: pipelineID,
-			StageName:  stageName,
+			StageName:  def.Name,
+			StageType:  def.Type,
+			Config:     string(def.Config),
+			Position:   i,
 			Status:     "Pending",
+			TimeoutMs:  def.TimeoutMs,
 		}
-
-		if err := ps.Repository.SaveExecutionLog(&stage); err != nil {
-			return err
+		if def.Retry != nil {
+			retryPolicy, err := json.Marshal(def.Retry)
+			if err != nil {
+				return nil, nil, err
+			}
+			stage.RetryPolicy = string(retryPolicy)
 		}
+
+		rows = append(rows, stage)
+		stageIDs[def.Name] = stage.StageID
 	}
 
-	return nil
+	var deps []models.StageDependencies
+	for _, def := range stages {
+		for _, upstream := range def.DependsOn {
+			deps = append(deps, models.StageDependencies{
+				StageID:          stageIDs[def.Name],
+				DependsOnStageID: stageIDs[upstream],
+				PipelineID:       pipelineID,
+			})
+		}
+	}
+	return rows, deps, nil
 }
 
-func (ps *PipelineService) StartPipeline(ctx context.Context, userID uuid.UUID, pipelineID uuid.UUID, input interface{}) error {
+// StartPipeline creates a new run of the pipeline and executes it in the
+// background, with the execution mode saved at creation time unless
+// isParallel overrides it for this run. It returns the run ID.
+func (ps *PipelineService) StartPipeline(ctx context.Context, userID uuid.UUID, pipelineID uuid.UUID, input interface{}, isParallel *bool) (_ uuid.UUID, err error) {
 	fmt.Printf("🚀 Received request to start pipeline: %s\n", pipelineID)
 
-	ps.mu.Lock()
-	orchestrator, exists := ps.ParallelOrchestrators[pipelineID]
-	if !exists {
-		fmt.Println("⚠️ Orchestrator not found in memory, reinitializing...")
-		orchestrator = domain.NewParallelPipelineOrchestrator(pipelineID, ps.Repository)
-		ps.ParallelOrchestrators[pipelineID] = orchestrator
+	pipeline, err := ps.Repository.GetPipelineByID(pipelineID)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	parallel := pipeline.IsParallel
+	if isParallel != nil {
+		parallel = *isParallel
+	}
+
+	leaseExpiresAt := time.Now().Add(domain.RunLeaseDuration)
+	run := &models.PipelineRun{
+		RunID:          uuid.New(),
+		PipelineID:     pipelineID,
+		UserID:         userID,
+		Status:         "Pending",
+		IsParallel:     parallel,
+		Owner:          ps.Runs.Owner,
+		LeaseExpiresAt: &leaseExpiresAt,
+	}
+	if err := ps.Runs.Reserve(pipelineID, run.RunID); err != nil {
+		return uuid.Nil, err
+	}
+	defer func() {
+		if err != nil {
+			ps.Runs.Remove(pipelineID)
+		}
+	}()
+
+	orchestrator, stageCount, err := ps.buildOrchestrator(pipelineID, parallel)
+	if err != nil {
+		return uuid.Nil, err
+	}
+
+	if input != nil {
+		encodedInput, err := json.Marshal(input)
+		if err != nil {
+			return uuid.Nil, fmt.Errorf("invalid pipeline input: %w", err)
+		}
+		run.Input = string(encodedInput)
 	}
+	if err := ps.Repository.SavePipelineRun(run); err != nil {
+		return uuid.Nil, err
+	}
+
+	ps.mu.Lock()
+	ps.Orchestrators[pipelineID] = orchestrator
 	ps.mu.Unlock()
 
-	fmt.Println("✅ Orchestrator initialized, updating pipeline status to Running...")
-	if err := ps.updatePipelineStatus(pipelineID, "Running"); err != nil {
+	fmt.Printf("✅ Orchestrator initialized (parallel=%t), executing %d stages in run %s...\n", parallel, stageCount, run.RunID)
+	go func() {
+		if _, _, err := orchestrator.Execute(context.WithoutCancel(ctx), userID, pipelineID, run.RunID, input); err != nil {
+			fmt.Printf("❌ Error executing run %s of pipeline %s: %v\n", run.RunID, pipelineID, err)
+			return
+		}
+		fmt.Printf("✅ Pipeline completed: %s (run %s)\n", pipelineID, run.RunID)
+	}()
+
+	return run.RunID, nil
+}
+
+// RecoverRuns claims the runs left Pending or Running by processes that
+// stopped renewing their lease, whether before a restart or on another
+// replica. With RecoveryPolicyResume they are executed again from their
+// completed stages; otherwise, or when a run cannot be resumed, they are
+// marked Interrupted. Runs of live processes are left alone.
+func (ps *PipelineService) RecoverRuns(ctx context.Context, policy domain.RecoveryPolicy) error {
+	runs, err := ps.Repository.ClaimExpiredRuns(ps.Runs.Owner, time.Now().Add(domain.RunLeaseDuration), domain.UnfinishedRunStatuses...)
+	if err != nil {
+		return err
+	}
+	if len(runs) == 0 {
+		return nil
+	}
+	fmt.Printf("🔄 Recovering %d unfinished runs with expired leases (policy=%s)\n", len(runs), policy)
+
+	for i := range runs {
+		run := &runs[i]
+		if policy == domain.RecoveryPolicyResume {
+			err := ps.resumeRun(ctx, run)
+			if err == nil {
+				continue
+			}
+			fmt.Printf("❌ Cannot resume run %s of pipeline %s: %v\n", run.RunID, run.PipelineID, err)
+		}
+		if err := domain.InterruptRun(ps.Repository, run, "the server executing the run stopped"); err != nil {
+			fmt.Printf("❌ Failed to mark run %s of pipeline %s as Interrupted: %v\n", run.RunID, run.PipelineID, err)
+		}
+	}
+	return nil
+}
+
+// MaintainRunLeases renews the leases of the runs this process executes, and
+// recovers with the given policy the runs whose owner stopped renewing them.
+// It also stops the runs that were cancelled through another replica. It
+// returns when ctx is done.
+func (ps *PipelineService) MaintainRunLeases(ctx context.Context, policy domain.RecoveryPolicy) {
+	ticker := time.NewTicker(domain.RunLeaseRenewInterval)
+	defer ticker.Stop()
+
+	for {
+		select {
+		case <-ticker.C:
+		case <-ctx.Done():
+			return
+		}
+
+		leaseUntil := time.Now().Add(domain.RunLeaseDuration)
+		if err := ps.Repository.RenewRunLeases(ps.Runs.Owner, leaseUntil, domain.UnfinishedRunStatuses...); err != nil {
+			fmt.Printf("❌ Failed to renew the leases of %s: %v\n", ps.Runs.Owner, err)
+		}
+		if err := ps.RecoverRuns(ctx, policy); err != nil {
+			fmt.Printf("❌ Failed to recover runs with expired leases: %v\n", err)
+		}
+		ps.stopCancelledRuns()
+	}
+}
+
+// stopCancelledRuns stops the runs executing here that another replica
+// cancelled. That replica could only record the cancel.
+func (ps *PipelineService) stopCancelledRuns() {
+	for pipelineID, runID := range ps.Runs.ActiveRuns() {
+		run, err := ps.Repository.GetPipelineRun(runID)
+		if err != nil || run.Status != "Cancelled" {
+			continue
+		}
+		if err := ps.Runs.CancelRun(pipelineID, runID); err == nil {
+			fmt.Printf("🛑 Stopped run %s of pipeline %s, cancelled through another replica\n", runID, pipelineID)
+		}
+	}
+}
+
+// resumeRun rebuilds the orchestrator of an unfinished run and executes the
+// rest of it in the background.
+func (ps *PipelineService) resumeRun(ctx context.Context, run *models.PipelineRun) (err error) {
+	if err := ps.Runs.Reserve(run.PipelineID, run.RunID); err != nil {
 		return err
 	}
+	defer func() {
+		if err != nil {
+			ps.Runs.Remove(run.PipelineID)
+		}
+	}()
+
+	var input interface{}
+	if run.Input != "" {
+		if err := json.Unmarshal([]byte(run.Input), &input); err != nil {
+			return fmt.Errorf("invalid input stored for run %s: %w", run.RunID, err)
+		}
+	}
 
+	orchestrator, _, err := ps.buildOrchestrator(run.PipelineID, run.IsParallel)
+	if err != nil {
+		return err
+	}
+
+	ps.mu.Lock()
+	ps.Orchestrators[run.PipelineID] = orchestrator
+	ps.mu.Unlock()
+
+	fmt.Printf("♻️ Resuming run %s of pipeline %s (parallel=%t)\n", run.RunID, run.PipelineID, run.IsParallel)
+	go func() {
+		if _, _, err := orchestrator.Resume(context.WithoutCancel(ctx), run.UserID, run.PipelineID, run.RunID, input); err != nil {
+			fmt.Printf("❌ Error resuming run %s of pipeline %s: %v\n", run.RunID, run.PipelineID, err)
+			return
+		}
+		fmt.Printf("✅ Pipeline completed: %s (run %s)\n", run.PipelineID, run.RunID)
+	}()
+	return nil
+}
+
+// buildOrchestrator loads the pipeline's stages and dependencies into a new
+// orchestrator and returns it with the number of stages.
+func (ps *PipelineService) buildOrchestrator(pipelineID uuid.UUID, parallel bool) (domain.PipelineOrchestrator, int, error) {
 	fmt.Println("🔄 Fetching pipeline stages...")
 	stages, err := ps.Repository.GetPipelineStages(pipelineID)
 	if err != nil {
-		return err
+		return nil, 0, err
 	}
 
+	deps, err := ps.Repository.GetStageDependencies(pipelineID)
+	if err != nil {
+		return nil, 0, err
+	}
+
+	orchestrator := domain.NewPipelineOrchestrator(parallel, pipelineID, ps.Repository, ps.Runs)
 	for _, stage := range stages {
-		fmt.Printf("🔄 Updating stage %s to Running\n", stage.StageName)
-		if err := ps.Repository.UpdateStageStatus(stage.StageID, "Running"); err != nil {
-			fmt.Println("❌ Failed to update stage status:", err)
-			return err
+		baseStage := domain.NewBaseStageWithID(stage.StageID, stage.StageName)
+		baseStage.Timeout = time.Duration(stage.TimeoutMs) * time.Millisecond
+		if stage.RetryPolicy != "" {
+			if err := json.Unmarshal([]byte(stage.RetryPolicy), &baseStage.Retry); err != nil {
+				return nil, 0, fmt.Errorf("invalid retry policy for stage %s: %w", stage.StageName, err)
+			}
+		}
+		typedStage, err := ps.StageTypes.Build(stage.StageType, baseStage, json.RawMessage(stage.Config))
+		if err != nil {
+			return nil, 0, err
+		}
+		if ps.Dispatcher != nil {
+			typedStage = domain.NewRemoteStage(baseStage, stage.StageType, json.RawMessage(stage.Config), ps.Dispatcher)
 		}
+		if err := orchestrator.AddStage(typedStage); err != nil {
+			return nil, 0, err
+		}
+	}
+	for _, dep := range deps {
+		if err := orchestrator.AddStageDependency(dep.StageID, dep.DependsOnStageID); err != nil {
+			return nil, 0, err
+		}
+	}
+
+	return orchestrator, len(stages), nil
+}
+
+// GetPipelineStatus returns the status of the given run, or of the latest run
+// when runID is uuid.Nil. It reads the database, so it also works for runs
+// started before a restart.
+func (ps *PipelineService) GetPipelineStatus(pipelineID uuid.UUID, runID uuid.UUID) (string, error) {
+	return domain.GetPipelineStatus(ps.Repository, pipelineID, runID)
+}
 
-		baseStage := domain.NewBaseStage(stage.StageName)
-		_, err := baseStage.Execute(ctx, pipelineID.String(), input)
+// GetPipelineOutput returns the status and decoded output of the given run,
+// or of the latest run when runID is uuid.Nil. The output is nil until a run
+// has produced one.
+func (ps *PipelineService) GetPipelineOutput(pipelineID uuid.UUID, runID uuid.UUID) (string, interface{}, error) {
+	var status, encoded string
+	if runID == uuid.Nil {
+		pipeline, err := ps.Repository.GetPipelineByID(pipelineID)
 		if err != nil {
-			fmt.Println("❌ Error executing stage:", err)
-			return err
+			return "", nil, err
 		}
+		status, encoded = pipeline.Status, pipeline.Output
+	} else {
+		run, err := domain.FindPipelineRun(ps.Repository, pipelineID, runID)
+		if err != nil {
+			return "", nil, err
+		}
+		status, encoded = run.Status, run.Output
+	}
 
-		fmt.Printf("✅ Stage %s Completed\n", stage.StageName)
-		if err := ps.Repository.UpdateStageStatus(stage.StageID, "Completed"); err != nil {
-			fmt.Println("❌ Failed to update stage to Completed:", err)
-			return err
+	var output interface{}
+	if encoded != "" {
+		if err := json.Unmarshal([]byte(encoded), &output); err != nil {
+			return "", nil, fmt.Errorf("invalid output stored for pipeline %s: %w", pipelineID, err)
 		}
 	}
+	return status, output, nil
+}
 
-	fmt.Printf("✅ Pipeline completed: %s\n", pipelineID)
-	return ps.updatePipelineStatus(pipelineID, "Completed")
+// CanViewPipeline returns nil when the user owns the pipeline or is an admin,
+// and an error wrapping ports.ErrPermissionDenied otherwise.
+func (ps *PipelineService) CanViewPipeline(userID uuid.UUID, pipelineID uuid.UUID) error {
+	pipeline, err := ps.Repository.GetPipelineByID(pipelineID)
+	if err != nil {
+		return err
+	}
+	if pipeline.UserID == userID || isAdmin(ps.Repository, userID) {
+		return nil
+	}
+	return fmt.Errorf("%w: pipeline %s belongs to another user", ports.ErrPermissionDenied, pipelineID)
 }
 
-func (ps *PipelineService) GetPipelineStatus(pipelineID uuid.UUID) (string, error) {
-	ps.mu.RLock()
-	orchestrator, exists := ps.ParallelOrchestrators[pipelineID]
-	ps.mu.RUnlock()
+// WatchPipeline follows the pipeline's live updates for the user, starting
+// after lastSeq when it is not 0. activeRunID is the run in progress, or
+// uuid.Nil when there is none and the watcher only holds the missed updates.
+// The caller stops the watcher.
+func (ps *PipelineService) WatchPipeline(userID uuid.UUID, pipelineID uuid.UUID, lastSeq int64) (watcher *infrastructure.Watcher, activeRunID uuid.UUID, err error) {
+	if err := ps.CanViewPipeline(userID, pipelineID); err != nil {
+		return nil, uuid.Nil, err
+	}
 
-	if !exists {
-		return "", errors.New("orchestrator not found for pipeline")
+	// Watch before looking at the run, so that an update ending it can't be
+	// missed in between.
+	watcher = infrastructure.WebSocket.Watch(pipelineID, lastSeq)
+	run, err := domain.FindPipelineRun(ps.Repository, pipelineID, uuid.Nil)
+	switch {
+	case errors.Is(err, ports.ErrRunNotFound):
+		return watcher, uuid.Nil, nil
+	case err != nil:
+		watcher.Stop()
+		return nil, uuid.Nil, err
+	case domain.IsRunFinished(run.Status):
+		return watcher, uuid.Nil, nil
 	}
+	return watcher, run.RunID, nil
+}
 
-	return orchestrator.GetStatus(pipelineID)
+// EndsWatch reports whether the update is the final status of the run a
+// watch follows.
+func EndsWatch(update infrastructure.PipelineUpdate, activeRunID uuid.UUID) bool {
+	return update.Type == infrastructure.RunUpdateType && update.RunID == activeRunID && domain.IsRunFinished(update.Status)
 }
 
-func (ps *PipelineService) CancelPipeline(pipelineID uuid.UUID, userID uuid.UUID) error {
+// LatestRunUpdates describes the state of the pipeline's latest run as
+// unsequenced updates, the run's status first and then each stage's, so that
+// watchers can start from it. It returns nothing when the pipeline has not
+// been run.
+func (ps *PipelineService) LatestRunUpdates(pipelineID uuid.UUID) ([]infrastructure.PipelineUpdate, error) {
+	pipeline, err := ps.Repository.GetPipelineByID(pipelineID)
+	if err != nil {
+		return nil, err
+	}
+	run, err := domain.FindPipelineRun(ps.Repository, pipelineID, uuid.Nil)
+	if errors.Is(err, ports.ErrRunNotFound) {
+		return nil, nil
+	}
+	if err != nil {
+		return nil, err
+	}
+	stageRuns, err := ps.Repository.GetStageRuns(run.RunID)
+	if err != nil {
+		return nil, err
+	}
+
+	updates := make([]infrastructure.PipelineUpdate, 0, len(stageRuns)+1)
+	updates = append(updates, infrastructure.PipelineUpdate{
+		Type:         infrastructure.RunUpdateType,
+		PipelineID:   pipelineID,
+		PipelineName: pipeline.PipelineName,
+		UserID:       pipeline.UserID,
+		RunID:        run.RunID,
+		Status:       run.Status,
+	})
+	for _, stageRun := range stageRuns {
+		updates = append(updates, infrastructure.PipelineUpdate{
+			Type:         infrastructure.StageUpdateType,
+			PipelineID:   pipelineID,
+			PipelineName: pipeline.PipelineName,
+			UserID:       pipeline.UserID,
+			RunID:        run.RunID,
+			StageID:      stageRun.StageID,
+			StageName:    stageRun.StageName,
+			Status:       stageRun.Status,
+		})
+	}
+	return updates, nil
+}
+
+// GetPipelineRuns lists every run of the pipeline, newest first.
+func (ps *PipelineService) GetPipelineRuns(pipelineID uuid.UUID) ([]models.PipelineRun, error) {
+	return ps.Repository.GetPipelineRuns(pipelineID)
+}
+
+// GetStageRuns returns the stages of the given run, or of the latest run when
+// runID is uuid.Nil, with their attempts.
+func (ps *PipelineService) GetStageRuns(pipelineID uuid.UUID, runID uuid.UUID) ([]models.StageRun, error) {
+	run, err := domain.FindPipelineRun(ps.Repository, pipelineID, runID)
+	if err != nil {
+		return nil, err
+	}
+	return ps.Repository.GetStageRuns(run.RunID)
+}
+
+func (ps *PipelineService) IsPipelineRunning(pipelineID uuid.UUID) bool {
+	return ps.Runs.IsRunning(pipelineID)
+}
+
+// CancelPipeline stops the given run, or the active run when runID is
+// uuid.Nil, and marks it Cancelled.
+func (ps *PipelineService) CancelPipeline(pipelineID uuid.UUID, runID uuid.UUID, userID uuid.UUID) error {
 	ps.mu.RLock()
-	orchestrator, exists := ps.ParallelOrchestrators[pipelineID]
+	orchestrator, exists := ps.Orchestrators[pipelineID]
 	ps.mu.RUnlock()
 
 	if !exists {
-		log.Printf("Orchestrator not found for pipeline: %s", pipelineID)
-		return errors.New("orchestrator not initialized for this pipeline")
+		log.Printf("Orchestrator not found for pipeline %s, cancelling through the run registry", pipelineID)
+		orchestrator = domain.NewPipelineOrchestrator(false, pipelineID, ps.Repository, ps.Runs)
 	}
 
 	log.Printf("Cancelling pipeline: %s by user: %s", pipelineID, userID)
 
-	err := orchestrator.Cancel(pipelineID, userID)
-	if err != nil {
+	if err := orchestrator.Cancel(pipelineID, runID, userID); err != nil {
 		log.Printf("Failed to cancel pipeline: %v", err)
-		_ = ps.updatePipelineStatus(pipelineID, "Failed to Cancel")
 		return err
 	}
 
-	return ps.updatePipelineStatus(pipelineID, "Cancelled")
+	return nil
 }
 
 func (ps *PipelineService) updatePipelineStatus(pipelineID uuid.UUID, status string) error {
@@ -187,6 +543,69 @@ func (ps *PipelineService) GetPipelinesByUser(userID string) ([]models.Pipelines
 	return ps.Repository.GetPipelinesByUser(userID)
 }
 
+// ErrInvalidPageToken is returned for page tokens ListPipelines did not issue.
+var ErrInvalidPageToken = errors.New("invalid page token")
+
+// Page sizes of ListPipelines.
+const (
+	DefaultPageSize = 50
+	MaxPageSize     = 500
+)
+
+// ListPipelines returns a page of the user's pipelines, oldest first, and the
+// token of the next page, which is empty on the last one. A pageSize of zero
+// means DefaultPageSize, and larger ones are capped at MaxPageSize.
+func (ps *PipelineService) ListPipelines(userID uuid.UUID, pageToken string, pageSize int) ([]models.Pipelines, string, error) {
+	offset := 0
+	if pageToken != "" {
+		var err error
+		if offset, err = strconv.Atoi(pageToken); err != nil || offset < 0 {
+			return nil, "", fmt.Errorf("%w: %q", ErrInvalidPageToken, pageToken)
+		}
+	}
+	if pageSize <= 0 {
+		pageSize = DefaultPageSize
+	}
+	if pageSize > MaxPageSize {
+		pageSize = MaxPageSize
+	}
+
+	// The extra pipeline only tells whether another page follows.
+	pipelines, err := ps.Repository.GetPipelinesPage(userID, offset, pageSize+1)
+	if err != nil {
+		return nil, "", err
+	}
+	if len(pipelines) <= pageSize {
+		return pipelines, "", nil
+	}
+	return pipelines[:pageSize], strconv.Itoa(offset + pageSize), nil
+}
+
+// GetPipeline returns the pipeline if the user may view it.
+func (ps *PipelineService) GetPipeline(userID uuid.UUID, pipelineID uuid.UUID) (*models.Pipelines, error) {
+	if err := ps.CanViewPipeline(userID, pipelineID); err != nil {
+		return nil, err
+	}
+	return ps.Repository.GetPipelineByID(pipelineID)
+}
+
+// DeletePipeline deletes the pipeline with its stages and runs, on behalf of
+// its owner or an admin. Pipelines with a run in progress are kept and
+// domain.ErrRunInProgress is returned.
+func (ps *PipelineService) DeletePipeline(ctx context.Context, userID uuid.UUID, pipelineID uuid.UUID) error {
+	if err := ps.CanViewPipeline(userID, pipelineID); err != nil {
+		return err
+	}
+	run, err := domain.FindPipelineRun(ps.Repository, pipelineID, uuid.Nil)
+	if err != nil && !errors.Is(err, ports.ErrRunNotFound) {
+		return err
+	}
+	if err == nil && !domain.IsRunFinished(run.Status) {
+		return domain.ErrRunInProgress
+	}
+	return ps.Repository.DeletePipeline(ctx, pipelineID.String())
+}
+
 func (ps *PipelineService) GetPipelineStages(pipelineID uuid.UUID) ([]models.Stages, error) {
 	return ps.Repository.GetPipelineStages(pipelineID)
 }